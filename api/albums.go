@@ -0,0 +1,198 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+
+	"github.com/btschwartz12/site/internal/logging"
+)
+
+type createAlbumRequest struct {
+	Slug        string `json:"slug"`
+	Title       string `json:"title"`
+	Description string `json:"description"`
+}
+
+type tagPictureRequest struct {
+	Tags []string `json:"tags"`
+}
+
+type pictureTagsResponse struct {
+	Tags []string `json:"tags"`
+}
+
+// getAlbumsHandler godoc
+// @Summary Get albums
+// @Description Get every album
+// @Tags albums
+// @Produce json
+// @Router /api/pics/albums [get]
+// @Security Bearer
+// @Success 200 {array} repo.Album
+// @Failure 401,403,500 {object} errorResponse
+func (s *handler) getAlbumsHandler(w http.ResponseWriter, r *http.Request) {
+	albums, err := s.rpo.GetAllAlbums(r.Context())
+	if err != nil {
+		s.writeRepoError(w, r, err, "error getting albums")
+		return
+	}
+
+	resp, err := json.MarshalIndent(albums, "", " \t")
+	if err != nil {
+		logging.FromRequest(s.logger, r).Errorw("error marshalling albums", "error", err)
+		writeError(w, r, http.StatusInternalServerError, codeInternal, "internal server error")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(resp)
+}
+
+// createAlbumHandler godoc
+// @Summary Create an album
+// @Description Create an album, shown in the gallery at /pics/album/{slug}
+// @Tags albums
+// @Param body body createAlbumRequest true "Album"
+// @Accept json
+// @Produce json
+// @Router /api/pics/albums [post]
+// @Security Bearer
+// @Success 201 {object} repo.Album
+// @Failure 400,401,403,409,500 {object} errorResponse
+func (s *handler) createAlbumHandler(w http.ResponseWriter, r *http.Request) {
+	var req createAlbumRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		logging.FromRequest(s.logger, r).Errorw("error decoding request", "error", err)
+		writeError(w, r, http.StatusBadRequest, codeBadRequest, "invalid request body")
+		return
+	}
+
+	album, err := s.rpo.CreateAlbum(r.Context(), req.Slug, req.Title, req.Description)
+	if err != nil {
+		s.writeRepoError(w, r, err, "error creating album")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	if err := json.NewEncoder(w).Encode(album); err != nil {
+		logging.FromRequest(s.logger, r).Errorw("error encoding album", "error", err)
+	}
+}
+
+// deleteAlbumHandler godoc
+// @Summary Delete an album
+// @Description Delete an album, leaving its pictures in the gallery
+// @Tags albums
+// @Param slug path string true "Album slug"
+// @Router /api/pics/albums/{slug} [delete]
+// @Security Bearer
+// @Success 204
+// @Failure 400,401,403,404,500 {object} errorResponse
+func (s *handler) deleteAlbumHandler(w http.ResponseWriter, r *http.Request) {
+	if err := s.rpo.DeleteAlbum(r.Context(), chi.URLParam(r, "slug")); err != nil {
+		s.writeRepoError(w, r, err, "error deleting album")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// addAlbumPictureHandler godoc
+// @Summary Add a picture to an album
+// @Description Add a picture to an album
+// @Tags albums
+// @Param slug path string true "Album slug"
+// @Param id path string true "Picture ID"
+// @Router /api/pics/albums/{slug}/pictures/{id} [put]
+// @Security Bearer
+// @Success 204
+// @Failure 400,401,403,404,500 {object} errorResponse
+func (s *handler) addAlbumPictureHandler(w http.ResponseWriter, r *http.Request) {
+	err := s.rpo.AddPictureToAlbum(r.Context(), chi.URLParam(r, "slug"), chi.URLParam(r, "id"))
+	s.albumPictureResponse(w, r, err)
+}
+
+// removeAlbumPictureHandler godoc
+// @Summary Remove a picture from an album
+// @Description Remove a picture from an album
+// @Tags albums
+// @Param slug path string true "Album slug"
+// @Param id path string true "Picture ID"
+// @Router /api/pics/albums/{slug}/pictures/{id} [delete]
+// @Security Bearer
+// @Success 204
+// @Failure 400,401,403,404,500 {object} errorResponse
+func (s *handler) removeAlbumPictureHandler(w http.ResponseWriter, r *http.Request) {
+	err := s.rpo.RemovePictureFromAlbum(r.Context(), chi.URLParam(r, "slug"), chi.URLParam(r, "id"))
+	s.albumPictureResponse(w, r, err)
+}
+
+func (s *handler) albumPictureResponse(w http.ResponseWriter, r *http.Request, err error) {
+	if err != nil {
+		s.writeRepoError(w, r, err, "error updating album")
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// tagPictureHandler godoc
+// @Summary Tag a picture
+// @Description Add tags to a picture. Tags are lowercased, with spaces turned into dashes.
+// @Tags albums
+// @Param id path string true "Picture ID"
+// @Param body body tagPictureRequest true "Tags"
+// @Accept json
+// @Produce json
+// @Router /api/pics/tags/{id} [post]
+// @Security Bearer
+// @Success 200 {object} pictureTagsResponse
+// @Failure 400,401,403,404,500 {object} errorResponse
+func (s *handler) tagPictureHandler(w http.ResponseWriter, r *http.Request) {
+	var req tagPictureRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		logging.FromRequest(s.logger, r).Errorw("error decoding request", "error", err)
+		writeError(w, r, http.StatusBadRequest, codeBadRequest, "invalid request body")
+		return
+	}
+	if len(req.Tags) == 0 {
+		writeError(w, r, http.StatusBadRequest, codeBadRequest, "tags is required")
+		return
+	}
+
+	tags, err := s.rpo.TagPicture(r.Context(), chi.URLParam(r, "id"), req.Tags)
+	s.pictureTagsResponse(w, r, tags, err)
+}
+
+// untagPictureHandler godoc
+// @Summary Untag a picture
+// @Description Remove a tag from a picture
+// @Tags albums
+// @Param id path string true "Picture ID"
+// @Param tag path string true "Tag"
+// @Produce json
+// @Router /api/pics/tags/{id}/{tag} [delete]
+// @Security Bearer
+// @Success 200 {object} pictureTagsResponse
+// @Failure 400,401,403,404,500 {object} errorResponse
+func (s *handler) untagPictureHandler(w http.ResponseWriter, r *http.Request) {
+	tags, err := s.rpo.UntagPicture(r.Context(), chi.URLParam(r, "id"), chi.URLParam(r, "tag"))
+	s.pictureTagsResponse(w, r, tags, err)
+}
+
+func (s *handler) pictureTagsResponse(w http.ResponseWriter, r *http.Request, tags []string, err error) {
+	if err != nil {
+		s.writeRepoError(w, r, err, "error tagging picture")
+		return
+	}
+
+	if tags == nil {
+		tags = []string{}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(pictureTagsResponse{Tags: tags}); err != nil {
+		logging.FromRequest(s.logger, r).Errorw("error encoding tags", "error", err)
+	}
+}
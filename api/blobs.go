@@ -0,0 +1,90 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+
+	"github.com/btschwartz12/site/internal/logging"
+)
+
+// getBlobHandler godoc
+// @Summary Check for stored content
+// @Description Look up stored content by its hex SHA-256, to avoid uploading a file that is already here. Returns 404 if it isn't.
+// @Tags drive
+// @Param sha256 path string true "SHA-256"
+// @Produce json
+// @Router /api/drive/blobs/{sha256} [get]
+// @Security Bearer
+// @Success 200 {object} repo.Blob
+// @Failure 400,401,403,404,500 {object} errorResponse
+func (s *handler) getBlobHandler(w http.ResponseWriter, r *http.Request) {
+	sha256 := chi.URLParam(r, "sha256")
+
+	b, err := s.rpo.GetBlob(r.Context(), sha256)
+	if err != nil {
+		s.writeRepoError(w, r, err, "error getting blob")
+		return
+	}
+
+	if err := json.NewEncoder(w).Encode(b); err != nil {
+		logging.FromRequest(s.logger, r).Errorw("error encoding blob", "error", err)
+	}
+}
+
+type createFileFromBlobRequest struct {
+	Name  string `json:"name"`
+	Notes string `json:"notes"`
+	// TTL optionally deletes the file after this long
+	TTL string `json:"ttl"`
+}
+
+// createFileFromBlobHandler godoc
+// @Summary Add a file from stored content
+// @Description Add a drive file with content that is already stored, instead of uploading it again
+// @Tags drive
+// @Param sha256 path string true "SHA-256"
+// @Param body body createFileFromBlobRequest true "File"
+// @Accept json
+// @Produce json
+// @Router /api/drive/blobs/{sha256}/files [post]
+// @Security Bearer
+// @Success 201 {object} repo.File
+// @Failure 400,401,403,404,500,507 {object} errorResponse
+func (s *handler) createFileFromBlobHandler(w http.ResponseWriter, r *http.Request) {
+	sha256 := chi.URLParam(r, "sha256")
+
+	var req createFileFromBlobRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		logging.FromRequest(s.logger, r).Errorw("error decoding request", "error", err)
+		writeError(w, r, http.StatusBadRequest, codeBadRequest, "invalid request body")
+		return
+	}
+	if req.Notes == "" {
+		writeError(w, r, http.StatusBadRequest, codeBadRequest, "notes is required")
+		return
+	}
+
+	var ttl time.Duration
+	if req.TTL != "" {
+		var err error
+		ttl, err = time.ParseDuration(req.TTL)
+		if err != nil || ttl <= 0 {
+			writeError(w, r, http.StatusBadRequest, codeBadRequest, "invalid ttl: must be in correct format (300s, 2h45m, etc.)")
+			return
+		}
+	}
+
+	f, err := s.rpo.InsertFileFromBlob(r.Context(), sha256, req.Name, req.Notes, uploaderFromContext(r.Context()), ttl)
+	if err != nil {
+		s.writeRepoError(w, r, err, "error inserting file")
+		return
+	}
+
+	w.WriteHeader(http.StatusCreated)
+	if err := json.NewEncoder(w).Encode(f); err != nil {
+		logging.FromRequest(s.logger, r).Errorw("error encoding file", "error", err)
+	}
+}
@@ -0,0 +1,143 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+
+	"github.com/btschwartz12/site/internal/logging"
+	"github.com/btschwartz12/site/internal/repo"
+)
+
+// getCommentsHandler godoc
+// @Summary Get picture comments
+// @Description Get comments on pictures, hidden ones included
+// @Tags comments
+// @Produce json
+// @Param limit query int false "Page size, 50 by default and at most 500"
+// @Param cursor query string false "Cursor from the previous page"
+// @Param order query string false "asc or desc (the default) by creation time"
+// @Param since query string false "Only items created at or after this time (RFC 3339 or YYYY-MM-DD)"
+// @Param until query string false "Only items created before this time (RFC 3339 or YYYY-MM-DD)"
+// @Param picture_id query int false "Only comments on this picture"
+// @Param status query string false "Only comments in this moderation status (visible or hidden)"
+// @Router /api/pics/comments [get]
+// @Security Bearer
+// @Success 200 {array} repo.Comment
+// @Header 200 {string} Link "Link to the next page, if any"
+// @Header 200 {string} X-Next-Cursor "Cursor for the next page, if any"
+// @Failure 400,401,403,500 {object} errorResponse
+func (s *handler) getCommentsHandler(w http.ResponseWriter, r *http.Request) {
+	opts, err := parseListOptions(r)
+	if err != nil {
+		writeError(w, r, http.StatusBadRequest, codeBadRequest, err.Error())
+		return
+	}
+	var pictureID int64
+	if v := r.URL.Query().Get("picture_id"); v != "" {
+		pictureID, err = strconv.ParseInt(v, 10, 64)
+		if err != nil || pictureID < 1 {
+			writeError(w, r, http.StatusBadRequest, codeInvalidID, "invalid picture_id")
+			return
+		}
+	}
+	comments, next, err := s.rpo.ListComments(r.Context(), repo.CommentFilter{
+		ListOptions: opts,
+		PictureID:   pictureID,
+		Status:      r.URL.Query().Get("status"),
+	})
+	if err != nil {
+		s.writeRepoError(w, r, err, "error getting comments")
+		return
+	}
+
+	resp, err := json.MarshalIndent(comments, "", " \t")
+	if err != nil {
+		logging.FromRequest(s.logger, r).Errorw("error marshalling comments", "error", err)
+		writeError(w, r, http.StatusInternalServerError, codeInternal, "internal server error")
+		return
+	}
+
+	setPageHeaders(w, r, next)
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(resp)
+}
+
+// hideCommentHandler godoc
+// @Summary Hide a comment
+// @Description Hide a comment from its picture's page
+// @Tags comments
+// @Param id path string true "Comment ID"
+// @Param body body moderatePictureRequest false "Reason"
+// @Accept json
+// @Produce json
+// @Router /api/pics/comments/hide/{id} [post]
+// @Security Bearer
+// @Success 200 {object} repo.Comment
+// @Failure 400,401,403,404,500 {object} errorResponse
+func (s *handler) hideCommentHandler(w http.ResponseWriter, r *http.Request) {
+	s.moderateComment(w, r, repo.CommentHidden)
+}
+
+// showCommentHandler godoc
+// @Summary Show a comment
+// @Description Show a hidden comment on its picture's page again
+// @Tags comments
+// @Param id path string true "Comment ID"
+// @Param body body moderatePictureRequest false "Reason"
+// @Accept json
+// @Produce json
+// @Router /api/pics/comments/show/{id} [post]
+// @Security Bearer
+// @Success 200 {object} repo.Comment
+// @Failure 400,401,403,404,500 {object} errorResponse
+func (s *handler) showCommentHandler(w http.ResponseWriter, r *http.Request) {
+	s.moderateComment(w, r, repo.CommentVisible)
+}
+
+func (s *handler) moderateComment(w http.ResponseWriter, r *http.Request, status string) {
+	id := chi.URLParam(r, "id")
+
+	// the reason is optional, and so is the body
+	var req moderatePictureRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && !errors.Is(err, io.EOF) {
+		logging.FromRequest(s.logger, r).Errorw("error decoding request", "error", err)
+		writeError(w, r, http.StatusBadRequest, codeBadRequest, "invalid request body")
+		return
+	}
+
+	c, err := s.rpo.ModerateComment(r.Context(), id, status, req.Reason, uploaderFromContext(r.Context()))
+	if err != nil {
+		s.writeRepoError(w, r, err, "error moderating comment")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(c); err != nil {
+		logging.FromRequest(s.logger, r).Errorw("error encoding comment", "error", err)
+	}
+}
+
+// deleteCommentHandler godoc
+// @Summary Delete a comment
+// @Description Delete a comment
+// @Tags comments
+// @Param id path string true "Comment ID"
+// @Router /api/pics/comments/delete/{id} [delete]
+// @Security Bearer
+// @Success 204
+// @Failure 400,401,403,404,500 {object} errorResponse
+func (s *handler) deleteCommentHandler(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+
+	if err := s.rpo.DeleteComment(r.Context(), id); err != nil {
+		s.writeRepoError(w, r, err, "error deleting comment")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -1,15 +1,17 @@
 package api
 
 import (
-	"database/sql"
 	"encoding/json"
-	"errors"
 	"net/http"
-	"os"
-	"strings"
+	"strconv"
 	"time"
 
 	"github.com/go-chi/chi/v5"
+
+	"github.com/btschwartz12/site/internal/ipdata"
+	"github.com/btschwartz12/site/internal/logging"
+	"github.com/btschwartz12/site/internal/repo"
+	"github.com/btschwartz12/site/internal/storage"
 )
 
 // uploadFileHandler godoc
@@ -20,38 +22,42 @@ import (
 // @Produce json
 // @Param file formData file true "File"
 // @Param notes formData string true "Notes"
+// @Param ttl formData string false "Delete the file after this long (300s, 2h45m, etc.)"
 // @Router /api/drive/upload [post]
 // @Security Bearer
 // @Success 200 {object} repo.File
+// @Failure 400,401,403,413,500,507 {object} errorResponse
 func (s *handler) uploadFileHandler(w http.ResponseWriter, r *http.Request) {
 	file, header, err := r.FormFile("file")
 	if err != nil {
-		s.logger.Errorw("error getting file from form", "error", err)
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		writeError(w, r, http.StatusBadRequest, codeBadRequest, err.Error())
 		return
 	}
 	defer file.Close()
 
 	notes := r.FormValue("notes")
 	if notes == "" {
-		http.Error(w, "notes is required", http.StatusBadRequest)
+		writeError(w, r, http.StatusBadRequest, codeBadRequest, "notes is required")
 		return
 	}
 
-	f, err := s.rpo.InsertFile(r.Context(), file, header, notes)
-	if err != nil {
-		if strings.Contains(err.Error(), "file too large") {
-			http.Error(w, "File Too Large", http.StatusBadRequest)
+	var ttl time.Duration
+	if v := r.FormValue("ttl"); v != "" {
+		ttl, err = time.ParseDuration(v)
+		if err != nil || ttl <= 0 {
+			writeError(w, r, http.StatusBadRequest, codeBadRequest, "invalid ttl: must be in correct format (300s, 2h45m, etc.)")
 			return
 		}
-		s.logger.Errorw("error inserting file", "error", err)
-		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+	}
+
+	f, err := s.rpo.InsertFile(r.Context(), file, header, notes, uploaderFromContext(r.Context()), ttl)
+	if err != nil {
+		s.writeRepoError(w, r, err, "error inserting file")
 		return
 	}
 
 	if err := json.NewEncoder(w).Encode(f); err != nil {
-		s.logger.Errorw("error encoding picture", "error", err)
-		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		logging.FromRequest(s.logger, r).Errorw("error encoding picture", "error", err)
 	}
 }
 
@@ -63,23 +69,131 @@ func (s *handler) uploadFileHandler(w http.ResponseWriter, r *http.Request) {
 // @Router /api/drive/files/{id} [get]
 // @Security Bearer
 // @Success 200 {object} repo.File
+// @Failure 400,401,403,404,500 {object} errorResponse
 func (s *handler) getFileHandler(w http.ResponseWriter, r *http.Request) {
 	id := chi.URLParam(r, "id")
 	if id == "" {
-		http.Error(w, "id is required", http.StatusBadRequest)
+		writeError(w, r, http.StatusBadRequest, codeBadRequest, "id is required")
 		return
 	}
 
 	f, err := s.rpo.GetFile(r.Context(), id)
 	if err != nil {
-		s.logger.Errorw("error getting file", "error", err)
-		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		s.writeRepoError(w, r, err, "error getting file")
+		return
+	}
+
+	if err := json.NewEncoder(w).Encode(f); err != nil {
+		logging.FromRequest(s.logger, r).Errorw("error encoding picture", "error", err)
+	}
+}
+
+type updateFileRequest struct {
+	Name  *string `json:"name"`
+	Notes *string `json:"notes"`
+	// TTL resets the expiry relative to now; "0" removes it
+	TTL *string `json:"ttl"`
+}
+
+// updateFileHandler godoc
+// @Summary Update a file
+// @Description Rename a file, edit its notes or change its expiry
+// @Tags drive
+// @Param id path string true "File ID"
+// @Param body body updateFileRequest true "Changes"
+// @Accept json
+// @Produce json
+// @Router /api/drive/files/{id} [patch]
+// @Security Bearer
+// @Success 200 {object} repo.File
+// @Failure 400,401,403,404,500 {object} errorResponse
+func (s *handler) updateFileHandler(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+
+	var req updateFileRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		logging.FromRequest(s.logger, r).Errorw("error decoding request", "error", err)
+		writeError(w, r, http.StatusBadRequest, codeBadRequest, "invalid request body")
+		return
+	}
+
+	upd := repo.FileUpdate{
+		Name:  req.Name,
+		Notes: req.Notes,
+	}
+	if upd.Name != nil && *upd.Name == "" {
+		writeError(w, r, http.StatusBadRequest, codeBadRequest, "name cannot be empty")
+		return
+	}
+	if upd.Notes != nil && *upd.Notes == "" {
+		writeError(w, r, http.StatusBadRequest, codeBadRequest, "notes cannot be empty")
+		return
+	}
+	if req.TTL != nil {
+		ttl, err := time.ParseDuration(*req.TTL)
+		if err != nil || ttl < 0 {
+			writeError(w, r, http.StatusBadRequest, codeBadRequest, "invalid ttl: must be in correct format (300s, 2h45m, etc.)")
+			return
+		}
+		expires := time.Time{}
+		if ttl > 0 {
+			expires = time.Now().Add(ttl)
+		}
+		upd.Expires = &expires
+	}
+
+	f, err := s.rpo.UpdateFile(r.Context(), id, upd)
+	if err != nil {
+		s.writeRepoError(w, r, err, "error updating file")
+		return
+	}
+
+	if err := json.NewEncoder(w).Encode(f); err != nil {
+		logging.FromRequest(s.logger, r).Errorw("error encoding file", "error", err)
+	}
+}
+
+// deleteFileHandler godoc
+// @Summary Delete a file
+// @Description Move a file to the trash, taking its permalinks with it. It can be restored until the janitor purges the trash.
+// @Tags drive
+// @Param id path string true "File ID"
+// @Router /api/drive/files/{id} [delete]
+// @Security Bearer
+// @Success 204
+// @Failure 400,401,403,404,500 {object} errorResponse
+func (s *handler) deleteFileHandler(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+
+	err := s.rpo.DeleteFile(r.Context(), id)
+	if err != nil {
+		s.writeRepoError(w, r, err, "error deleting file")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// restoreFileHandler godoc
+// @Summary Restore a file
+// @Description Take a file back out of the trash, along with its permalinks
+// @Tags drive
+// @Param id path string true "File ID"
+// @Produce json
+// @Router /api/drive/files/{id}/restore [post]
+// @Security Bearer
+// @Success 200 {object} repo.File
+// @Failure 400,401,403,404,500 {object} errorResponse
+func (s *handler) restoreFileHandler(w http.ResponseWriter, r *http.Request) {
+	f, err := s.rpo.RestoreFile(r.Context(), chi.URLParam(r, "id"))
+	if err != nil {
+		s.writeRepoError(w, r, err, "error restoring file")
 		return
 	}
 
+	w.Header().Set("Content-Type", "application/json")
 	if err := json.NewEncoder(w).Encode(f); err != nil {
-		s.logger.Errorw("error encoding picture", "error", err)
-		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		logging.FromRequest(s.logger, r).Errorw("error encoding file", "error", err)
 	}
 }
 
@@ -87,24 +201,66 @@ func (s *handler) getFileHandler(w http.ResponseWriter, r *http.Request) {
 // @Summary Get all files
 // @Description Get all files
 // @Tags drive
+// @Param limit query int false "Page size, 50 by default and at most 500"
+// @Param cursor query string false "Cursor from the previous page"
+// @Param order query string false "asc or desc (the default) by creation time"
+// @Param since query string false "Only items created at or after this time (RFC 3339 or YYYY-MM-DD)"
+// @Param until query string false "Only items created before this time (RFC 3339 or YYYY-MM-DD)"
+// @Param extension query string false "Only files with this extension"
 // @Router /api/drive/files [get]
 // @Security Bearer
 // @Success 200 {array} repo.File
+// @Header 200 {string} Link "Link to the next page, if any"
+// @Header 200 {string} X-Next-Cursor "Cursor for the next page, if any"
+// @Failure 400,401,403,500 {object} errorResponse
 func (s *handler) getFilesHandler(w http.ResponseWriter, r *http.Request) {
-	files, err := s.rpo.GetAllFiles(r.Context())
+	s.listFiles(w, r, false)
+}
+
+// getFilesTrashHandler godoc
+// @Summary Get trashed files
+// @Description Get the files in the trash
+// @Tags drive
+// @Param limit query int false "Page size, 50 by default and at most 500"
+// @Param cursor query string false "Cursor from the previous page"
+// @Param order query string false "asc or desc (the default) by creation time"
+// @Param since query string false "Only items created at or after this time (RFC 3339 or YYYY-MM-DD)"
+// @Param until query string false "Only items created before this time (RFC 3339 or YYYY-MM-DD)"
+// @Param extension query string false "Only files with this extension"
+// @Router /api/drive/trash [get]
+// @Security Bearer
+// @Success 200 {array} repo.File
+// @Header 200 {string} Link "Link to the next page, if any"
+// @Header 200 {string} X-Next-Cursor "Cursor for the next page, if any"
+// @Failure 400,401,403,500 {object} errorResponse
+func (s *handler) getFilesTrashHandler(w http.ResponseWriter, r *http.Request) {
+	s.listFiles(w, r, true)
+}
+
+func (s *handler) listFiles(w http.ResponseWriter, r *http.Request, deleted bool) {
+	opts, err := parseListOptions(r)
+	if err != nil {
+		writeError(w, r, http.StatusBadRequest, codeBadRequest, err.Error())
+		return
+	}
+	files, next, err := s.rpo.ListFiles(r.Context(), repo.FileFilter{
+		ListOptions: opts,
+		Extension:   r.URL.Query().Get("extension"),
+		Deleted:     deleted,
+	})
 	if err != nil {
-		s.logger.Errorw("error getting files", "error", err)
-		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		s.writeRepoError(w, r, err, "error getting files")
 		return
 	}
 
 	resp, err := json.MarshalIndent(files, "", " \t")
 	if err != nil {
-		s.logger.Errorw("error marshalling files", "error", err)
-		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		logging.FromRequest(s.logger, r).Errorw("error marshalling files", "error", err)
+		writeError(w, r, http.StatusInternalServerError, codeInternal, "internal server error")
 		return
 	}
 
+	setPageHeaders(w, r, next)
 	w.Header().Set("Content-Type", "application/json")
 	w.Write(resp)
 }
@@ -113,41 +269,59 @@ func (s *handler) getFilesHandler(w http.ResponseWriter, r *http.Request) {
 // @Summary Generate a permalink
 // @Description Generate a permalink
 // @Tags drive
-// @Param file_id formData string true "File ID"
-// @Param duration formData string true "Duration (300s, 2h45m, etc.)"
+// @Accept x-www-form-urlencoded
+// @Param id path string true "File ID"
+// @Param duration_seconds formData string true "How long the permalink lasts, as a duration (300s, 2h45m, etc.)"
+// @Param max_downloads formData int false "Maximum number of downloads"
+// @Param password formData string false "Password required to download"
 // @Router /api/drive/files/{id}/permalink [post]
 // @Security Bearer
 // @Success 200 {object} repo.Permalink
+// @Failure 400,401,403,404,500 {object} errorResponse
 func (s *handler) generatePermalinkHandler(w http.ResponseWriter, r *http.Request) {
 	fileId := chi.URLParam(r, "id")
 	if fileId == "" {
-		http.Error(w, "id is required", http.StatusBadRequest)
+		writeError(w, r, http.StatusBadRequest, codeBadRequest, "id is required")
 		return
 	}
 
 	dur := r.FormValue("duration_seconds")
 	if dur == "" {
-		http.Error(w, "duration_seconds is required", http.StatusBadRequest)
+		writeError(w, r, http.StatusBadRequest, codeBadRequest, "duration_seconds is required")
 		return
 	}
 
 	duration, err := time.ParseDuration(dur)
 	if err != nil {
-		http.Error(w, "invalid duration: must be in correct format (300s, 2h45m, etc.)", http.StatusBadRequest)
+		writeError(w, r, http.StatusBadRequest, codeBadRequest, "invalid duration: must be in correct format (300s, 2h45m, etc.)")
+		return
+	}
+	if duration < time.Second {
+		writeError(w, r, http.StatusBadRequest, codeBadRequest, "invalid duration: must be at least 1s")
 		return
 	}
 
-	p, err := s.rpo.InsertPermalink(r.Context(), fileId, int64(duration.Seconds()))
+	opts := repo.PermalinkOptions{
+		Password: r.FormValue("password"),
+	}
+	if v := r.FormValue("max_downloads"); v != "" {
+		opts.MaxDownloads, err = strconv.ParseInt(v, 10, 64)
+		if err != nil || opts.MaxDownloads <= 0 {
+			writeError(w, r, http.StatusBadRequest, codeBadRequest, "invalid max_downloads: must be a positive integer")
+			return
+		}
+	}
+
+	p, err := s.rpo.InsertPermalink(r.Context(), fileId, int64(duration.Seconds()), opts)
 	if err != nil {
-		s.logger.Errorw("error generating permalink", "error", err)
-		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		s.writeRepoError(w, r, err, "error generating permalink")
 		return
 	}
 
 	resp, err := json.MarshalIndent(p, "", " \t")
 	if err != nil {
-		s.logger.Errorw("error marshalling permalink", "error", err)
-		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		logging.FromRequest(s.logger, r).Errorw("error marshalling permalink", "error", err)
+		writeError(w, r, http.StatusInternalServerError, codeInternal, "internal server error")
 		return
 	}
 
@@ -159,24 +333,41 @@ func (s *handler) generatePermalinkHandler(w http.ResponseWriter, r *http.Reques
 // @Summary Get all permalinks
 // @Description Get all permalinks
 // @Tags drive
+// @Param limit query int false "Page size, 50 by default and at most 500"
+// @Param cursor query string false "Cursor from the previous page"
+// @Param order query string false "asc or desc (the default) by creation time"
+// @Param since query string false "Only items created at or after this time (RFC 3339 or YYYY-MM-DD)"
+// @Param until query string false "Only items created before this time (RFC 3339 or YYYY-MM-DD)"
+// @Param file_id query string false "Only permalinks to this file"
 // @Router /api/drive/files/permalinks [get]
 // @Security Bearer
 // @Success 200 {array} repo.Permalink
+// @Header 200 {string} Link "Link to the next page, if any"
+// @Header 200 {string} X-Next-Cursor "Cursor for the next page, if any"
+// @Failure 400,401,403,500 {object} errorResponse
 func (s *handler) getPermalinksHandler(w http.ResponseWriter, r *http.Request) {
-	permalinks, err := s.rpo.GetAllPermalinks(r.Context())
+	opts, err := parseListOptions(r)
+	if err != nil {
+		writeError(w, r, http.StatusBadRequest, codeBadRequest, err.Error())
+		return
+	}
+	permalinks, next, err := s.rpo.ListPermalinks(r.Context(), repo.PermalinkFilter{
+		ListOptions: opts,
+		FileUuid:    r.URL.Query().Get("file_id"),
+	})
 	if err != nil {
-		s.logger.Errorw("error getting permalinks", "error", err)
-		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		s.writeRepoError(w, r, err, "error getting permalinks")
 		return
 	}
 
 	resp, err := json.MarshalIndent(permalinks, "", " \t")
 	if err != nil {
-		s.logger.Errorw("error marshalling permalinks", "error", err)
-		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		logging.FromRequest(s.logger, r).Errorw("error marshalling permalinks", "error", err)
+		writeError(w, r, http.StatusInternalServerError, codeInternal, "internal server error")
 		return
 	}
 
+	setPageHeaders(w, r, next)
 	w.Header().Set("Content-Type", "application/json")
 	w.Write(resp)
 }
@@ -189,29 +380,79 @@ func (s *handler) getPermalinksHandler(w http.ResponseWriter, r *http.Request) {
 // @Router /api/drive/files/permalinks/{id}/ [get]
 // @Security Bearer
 // @Success 200
+// @Failure 400,401,403,404,410,500 {object} errorResponse
 func (s *handler) servePermalinkHandler(w http.ResponseWriter, r *http.Request) {
 	permalinkId := chi.URLParam(r, "id")
 	if permalinkId == "" {
-		http.Error(w, "permalink_id is required", http.StatusBadRequest)
+		writeError(w, r, http.StatusBadRequest, codeBadRequest, "permalink_id is required")
 		return
 	}
 
-	p, err := s.rpo.GetPermalink(r.Context(), permalinkId)
+	p, err := s.rpo.AccessPermalink(r.Context(), permalinkId, repo.PermalinkRequest{
+		Ip:           ipdata.GetIp(r).String(),
+		UserAgent:    r.UserAgent(),
+		SkipPassword: true,
+		Range:        r.Header.Get("Range"),
+		IfRange:      r.Header.Get("If-Range"),
+	})
 	if err != nil {
-		if errors.Is(err, sql.ErrNoRows) {
-			http.Error(w, "permalink not found", http.StatusNotFound)
-			return
-		}
-		s.logger.Errorw("error getting permalink", "error", err)
-		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		s.writeRepoError(w, r, err, "error getting permalink")
+		return
+	}
+
+	storage.ServeContent(w, r, s.rpo.Blobs(), p.File.Content())
+}
+
+// revokePermalinkHandler godoc
+// @Summary Revoke a permalink
+// @Description Revoke a permalink so it can no longer be used
+// @Tags drive
+// @Param id path string true "Permalink ID"
+// @Produce json
+// @Router /api/drive/files/permalinks/{id} [delete]
+// @Security Bearer
+// @Success 200 {object} repo.Permalink
+// @Failure 400,401,403,404,500 {object} errorResponse
+func (s *handler) revokePermalinkHandler(w http.ResponseWriter, r *http.Request) {
+	permalinkId := chi.URLParam(r, "id")
+
+	p, err := s.rpo.RevokePermalink(r.Context(), permalinkId)
+	if err != nil {
+		s.writeRepoError(w, r, err, "error revoking permalink")
+		return
+	}
+
+	if err := json.NewEncoder(w).Encode(p); err != nil {
+		logging.FromRequest(s.logger, r).Errorw("error encoding permalink", "error", err)
+	}
+}
+
+// getPermalinkAccessesHandler godoc
+// @Summary Get permalink accesses
+// @Description Get every attempt to download a permalink, newest first
+// @Tags drive
+// @Param id path string true "Permalink ID"
+// @Produce json
+// @Router /api/drive/files/permalinks/{id}/accesses [get]
+// @Security Bearer
+// @Success 200 {array} repo.PermalinkAccess
+// @Failure 400,401,403,404,500 {object} errorResponse
+func (s *handler) getPermalinkAccessesHandler(w http.ResponseWriter, r *http.Request) {
+	permalinkId := chi.URLParam(r, "id")
+
+	accesses, err := s.rpo.GetPermalinkAccesses(r.Context(), permalinkId)
+	if err != nil {
+		s.writeRepoError(w, r, err, "error getting permalink accesses")
 		return
 	}
 
-	if _, err := os.Stat(p.File.Url); os.IsNotExist(err) {
-		s.logger.Errorw("error getting file", "error", err)
-		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+	resp, err := json.MarshalIndent(accesses, "", " \t")
+	if err != nil {
+		logging.FromRequest(s.logger, r).Errorw("error marshalling permalink accesses", "error", err)
+		writeError(w, r, http.StatusInternalServerError, codeInternal, "internal server error")
 		return
 	}
 
-	http.ServeFile(w, r, p.File.Url)
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(resp)
 }
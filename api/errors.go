@@ -0,0 +1,106 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/go-chi/chi/v5/middleware"
+
+	"github.com/btschwartz12/site/internal/logging"
+	"github.com/btschwartz12/site/internal/repo"
+)
+
+// Error codes, so clients don't have to match on messages.
+const (
+	codeBadRequest       = "bad_request"
+	codeInvalidID        = "invalid_id"
+	codeInvalidCursor    = "invalid_cursor"
+	codeUnauthorized     = "unauthorized"
+	codeForbidden        = "forbidden"
+	codeNotFound         = "not_found"
+	codeAlreadyExists    = "already_exists"
+	codeOffsetMismatch   = "offset_mismatch"
+	codeUploadIncomplete = "upload_incomplete"
+	codeTooManyUploads   = "too_many_uploads"
+	codePasswordRequired = "password_required"
+	codeInvalidPassword  = "invalid_password"
+	codeUnavailable      = "unavailable"
+	codeTooLarge         = "too_large"
+	codeInvalidExtension = "invalid_extension"
+	codeInvalidImage     = "invalid_image"
+	codeLengthRequired   = "length_required"
+	codeStorageFull      = "storage_full"
+	codeInternal         = "internal_error"
+)
+
+// errorResponse is the body of every error the api returns.
+type errorResponse struct {
+	Code    string `json:"code" example:"not_found"`
+	Message string `json:"message" example:"not found"`
+	// Details has more about some errors, like the offset to resume
+	// an upload from
+	Details   map[string]any `json:"details,omitempty"`
+	RequestID string         `json:"request_id,omitempty" example:"host/abcdef-000001"`
+} // @name Error
+
+// repoErrors are the statuses and codes for the repo's errors.
+var repoErrors = []struct {
+	err    error
+	status int
+	code   string
+}{
+	{repo.ErrNotFound, http.StatusNotFound, codeNotFound},
+	{repo.ErrInvalidID, http.StatusBadRequest, codeInvalidID},
+	{repo.ErrInvalidInput, http.StatusBadRequest, codeBadRequest},
+	{repo.ErrInvalidCursor, http.StatusBadRequest, codeInvalidCursor},
+	{repo.ErrAlreadyExists, http.StatusConflict, codeAlreadyExists},
+	{repo.ErrOffsetMismatch, http.StatusConflict, codeOffsetMismatch},
+	{repo.ErrUploadIncomplete, http.StatusConflict, codeUploadIncomplete},
+	{repo.ErrTooManyUploads, http.StatusTooManyRequests, codeTooManyUploads},
+	{repo.ErrPasswordRequired, http.StatusUnauthorized, codePasswordRequired},
+	{repo.ErrInvalidPassword, http.StatusForbidden, codeInvalidPassword},
+	{repo.ErrUnavailable, http.StatusGone, codeUnavailable},
+	{repo.ErrTooLarge, http.StatusRequestEntityTooLarge, codeTooLarge},
+	{repo.ErrInvalidExtension, http.StatusUnsupportedMediaType, codeInvalidExtension},
+	{repo.ErrInvalidImage, http.StatusUnsupportedMediaType, codeInvalidImage},
+	{repo.ErrStorageFull, http.StatusInsufficientStorage, codeStorageFull},
+	{repo.ErrInvalidToken, http.StatusUnauthorized, codeUnauthorized},
+}
+
+// writeError replies with an errorResponse.
+func writeError(w http.ResponseWriter, r *http.Request, status int, code string, message string) {
+	writeErrorDetails(w, r, status, code, message, nil)
+}
+
+func writeErrorDetails(w http.ResponseWriter, r *http.Request, status int, code string, message string, details map[string]any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(errorResponse{
+		Code:      code,
+		Message:   message,
+		Details:   details,
+		RequestID: middleware.GetReqID(r.Context()),
+	})
+}
+
+// writeRepoError replies to an error from the repo with the status
+// and code for it. Anything unexpected is logged with msg and hidden
+// behind a 500.
+func (s *handler) writeRepoError(w http.ResponseWriter, r *http.Request, err error, msg string) {
+	for _, e := range repoErrors {
+		if !errors.Is(err, e.err) {
+			continue
+		}
+		message := err.Error()
+		if e.err == repo.ErrNotFound {
+			// the wrapped sql error says nothing useful to clients
+			message = "not found"
+		}
+		writeError(w, r, e.status, e.code, message)
+		return
+	}
+	logging.FromRequest(s.logger, r).Errorw(msg, "error", err)
+	writeError(w, r, http.StatusInternalServerError, codeInternal, "internal server error")
+}
@@ -0,0 +1,74 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"github.com/btschwartz12/site/internal/logging"
+)
+
+// getJanitorReportHandler godoc
+// @Summary Get the last janitor report
+// @Description Get what the most recent janitor run cleaned up, or would have in dry-run mode
+// @Tags janitor
+// @Produce json
+// @Router /api/janitor [get]
+// @Security Bearer
+// @Success 200 {object} repo.JanitorReport
+// @Failure 401,403,404,500 {object} errorResponse
+func (s *handler) getJanitorReportHandler(w http.ResponseWriter, r *http.Request) {
+	report := s.rpo.LastJanitorReport()
+	if report == nil {
+		writeError(w, r, http.StatusNotFound, codeNotFound, "janitor has not run yet")
+		return
+	}
+
+	resp, err := json.MarshalIndent(report, "", " \t")
+	if err != nil {
+		logging.FromRequest(s.logger, r).Errorw("error marshalling janitor report", "error", err)
+		writeError(w, r, http.StatusInternalServerError, codeInternal, "internal server error")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(resp)
+}
+
+// runJanitorHandler godoc
+// @Summary Run the janitor
+// @Description Run the janitor now instead of waiting for the next scheduled run
+// @Tags janitor
+// @Param dry_run query bool false "Only report what would be cleaned up"
+// @Produce json
+// @Router /api/janitor/run [post]
+// @Security Bearer
+// @Success 200 {object} repo.JanitorReport
+// @Failure 400,401,403,500 {object} errorResponse
+func (s *handler) runJanitorHandler(w http.ResponseWriter, r *http.Request) {
+	dryRun := false
+	if v := r.URL.Query().Get("dry_run"); v != "" {
+		var err error
+		dryRun, err = strconv.ParseBool(v)
+		if err != nil {
+			writeError(w, r, http.StatusBadRequest, codeBadRequest, "invalid dry_run")
+			return
+		}
+	}
+
+	report, err := s.rpo.Janitor(r.Context(), dryRun)
+	if err != nil {
+		s.writeRepoError(w, r, err, "error running janitor")
+		return
+	}
+
+	resp, err := json.MarshalIndent(report, "", " \t")
+	if err != nil {
+		logging.FromRequest(s.logger, r).Errorw("error marshalling janitor report", "error", err)
+		writeError(w, r, http.StatusInternalServerError, codeInternal, "internal server error")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(resp)
+}
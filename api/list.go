@@ -0,0 +1,70 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/btschwartz12/site/internal/repo"
+)
+
+// parseListOptions reads the paging, ordering and date range query
+// parameters shared by every list endpoint.
+func parseListOptions(r *http.Request) (repo.ListOptions, error) {
+	opts := repo.ListOptions{Cursor: r.URL.Query().Get("cursor")}
+
+	if v := r.URL.Query().Get("limit"); v != "" {
+		limit, err := strconv.Atoi(v)
+		if err != nil || limit < 1 || limit > repo.MaxPageSize {
+			return opts, fmt.Errorf("limit must be between 1 and %d", repo.MaxPageSize)
+		}
+		opts.Limit = limit
+	}
+
+	switch r.URL.Query().Get("order") {
+	case "", "desc":
+	case "asc":
+		opts.Ascending = true
+	default:
+		return opts, fmt.Errorf("order must be asc or desc")
+	}
+
+	var err error
+	if opts.Since, err = parseListTime(r, "since"); err != nil {
+		return opts, err
+	}
+	if opts.Until, err = parseListTime(r, "until"); err != nil {
+		return opts, err
+	}
+	return opts, nil
+}
+
+// parseListTime reads a query parameter given as RFC 3339 or as a
+// plain date, which means midnight UTC.
+func parseListTime(r *http.Request, name string) (time.Time, error) {
+	v := r.URL.Query().Get(name)
+	if v == "" {
+		return time.Time{}, nil
+	}
+	for _, layout := range []string{time.RFC3339, time.DateOnly} {
+		if t, err := time.Parse(layout, v); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("%s must be an RFC 3339 time or a date", name)
+}
+
+// setPageHeaders points the client at the next page, if there is one,
+// with the same query apart from the cursor.
+func setPageHeaders(w http.ResponseWriter, r *http.Request, next string) {
+	if next == "" {
+		return
+	}
+	u := *r.URL
+	q := u.Query()
+	q.Set("cursor", next)
+	u.RawQuery = q.Encode()
+	w.Header().Set("X-Next-Cursor", next)
+	w.Header().Set("Link", fmt.Sprintf(`<%s>; rel="next"`, u.RequestURI()))
+}
@@ -1,16 +1,118 @@
 package api
 
 import (
+	"context"
+	"crypto/subtle"
+	"fmt"
 	"net/http"
+	"strings"
+
+	"github.com/btschwartz12/site/internal/logging"
+	"github.com/btschwartz12/site/internal/repo"
+)
+
+const (
+	scopePicsRead     = "pics:read"
+	scopePicsWrite    = "pics:write"
+	scopePicsModerate = "pics:moderate"
+	scopeDriveRead    = "drive:read"
+	scopeDriveWrite   = "drive:write"
+	scopeVisitorsRead = "visitors:read"
+	scopeStorageRead  = "storage:read"
 )
 
+var knownScopes = map[string]bool{
+	repo.AdminScope:   true,
+	scopePicsRead:     true,
+	scopePicsWrite:    true,
+	scopePicsModerate: true,
+	scopeDriveRead:    true,
+	scopeDriveWrite:   true,
+	scopeVisitorsRead: true,
+	scopeStorageRead:  true,
+}
+
+type contextKey int
+
+const tokenContextKey contextKey = iota
+
+// tokenMiddleware authenticates the bearer token on the request and
+// stores it in the request context for requireScope.
 func (s *handler) tokenMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		if r.Header.Get("Authorization") != s.token {
-			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		secret, ok := bearerToken(r)
+		if !ok {
+			writeError(w, r, http.StatusUnauthorized, codeUnauthorized, "missing or invalid token")
 			return
 		}
 
-		next.ServeHTTP(w, r)
+		token, err := s.authenticate(r.Context(), secret)
+		if err != nil {
+			logging.FromRequest(s.logger, r).Infow("rejected api token", "error", err)
+			writeError(w, r, http.StatusUnauthorized, codeUnauthorized, "missing or invalid token")
+			return
+		}
+
+		ctx := context.WithValue(r.Context(), tokenContextKey, token)
+		next.ServeHTTP(w, r.WithContext(ctx))
 	})
 }
+
+// requireScope rejects requests whose token does not grant scope.
+// It must run after tokenMiddleware.
+func requireScope(scope string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			token := tokenFromContext(r.Context())
+			if token == nil || !token.HasScope(scope) {
+				writeError(w, r, http.StatusForbidden, codeForbidden, "token is missing scope "+scope)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+func (s *handler) authenticate(ctx context.Context, secret string) (*repo.ApiToken, error) {
+	// the API_TOKEN env var acts as a bootstrap admin token, so the
+	// first real tokens can be minted. It stops working once they
+	// have been, so it can't linger as a permanent admin credential.
+	if s.token != "" && subtle.ConstantTimeCompare([]byte(secret), []byte(s.token)) == 1 {
+		minted, err := s.rpo.HasApiTokens(ctx)
+		if err != nil {
+			return nil, err
+		}
+		if minted {
+			return nil, fmt.Errorf("%w: bootstrap token is disabled once a token has been created", repo.ErrInvalidToken)
+		}
+		return &repo.ApiToken{
+			Name:   "bootstrap",
+			Scopes: []string{repo.AdminScope},
+		}, nil
+	}
+	return s.rpo.AuthenticateApiToken(ctx, secret)
+}
+
+func tokenFromContext(ctx context.Context) *repo.ApiToken {
+	token, _ := ctx.Value(tokenContextKey).(*repo.ApiToken)
+	return token
+}
+
+// uploaderFromContext identifies the token that made the request,
+// for per-uploader storage quotas.
+func uploaderFromContext(ctx context.Context) string {
+	token := tokenFromContext(ctx)
+	if token == nil {
+		return "api"
+	}
+	return "api:" + token.Name
+}
+
+// bearerToken extracts the token from the Authorization header. The
+// "Bearer " prefix is optional so older clients sending the bare
+// token keep working.
+func bearerToken(r *http.Request) (string, bool) {
+	h := r.Header.Get("Authorization")
+	h = strings.TrimSpace(strings.TrimPrefix(h, "Bearer "))
+	return h, h != ""
+}
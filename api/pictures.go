@@ -1,12 +1,15 @@
 package api
 
 import (
-	"database/sql"
 	"encoding/json"
 	"errors"
+	"io"
 	"net/http"
-	"strings"
+	"strconv"
 
+	"github.com/btschwartz12/site/internal/logging"
+	"github.com/btschwartz12/site/internal/repo"
+	"github.com/btschwartz12/site/internal/storage"
 	"github.com/go-chi/chi/v5"
 )
 
@@ -18,99 +21,158 @@ import (
 // @Produce json
 // @Param file formData file true "Picture file"
 // @Param description formData string true "Description of the picture"
+// @Param author formData string true "Author of the picture"
 // @Router /api/pics/upload [post]
 // @Security Bearer
 // @Success 200 {object} repo.Picture
+// @Failure 400,401,403,413,415,500,507 {object} errorResponse
 func (s *handler) uploadPictureHandler(w http.ResponseWriter, r *http.Request) {
 	file, header, err := r.FormFile("file")
 	if err != nil {
-		s.logger.Errorw("error getting file from form", "error", err)
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		writeError(w, r, http.StatusBadRequest, codeBadRequest, err.Error())
 		return
 	}
 	defer file.Close()
 
 	description := r.FormValue("description")
 	if description == "" {
-		http.Error(w, "description is required", http.StatusBadRequest)
+		writeError(w, r, http.StatusBadRequest, codeBadRequest, "description is required")
 		return
 	}
 
 	author := r.FormValue("author")
 	if author == "" {
-		http.Error(w, "author is required", http.StatusBadRequest)
+		writeError(w, r, http.StatusBadRequest, codeBadRequest, "author is required")
 		return
 	}
 
-	p, err := s.rpo.InsertPicture(r.Context(), file, header, author, description)
+	p, err := s.rpo.InsertPicture(r.Context(), file, header, author, description, uploaderFromContext(r.Context()))
 	if err != nil {
-		if strings.Contains(err.Error(), "invalid extension") {
-			http.Error(w, "Invalid Extension", http.StatusBadRequest)
-			return
-		}
-		if strings.Contains(err.Error(), "file too large") {
-			http.Error(w, "File Too Large", http.StatusBadRequest)
-			return
-		}
-		s.logger.Errorw("error inserting picture", "error", err)
-		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		s.writeRepoError(w, r, err, "error inserting picture")
 		return
 	}
 
 	if err := json.NewEncoder(w).Encode(p); err != nil {
-		s.logger.Errorw("error encoding picture", "error", err)
-		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		logging.FromRequest(s.logger, r).Errorw("error encoding picture", "error", err)
 	}
 }
 
 // deletePictureHandler godoc
 // @Summary Delete a picture
-// @Description Delete a picture
+// @Description Move a picture to the trash. It can be restored until the janitor purges the trash.
 // @Tags pictures
 // @Param id path string true "Picture ID"
 // @Router /api/pics/delete/{id} [delete]
 // @Security Bearer
 // @Success 204
+// @Failure 400,401,403,404,500 {object} errorResponse
 func (s *handler) deletePictureHandler(w http.ResponseWriter, r *http.Request) {
 	id := chi.URLParam(r, "id")
 
 	err := s.rpo.DeletePicture(r.Context(), id)
 	if err != nil {
-		if errors.Is(err, sql.ErrNoRows) {
-			http.Error(w, "Not Found", http.StatusNotFound)
-			return
-		}
-		s.logger.Errorw("error deleting picture", "error", err)
-		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		s.writeRepoError(w, r, err, "error deleting picture")
 		return
 	}
 
 	w.WriteHeader(http.StatusNoContent)
 }
 
+// restorePictureHandler godoc
+// @Summary Restore a picture
+// @Description Take a picture back out of the trash
+// @Tags pictures
+// @Param id path string true "Picture ID"
+// @Produce json
+// @Router /api/pics/restore/{id} [post]
+// @Security Bearer
+// @Success 200 {object} repo.Picture
+// @Failure 400,401,403,404,500 {object} errorResponse
+func (s *handler) restorePictureHandler(w http.ResponseWriter, r *http.Request) {
+	p, err := s.rpo.RestorePicture(r.Context(), chi.URLParam(r, "id"))
+	if err != nil {
+		s.writeRepoError(w, r, err, "error restoring picture")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(p); err != nil {
+		logging.FromRequest(s.logger, r).Errorw("error encoding picture", "error", err)
+	}
+}
+
 // getPicturesHandler godoc
 // @Summary Get pictures
 // @Description Get pictures
 // @Tags pictures
 // @Produce json
+// @Param limit query int false "Page size, 50 by default and at most 500"
+// @Param cursor query string false "Cursor from the previous page"
+// @Param order query string false "asc or desc (the default) by creation time"
+// @Param since query string false "Only items created at or after this time (RFC 3339 or YYYY-MM-DD)"
+// @Param until query string false "Only items created before this time (RFC 3339 or YYYY-MM-DD)"
+// @Param author query string false "Only pictures by this author"
+// @Param extension query string false "Only pictures with this extension"
+// @Param status query string false "Only pictures in this moderation status (pending, approved or rejected)"
 // @Router /api/pics [get]
 // @Security Bearer
 // @Success 200 {array} repo.Picture
+// @Header 200 {string} Link "Link to the next page, if any"
+// @Header 200 {string} X-Next-Cursor "Cursor for the next page, if any"
+// @Failure 400,401,403,500 {object} errorResponse
 func (s *handler) getPicturesHandler(w http.ResponseWriter, r *http.Request) {
-	pictures, err := s.rpo.GetAllPictures(r.Context())
+	s.listPictures(w, r, false)
+}
+
+// getPicturesTrashHandler godoc
+// @Summary Get trashed pictures
+// @Description Get the pictures in the trash
+// @Tags pictures
+// @Produce json
+// @Param limit query int false "Page size, 50 by default and at most 500"
+// @Param cursor query string false "Cursor from the previous page"
+// @Param order query string false "asc or desc (the default) by creation time"
+// @Param since query string false "Only items created at or after this time (RFC 3339 or YYYY-MM-DD)"
+// @Param until query string false "Only items created before this time (RFC 3339 or YYYY-MM-DD)"
+// @Param author query string false "Only pictures by this author"
+// @Param extension query string false "Only pictures with this extension"
+// @Param status query string false "Only pictures in this moderation status (pending, approved or rejected)"
+// @Router /api/pics/trash [get]
+// @Security Bearer
+// @Success 200 {array} repo.Picture
+// @Header 200 {string} Link "Link to the next page, if any"
+// @Header 200 {string} X-Next-Cursor "Cursor for the next page, if any"
+// @Failure 400,401,403,500 {object} errorResponse
+func (s *handler) getPicturesTrashHandler(w http.ResponseWriter, r *http.Request) {
+	s.listPictures(w, r, true)
+}
+
+func (s *handler) listPictures(w http.ResponseWriter, r *http.Request, deleted bool) {
+	opts, err := parseListOptions(r)
 	if err != nil {
-		s.logger.Errorw("error getting pictures", "error", err)
-		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		writeError(w, r, http.StatusBadRequest, codeBadRequest, err.Error())
+		return
+	}
+	pictures, next, err := s.rpo.ListPictures(r.Context(), repo.PictureFilter{
+		ListOptions: opts,
+		Author:      r.URL.Query().Get("author"),
+		Extension:   r.URL.Query().Get("extension"),
+		Status:      r.URL.Query().Get("status"),
+		Deleted:     deleted,
+	})
+	if err != nil {
+		s.writeRepoError(w, r, err, "error getting pictures")
 		return
 	}
 
 	resp, err := json.MarshalIndent(pictures, "", " \t")
 	if err != nil {
-		s.logger.Errorw("error marshalling pictures", "error", err)
-		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		logging.FromRequest(s.logger, r).Errorw("error marshalling pictures", "error", err)
+		writeError(w, r, http.StatusInternalServerError, codeInternal, "internal server error")
 		return
 	}
 
+	setPageHeaders(w, r, next)
 	w.Header().Set("Content-Type", "application/json")
 	w.Write(resp)
 }
@@ -131,29 +193,111 @@ type updateLikesRequest struct {
 // @Router /api/pics/update_likes/{id} [put]
 // @Security Bearer
 // @Success 200 {object} repo.Picture
+// @Failure 400,401,403,404,500 {object} errorResponse
 func (s *handler) updateLikesHandler(w http.ResponseWriter, r *http.Request) {
 	id := chi.URLParam(r, "id")
 
 	var req updateLikesRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		s.logger.Errorw("error decoding request", "error", err)
-		http.Error(w, "Bad Request", http.StatusBadRequest)
+		logging.FromRequest(s.logger, r).Errorw("error decoding request", "error", err)
+		writeError(w, r, http.StatusBadRequest, codeBadRequest, "invalid request body")
 		return
 	}
 
 	p, err := s.rpo.UpdateLikesOfPicture(r.Context(), id, int64(req.NumLikes), int64(req.NumDislikes))
 	if err != nil {
-		if errors.Is(err, sql.ErrNoRows) {
-			http.Error(w, "Not Found", http.StatusNotFound)
-			return
-		}
-		s.logger.Errorw("error updating likes", "error", err)
-		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		s.writeRepoError(w, r, err, "error updating likes")
+		return
+	}
+
+	if err := json.NewEncoder(w).Encode(p); err != nil {
+		logging.FromRequest(s.logger, r).Errorw("error encoding picture", "error", err)
+	}
+}
+
+type moderatePictureRequest struct {
+	Reason string `json:"reason"`
+}
+
+// approvePictureHandler godoc
+// @Summary Approve a picture
+// @Description Approve a picture so it shows in the gallery
+// @Tags pictures
+// @Param id path string true "Picture ID"
+// @Param body body moderatePictureRequest false "Reason"
+// @Accept json
+// @Produce json
+// @Router /api/pics/approve/{id} [post]
+// @Security Bearer
+// @Success 200 {object} repo.Picture
+// @Failure 400,401,403,404,500 {object} errorResponse
+func (s *handler) approvePictureHandler(w http.ResponseWriter, r *http.Request) {
+	s.moderatePicture(w, r, repo.PictureApproved)
+}
+
+// rejectPictureHandler godoc
+// @Summary Reject a picture
+// @Description Reject a picture so it stays out of the gallery
+// @Tags pictures
+// @Param id path string true "Picture ID"
+// @Param body body moderatePictureRequest false "Reason"
+// @Accept json
+// @Produce json
+// @Router /api/pics/reject/{id} [post]
+// @Security Bearer
+// @Success 200 {object} repo.Picture
+// @Failure 400,401,403,404,500 {object} errorResponse
+func (s *handler) rejectPictureHandler(w http.ResponseWriter, r *http.Request) {
+	s.moderatePicture(w, r, repo.PictureRejected)
+}
+
+func (s *handler) moderatePicture(w http.ResponseWriter, r *http.Request, status string) {
+	id := chi.URLParam(r, "id")
+
+	// the reason is optional, and so is the body
+	var req moderatePictureRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && !errors.Is(err, io.EOF) {
+		logging.FromRequest(s.logger, r).Errorw("error decoding request", "error", err)
+		writeError(w, r, http.StatusBadRequest, codeBadRequest, "invalid request body")
+		return
+	}
+
+	p, err := s.rpo.ModeratePicture(r.Context(), id, status, req.Reason, uploaderFromContext(r.Context()))
+	if err != nil {
+		s.writeRepoError(w, r, err, "error moderating picture")
 		return
 	}
 
+	w.Header().Set("Content-Type", "application/json")
 	if err := json.NewEncoder(w).Encode(p); err != nil {
-		s.logger.Errorw("error encoding picture", "error", err)
-		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		logging.FromRequest(s.logger, r).Errorw("error encoding picture", "error", err)
+	}
+}
+
+// servePictureHandler godoc
+// @Summary Get a picture's image
+// @Description Get the image of a picture whatever its moderation status, optionally a thumbnail at least w pixels wide
+// @Tags pictures
+// @Param basename path string true "Picture ID and extension, like 12.png"
+// @Param w query int false "Width"
+// @Router /api/pics/image/{basename} [get]
+// @Security Bearer
+// @Success 200
+// @Failure 400,401,403,404,500 {object} errorResponse
+func (s *handler) servePictureHandler(w http.ResponseWriter, r *http.Request) {
+	p, err := s.rpo.GetPicture(r.Context(), chi.URLParam(r, "basename"))
+	if err != nil {
+		s.writeRepoError(w, r, err, "error getting picture")
+		return
+	}
+
+	var width int64
+	if v := r.URL.Query().Get("w"); v != "" {
+		width, err = strconv.ParseInt(v, 10, 64)
+		if err != nil || width < 0 {
+			writeError(w, r, http.StatusBadRequest, codeBadRequest, "invalid w: must be a non-negative integer")
+			return
+		}
 	}
+	storage.ServeContent(w, r, s.rpo.Blobs(), p.ContentFor(width, false))
 }
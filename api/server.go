@@ -1,6 +1,7 @@
 package api
 
 import (
+	"context"
 	"fmt"
 	"net/http"
 
@@ -37,6 +38,15 @@ func (s *ApiServer) Init(mountPoint string, logger *zap.SugaredLogger, rpo *repo
 		rpo:    rpo,
 		token:  config.Token,
 	}
+	if h.token != "" {
+		minted, err := rpo.HasApiTokens(context.Background())
+		if err != nil {
+			return fmt.Errorf("failed to check for api tokens: %w", err)
+		}
+		if minted {
+			logger.Warnw("API_TOKEN is set but ignored, since api tokens have been created")
+		}
+	}
 
 	s.router.Get("/", http.RedirectHandler("/api/swagger/index.html", http.StatusFound).ServeHTTP)
 	s.router.Get("/swagger.json", func(w http.ResponseWriter, r *http.Request) {
@@ -47,17 +57,52 @@ func (s *ApiServer) Init(mountPoint string, logger *zap.SugaredLogger, rpo *repo
 
 	s.router.Group(func(r chi.Router) {
 		r.Use(h.tokenMiddleware)
-		r.Get("/visitors", h.getVisitorsHandler)
-		r.Get("/pics", h.getPicturesHandler)
-		r.Post("/pics/upload", h.uploadPictureHandler)
-		r.Delete("/pics/delete/{id}", h.deletePictureHandler)
-		r.Put("/pics/update_likes/{id}", h.updateLikesHandler)
-		r.Post("/drive/upload", h.uploadFileHandler)
-		r.Get("/drive/files", h.getFilesHandler)
-		r.Get("/drive/files/{id}", h.getFileHandler)
-		r.Post("/drive/files/{id}/permalink", h.generatePermalinkHandler)
-		r.Get("/drive/files/permalinks", h.getPermalinksHandler)
-		r.Get("/drive/files/permalinks/{id}/", h.servePermalinkHandler)
+		r.With(requireScope(scopeVisitorsRead)).Get("/visitors", h.getVisitorsHandler)
+		r.With(requireScope(scopePicsRead)).Get("/pics", h.getPicturesHandler)
+		r.With(requireScope(scopePicsWrite)).Post("/pics/upload", h.uploadPictureHandler)
+		r.With(requireScope(scopePicsWrite)).Delete("/pics/delete/{id}", h.deletePictureHandler)
+		r.With(requireScope(scopePicsWrite)).Get("/pics/trash", h.getPicturesTrashHandler)
+		r.With(requireScope(scopePicsWrite)).Post("/pics/restore/{id}", h.restorePictureHandler)
+		r.With(requireScope(scopePicsWrite)).Put("/pics/update_likes/{id}", h.updateLikesHandler)
+		r.With(requireScope(scopePicsRead)).Get("/pics/image/{basename}", h.servePictureHandler)
+		r.With(requireScope(scopePicsModerate)).Post("/pics/approve/{id}", h.approvePictureHandler)
+		r.With(requireScope(scopePicsModerate)).Post("/pics/reject/{id}", h.rejectPictureHandler)
+		r.With(requireScope(scopePicsRead)).Get("/pics/comments", h.getCommentsHandler)
+		r.With(requireScope(scopePicsModerate)).Post("/pics/comments/hide/{id}", h.hideCommentHandler)
+		r.With(requireScope(scopePicsModerate)).Post("/pics/comments/show/{id}", h.showCommentHandler)
+		r.With(requireScope(scopePicsModerate)).Delete("/pics/comments/delete/{id}", h.deleteCommentHandler)
+		r.With(requireScope(scopePicsRead)).Get("/pics/albums", h.getAlbumsHandler)
+		r.With(requireScope(scopePicsWrite)).Post("/pics/albums", h.createAlbumHandler)
+		r.With(requireScope(scopePicsWrite)).Delete("/pics/albums/{slug}", h.deleteAlbumHandler)
+		r.With(requireScope(scopePicsWrite)).Put("/pics/albums/{slug}/pictures/{id}", h.addAlbumPictureHandler)
+		r.With(requireScope(scopePicsWrite)).Delete("/pics/albums/{slug}/pictures/{id}", h.removeAlbumPictureHandler)
+		r.With(requireScope(scopePicsWrite)).Post("/pics/tags/{id}", h.tagPictureHandler)
+		r.With(requireScope(scopePicsWrite)).Delete("/pics/tags/{id}/{tag}", h.untagPictureHandler)
+		r.With(requireScope(scopeDriveWrite)).Post("/drive/upload", h.uploadFileHandler)
+		r.With(requireScope(scopeDriveWrite)).Post("/drive/uploads", h.createUploadHandler)
+		r.With(requireScope(scopeDriveWrite)).Get("/drive/uploads/{id}", h.getUploadHandler)
+		r.With(requireScope(scopeDriveWrite)).Patch("/drive/uploads/{id}", h.appendUploadHandler)
+		r.With(requireScope(scopeDriveWrite)).Post("/drive/uploads/{id}/complete", h.completeUploadHandler)
+		r.With(requireScope(scopeDriveWrite)).Delete("/drive/uploads/{id}", h.abortUploadHandler)
+		r.With(requireScope(scopeDriveRead)).Get("/drive/blobs/{sha256}", h.getBlobHandler)
+		r.With(requireScope(scopeDriveWrite)).Post("/drive/blobs/{sha256}/files", h.createFileFromBlobHandler)
+		r.With(requireScope(scopeDriveRead)).Get("/drive/files", h.getFilesHandler)
+		r.With(requireScope(scopeDriveRead)).Get("/drive/files/{id}", h.getFileHandler)
+		r.With(requireScope(scopeDriveWrite)).Patch("/drive/files/{id}", h.updateFileHandler)
+		r.With(requireScope(scopeDriveWrite)).Delete("/drive/files/{id}", h.deleteFileHandler)
+		r.With(requireScope(scopeDriveWrite)).Post("/drive/files/{id}/restore", h.restoreFileHandler)
+		r.With(requireScope(scopeDriveWrite)).Get("/drive/trash", h.getFilesTrashHandler)
+		r.With(requireScope(scopeDriveWrite)).Post("/drive/files/{id}/permalink", h.generatePermalinkHandler)
+		r.With(requireScope(scopeDriveRead)).Get("/drive/files/permalinks", h.getPermalinksHandler)
+		r.With(requireScope(scopeDriveRead)).Get("/drive/files/permalinks/{id}/", h.servePermalinkHandler)
+		r.With(requireScope(scopeDriveRead)).Get("/drive/files/permalinks/{id}/accesses", h.getPermalinkAccessesHandler)
+		r.With(requireScope(scopeDriveWrite)).Delete("/drive/files/permalinks/{id}", h.revokePermalinkHandler)
+		r.With(requireScope(scopeStorageRead)).Get("/storage", h.getStorageUsageHandler)
+		r.With(requireScope(repo.AdminScope)).Get("/janitor", h.getJanitorReportHandler)
+		r.With(requireScope(repo.AdminScope)).Post("/janitor/run", h.runJanitorHandler)
+		r.With(requireScope(repo.AdminScope)).Get("/tokens", h.getTokensHandler)
+		r.With(requireScope(repo.AdminScope)).Post("/tokens", h.createTokenHandler)
+		r.With(requireScope(repo.AdminScope)).Delete("/tokens/{id}", h.revokeTokenHandler)
 	})
 
 	return nil
@@ -70,3 +115,11 @@ func (s *ApiServer) GetRouter() chi.Router {
 func (s *ApiServer) GetMountPoint() string {
 	return s.mountPoint
 }
+
+func (s *ApiServer) Start(ctx context.Context) error {
+	return nil
+}
+
+func (s *ApiServer) Shutdown(ctx context.Context) error {
+	return nil
+}
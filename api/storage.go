@@ -0,0 +1,35 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/btschwartz12/site/internal/logging"
+)
+
+// getStorageUsageHandler godoc
+// @Summary Get storage usage
+// @Description Get bytes charged per area and per uploader, along with the limits. Every file, picture and picture variant is charged its full size, even when its content is shared.
+// @Tags storage
+// @Produce json
+// @Router /api/storage [get]
+// @Security Bearer
+// @Success 200 {object} repo.StorageUsage
+// @Failure 401,403,500 {object} errorResponse
+func (s *handler) getStorageUsageHandler(w http.ResponseWriter, r *http.Request) {
+	usage, err := s.rpo.GetStorageUsage(r.Context())
+	if err != nil {
+		s.writeRepoError(w, r, err, "error getting storage usage")
+		return
+	}
+
+	resp, err := json.MarshalIndent(usage, "", " \t")
+	if err != nil {
+		logging.FromRequest(s.logger, r).Errorw("error marshalling storage usage", "error", err)
+		writeError(w, r, http.StatusInternalServerError, codeInternal, "internal server error")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(resp)
+}
@@ -0,0 +1,148 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+
+	"github.com/btschwartz12/site/internal/logging"
+	"github.com/btschwartz12/site/internal/repo"
+)
+
+type createTokenRequest struct {
+	Name   string   `json:"name"`
+	Scopes []string `json:"scopes"`
+	// ExpiresIn is optional, e.g. "720h"
+	ExpiresIn string `json:"expires_in"`
+	// MaxUploadMb optionally overrides the resumable upload size limit
+	MaxUploadMb int64 `json:"max_upload_mb"`
+}
+
+type createTokenResponse struct {
+	Token *repo.ApiToken `json:"token"`
+	// Secret is only ever returned here
+	Secret string `json:"secret"`
+}
+
+// createTokenHandler godoc
+// @Summary Create an api token
+// @Description Mint a new api token with the given scopes. The secret is only returned once. Names must be unique, since quotas and auto-approval go by them. Once a token exists, the API_TOKEN bootstrap token stops working.
+// @Tags tokens
+// @Param body body createTokenRequest true "Token"
+// @Accept json
+// @Produce json
+// @Router /api/tokens [post]
+// @Security Bearer
+// @Success 200 {object} createTokenResponse
+// @Failure 400,401,403,409,500 {object} errorResponse
+func (s *handler) createTokenHandler(w http.ResponseWriter, r *http.Request) {
+	var req createTokenRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		logging.FromRequest(s.logger, r).Errorw("error decoding request", "error", err)
+		writeError(w, r, http.StatusBadRequest, codeBadRequest, "invalid request body")
+		return
+	}
+
+	if req.Name == "" {
+		writeError(w, r, http.StatusBadRequest, codeBadRequest, "name is required")
+		return
+	}
+	if len(req.Scopes) == 0 {
+		writeError(w, r, http.StatusBadRequest, codeBadRequest, "scopes is required")
+		return
+	}
+	for _, scope := range req.Scopes {
+		if !knownScopes[scope] {
+			writeError(w, r, http.StatusBadRequest, codeBadRequest, fmt.Sprintf("unknown scope: %s", scope))
+			return
+		}
+	}
+
+	var expires *time.Time
+	if req.ExpiresIn != "" {
+		d, err := time.ParseDuration(req.ExpiresIn)
+		if err != nil || d <= 0 {
+			writeError(w, r, http.StatusBadRequest, codeBadRequest, "invalid expires_in: must be in correct format (300s, 2h45m, etc.)")
+			return
+		}
+		t := time.Now().Add(d)
+		expires = &t
+	}
+
+	if req.MaxUploadMb < 0 {
+		writeError(w, r, http.StatusBadRequest, codeBadRequest, "invalid max_upload_mb")
+		return
+	}
+
+	token, secret, err := s.rpo.InsertApiToken(r.Context(), req.Name, req.Scopes, expires, req.MaxUploadMb<<20)
+	if err != nil {
+		s.writeRepoError(w, r, err, "error creating token")
+		return
+	}
+	logging.FromRequest(s.logger, r).Infow("created api token", "id", token.ID, "name", token.Name, "scopes", token.Scopes)
+
+	resp, err := json.MarshalIndent(createTokenResponse{Token: token, Secret: secret}, "", " \t")
+	if err != nil {
+		logging.FromRequest(s.logger, r).Errorw("error marshalling token", "error", err)
+		writeError(w, r, http.StatusInternalServerError, codeInternal, "internal server error")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(resp)
+}
+
+// getTokensHandler godoc
+// @Summary Get api tokens
+// @Description Get all api tokens, including revoked and expired ones
+// @Tags tokens
+// @Produce json
+// @Router /api/tokens [get]
+// @Security Bearer
+// @Success 200 {array} repo.ApiToken
+// @Failure 401,403,500 {object} errorResponse
+func (s *handler) getTokensHandler(w http.ResponseWriter, r *http.Request) {
+	tokens, err := s.rpo.GetAllApiTokens(r.Context())
+	if err != nil {
+		s.writeRepoError(w, r, err, "error getting tokens")
+		return
+	}
+
+	resp, err := json.MarshalIndent(tokens, "", " \t")
+	if err != nil {
+		logging.FromRequest(s.logger, r).Errorw("error marshalling tokens", "error", err)
+		writeError(w, r, http.StatusInternalServerError, codeInternal, "internal server error")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(resp)
+}
+
+// revokeTokenHandler godoc
+// @Summary Revoke an api token
+// @Description Revoke an api token so it can no longer be used
+// @Tags tokens
+// @Param id path string true "Token ID"
+// @Produce json
+// @Router /api/tokens/{id} [delete]
+// @Security Bearer
+// @Success 200 {object} repo.ApiToken
+// @Failure 400,401,403,404,500 {object} errorResponse
+func (s *handler) revokeTokenHandler(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+
+	token, err := s.rpo.RevokeApiToken(r.Context(), id)
+	if err != nil {
+		s.writeRepoError(w, r, err, "error revoking token")
+		return
+	}
+	logging.FromRequest(s.logger, r).Infow("revoked api token", "id", token.ID, "name", token.Name)
+
+	if err := json.NewEncoder(w).Encode(token); err != nil {
+		logging.FromRequest(s.logger, r).Errorw("error encoding token", "error", err)
+	}
+}
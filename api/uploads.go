@@ -0,0 +1,200 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+
+	"github.com/btschwartz12/site/internal/logging"
+	"github.com/btschwartz12/site/internal/repo"
+)
+
+const uploadOffsetHeader = "Upload-Offset"
+
+type createUploadRequest struct {
+	Name  string `json:"name"`
+	Notes string `json:"notes"`
+	Size  int64  `json:"size"`
+	// TTL optionally deletes the file this long after it completes
+	TTL string `json:"ttl"`
+}
+
+// createUploadHandler godoc
+// @Summary Start a resumable upload
+// @Description Start a chunked upload of a drive file. Send the chunks in order with PATCH, then complete it. Each uploader can only have a few uploads open at once, and open uploads count against the storage quota at their declared size.
+// @Tags drive
+// @Param body body createUploadRequest true "Upload"
+// @Accept json
+// @Produce json
+// @Router /api/drive/uploads [post]
+// @Security Bearer
+// @Success 201 {object} repo.Upload
+// @Failure 400,401,403,413,429,500,507 {object} errorResponse
+func (s *handler) createUploadHandler(w http.ResponseWriter, r *http.Request) {
+	var req createUploadRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		logging.FromRequest(s.logger, r).Errorw("error decoding request", "error", err)
+		writeError(w, r, http.StatusBadRequest, codeBadRequest, "invalid request body")
+		return
+	}
+
+	opts := repo.UploadOptions{
+		Name:          req.Name,
+		Notes:         req.Notes,
+		Size:          req.Size,
+		Uploader:      uploaderFromContext(r.Context()),
+		Authenticated: true,
+	}
+	if token := tokenFromContext(r.Context()); token != nil && token.MaxUploadBytes != nil {
+		opts.MaxSize = *token.MaxUploadBytes
+	}
+	if req.TTL != "" {
+		ttl, err := time.ParseDuration(req.TTL)
+		if err != nil || ttl <= 0 {
+			writeError(w, r, http.StatusBadRequest, codeBadRequest, "invalid ttl: must be in correct format (300s, 2h45m, etc.)")
+			return
+		}
+		opts.TTL = ttl
+	}
+
+	u, err := s.rpo.CreateUpload(r.Context(), opts)
+	if err != nil {
+		s.writeRepoError(w, r, err, "error creating upload")
+		return
+	}
+
+	w.Header().Set("Location", "/api/drive/uploads/"+u.ID)
+	w.Header().Set(uploadOffsetHeader, strconv.FormatInt(u.Received, 10))
+	w.WriteHeader(http.StatusCreated)
+	if err := json.NewEncoder(w).Encode(u); err != nil {
+		logging.FromRequest(s.logger, r).Errorw("error encoding upload", "error", err)
+	}
+}
+
+// getUploadHandler godoc
+// @Summary Get a resumable upload
+// @Description Get how much of an upload has been received, to know where to resume from. The offset is also returned in the Upload-Offset header.
+// @Tags drive
+// @Param id path string true "Upload ID"
+// @Produce json
+// @Router /api/drive/uploads/{id} [get]
+// @Security Bearer
+// @Success 200 {object} repo.Upload
+// @Failure 400,401,403,404,500 {object} errorResponse
+func (s *handler) getUploadHandler(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+
+	u, err := s.rpo.GetUpload(r.Context(), id)
+	if err != nil {
+		s.writeRepoError(w, r, err, "error getting upload")
+		return
+	}
+
+	w.Header().Set(uploadOffsetHeader, strconv.FormatInt(u.Received, 10))
+	if err := json.NewEncoder(w).Encode(u); err != nil {
+		logging.FromRequest(s.logger, r).Errorw("error encoding upload", "error", err)
+	}
+}
+
+// appendUploadHandler godoc
+// @Summary Upload a chunk
+// @Description Append the request body to an upload. The Upload-Offset header must match how much has been received so far.
+// @Tags drive
+// @Param id path string true "Upload ID"
+// @Param Upload-Offset header int true "Offset of this chunk"
+// @Accept octet-stream
+// @Produce json
+// @Router /api/drive/uploads/{id} [patch]
+// @Security Bearer
+// @Success 200 {object} repo.Upload
+// @Failure 400,401,403,404,409,411,500 {object} errorResponse
+func (s *handler) appendUploadHandler(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+
+	offset, err := strconv.ParseInt(r.Header.Get(uploadOffsetHeader), 10, 64)
+	if err != nil || offset < 0 {
+		writeError(w, r, http.StatusBadRequest, codeBadRequest, "invalid Upload-Offset header")
+		return
+	}
+	if r.ContentLength <= 0 {
+		writeError(w, r, http.StatusLengthRequired, codeLengthRequired, "Content-Length is required")
+		return
+	}
+
+	u, err := s.rpo.AppendUpload(r.Context(), id, offset, r.Body, r.ContentLength)
+	if err != nil {
+		if errors.Is(err, repo.ErrOffsetMismatch) {
+			s.writeOffsetMismatch(w, r, id, err)
+			return
+		}
+		s.writeRepoError(w, r, err, "error appending to upload")
+		return
+	}
+
+	w.Header().Set(uploadOffsetHeader, strconv.FormatInt(u.Received, 10))
+	if err := json.NewEncoder(w).Encode(u); err != nil {
+		logging.FromRequest(s.logger, r).Errorw("error encoding upload", "error", err)
+	}
+}
+
+// completeUploadHandler godoc
+// @Summary Complete a resumable upload
+// @Description Turn a fully received upload into a drive file
+// @Tags drive
+// @Param id path string true "Upload ID"
+// @Produce json
+// @Router /api/drive/uploads/{id}/complete [post]
+// @Security Bearer
+// @Success 200 {object} repo.File
+// @Failure 400,401,403,404,409,500,507 {object} errorResponse
+func (s *handler) completeUploadHandler(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+
+	f, err := s.rpo.CompleteUpload(r.Context(), id)
+	if err != nil {
+		s.writeRepoError(w, r, err, "error completing upload")
+		return
+	}
+
+	if err := json.NewEncoder(w).Encode(f); err != nil {
+		logging.FromRequest(s.logger, r).Errorw("error encoding file", "error", err)
+	}
+}
+
+// abortUploadHandler godoc
+// @Summary Abort a resumable upload
+// @Description Discard an upload and everything received for it
+// @Tags drive
+// @Param id path string true "Upload ID"
+// @Router /api/drive/uploads/{id} [delete]
+// @Security Bearer
+// @Success 204
+// @Failure 400,401,403,404,500 {object} errorResponse
+func (s *handler) abortUploadHandler(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+
+	if err := s.rpo.AbortUpload(r.Context(), id); err != nil {
+		s.writeRepoError(w, r, err, "error aborting upload")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// writeOffsetMismatch tells the client where to resume an upload
+// from, in the Upload-Offset header and the error details.
+func (s *handler) writeOffsetMismatch(w http.ResponseWriter, r *http.Request, id string, err error) {
+	u, getErr := s.rpo.GetUpload(r.Context(), id)
+	if getErr != nil {
+		s.writeRepoError(w, r, err, "error appending to upload")
+		return
+	}
+	w.Header().Set(uploadOffsetHeader, strconv.FormatInt(u.Received, 10))
+	writeErrorDetails(w, r, http.StatusConflict, codeOffsetMismatch, err.Error(), map[string]any{
+		"offset": u.Received,
+	})
+}
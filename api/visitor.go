@@ -3,6 +3,9 @@ package api
 import (
 	"encoding/json"
 	"net/http"
+
+	"github.com/btschwartz12/site/internal/logging"
+	"github.com/btschwartz12/site/internal/repo"
 )
 
 // getVisitorsHandler godoc
@@ -10,24 +13,43 @@ import (
 // @Description Get the visitors
 // @Tags visitors
 // @Produce json
+// @Param limit query int false "Page size, 50 by default and at most 500"
+// @Param cursor query string false "Cursor from the previous page"
+// @Param order query string false "asc or desc (the default) by creation time"
+// @Param since query string false "Only items created at or after this time (RFC 3339 or YYYY-MM-DD)"
+// @Param until query string false "Only items created before this time (RFC 3339 or YYYY-MM-DD)"
+// @Param path query string false "Only visits to this path"
+// @Param country query string false "Only visitors from this country code"
 // @Router /api/visitors [get]
 // @Security Bearer
 // @Success 200 {array} repo.Visitor
+// @Header 200 {string} Link "Link to the next page, if any"
+// @Header 200 {string} X-Next-Cursor "Cursor for the next page, if any"
+// @Failure 400,401,403,500 {object} errorResponse
 func (s *handler) getVisitorsHandler(w http.ResponseWriter, r *http.Request) {
-	visitors, err := s.rpo.GetAllVisitors(r.Context())
+	opts, err := parseListOptions(r)
+	if err != nil {
+		writeError(w, r, http.StatusBadRequest, codeBadRequest, err.Error())
+		return
+	}
+	visitors, next, err := s.rpo.ListVisitors(r.Context(), repo.VisitorFilter{
+		ListOptions: opts,
+		Path:        r.URL.Query().Get("path"),
+		Country:     r.URL.Query().Get("country"),
+	})
 	if err != nil {
-		s.logger.Errorw("error getting visitors", "error", err)
-		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		s.writeRepoError(w, r, err, "error getting visitors")
 		return
 	}
 
 	resp, err := json.MarshalIndent(visitors, "", " \t")
 	if err != nil {
-		s.logger.Errorw("error marshalling visitors", "error", err)
-		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		logging.FromRequest(s.logger, r).Errorw("error marshalling visitors", "error", err)
+		writeError(w, r, http.StatusInternalServerError, codeInternal, "internal server error")
 		return
 	}
 
+	setPageHeaders(w, r, next)
 	w.Header().Set("Content-Type", "application/json")
 	w.Write(resp)
 }
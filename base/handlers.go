@@ -5,6 +5,7 @@ import (
 	"net/http"
 
 	"github.com/btschwartz12/site/base/assets"
+	"github.com/btschwartz12/site/internal/logging"
 )
 
 var (
@@ -29,7 +30,7 @@ func (s *BaseServer) indexHandler(w http.ResponseWriter, r *http.Request) {
 	}
 
 	if err := tmpl.ExecuteTemplate(w, "base.html.tmpl", templateData); err != nil {
-		s.logger.Errorw("error executing template", "error", err)
+		logging.FromRequest(s.logger, r).Errorw("error executing template", "error", err)
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 	}
 }
@@ -1,6 +1,7 @@
 package base
 
 import (
+	"context"
 	"net/http"
 
 	"github.com/go-chi/chi/v5"
@@ -42,3 +43,11 @@ func (s *BaseServer) GetRouter() chi.Router {
 func (s *BaseServer) GetMountPoint() string {
 	return s.mountPoint
 }
+
+func (s *BaseServer) Start(ctx context.Context) error {
+	return nil
+}
+
+func (s *BaseServer) Shutdown(ctx context.Context) error {
+	return nil
+}
@@ -0,0 +1,28 @@
+package drive
+
+import (
+	"fmt"
+	"time"
+
+	env "github.com/Netflix/go-env"
+)
+
+type config struct {
+	// PermalinkRate is how many permalink requests per minute
+	// each client may make, with bursts of up to PermalinkBurst.
+	PermalinkRate  float64 `env:"PERMALINK_RATE_PER_MINUTE,default=30"`
+	PermalinkBurst int     `env:"PERMALINK_BURST,default=10"`
+	// Clients that hit PermalinkLockoutThreshold unknown permalinks
+	// within PermalinkLockoutWindow are locked out.
+	PermalinkLockoutThreshold int           `env:"PERMALINK_LOCKOUT_THRESHOLD,default=10"`
+	PermalinkLockoutWindow    time.Duration `env:"PERMALINK_LOCKOUT_WINDOW,default=10m"`
+	PermalinkLockoutDuration  time.Duration `env:"PERMALINK_LOCKOUT_DURATION,default=1h"`
+}
+
+func newConfig() (*config, error) {
+	conf := config{}
+	if _, err := env.UnmarshalFromEnviron(&conf); err != nil {
+		return nil, fmt.Errorf("failed to load config: %w", err)
+	}
+	return &conf, nil
+}
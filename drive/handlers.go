@@ -1,38 +1,49 @@
 package drive
 
 import (
-	"database/sql"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"html/template"
 	"net/http"
-	"os"
-	"strings"
+	"strconv"
 	"time"
 
 	"github.com/btschwartz12/site/drive/assets"
+	"github.com/btschwartz12/site/internal/ipdata"
+	"github.com/btschwartz12/site/internal/logging"
+	"github.com/btschwartz12/site/internal/repo"
+	"github.com/btschwartz12/site/internal/storage"
 	"github.com/go-chi/chi/v5"
 )
 
 type templateData struct {
-	Title string
+	Title       string
+	MaxUploadMb int64
+}
+
+type permalinkTemplateData struct {
+	Title       string
+	PermalinkId string
+	Error       string
 }
 
 var (
 	tmpl = template.Must(template.ParseFS(
 		assets.Templates,
 		"templates/base.html.tmpl",
+		"templates/permalink.html.tmpl",
 	))
 )
 
 func (s *handler) indexHandler(w http.ResponseWriter, r *http.Request) {
 	templateData := templateData{
-		Title: "Home",
+		Title:       "Home",
+		MaxUploadMb: s.rpo.MaxAnonymousUploadSize() >> 20,
 	}
 
 	if err := tmpl.ExecuteTemplate(w, "base.html.tmpl", templateData); err != nil {
-		s.logger.Errorw("error executing template", "error", err)
+		logging.FromRequest(s.logger, r).Errorw("error executing template", "error", err)
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 	}
 }
@@ -40,7 +51,7 @@ func (s *handler) indexHandler(w http.ResponseWriter, r *http.Request) {
 func (s *handler) uploadFileHandler(w http.ResponseWriter, r *http.Request) {
 	file, header, err := r.FormFile("file")
 	if err != nil {
-		s.logger.Errorw("error getting file from form", "error", err)
+		logging.FromRequest(s.logger, r).Errorw("error getting file from form", "error", err)
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
@@ -52,13 +63,17 @@ func (s *handler) uploadFileHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	f, err := s.rpo.InsertFile(r.Context(), file, header, notes)
+	f, err := s.rpo.InsertFile(r.Context(), file, header, notes, ipdata.GetIp(r).String(), 0)
 	if err != nil {
-		if strings.Contains(err.Error(), "file too large") {
-			http.Error(w, "File Too Large", http.StatusBadRequest)
+		if errors.Is(err, repo.ErrTooLarge) {
+			http.Error(w, "File Too Large", http.StatusRequestEntityTooLarge)
+			return
+		}
+		if errors.Is(err, repo.ErrStorageFull) {
+			http.Error(w, "Storage Full", http.StatusInsufficientStorage)
 			return
 		}
-		s.logger.Errorw("error inserting file", "error", err)
+		logging.FromRequest(s.logger, r).Errorw("error inserting file", "error", err)
 		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
 		return
 	}
@@ -75,7 +90,7 @@ func (s *handler) uploadFileHandler(w http.ResponseWriter, r *http.Request) {
 
 	rsp, err := json.MarshalIndent(resp, "", " \t")
 	if err != nil {
-		s.logger.Errorw("error marshalling response", "error", err)
+		logging.FromRequest(s.logger, r).Errorw("error marshalling response", "error", err)
 		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
 		return
 	}
@@ -103,9 +118,24 @@ func (s *handler) generatePermalinkHandler(w http.ResponseWriter, r *http.Reques
 		return
 	}
 
-	p, err := s.rpo.InsertPermalink(r.Context(), fileId, int64(duration.Seconds()))
+	opts := repo.PermalinkOptions{
+		Password: r.FormValue("password"),
+	}
+	if v := r.FormValue("max_downloads"); v != "" {
+		opts.MaxDownloads, err = strconv.ParseInt(v, 10, 64)
+		if err != nil || opts.MaxDownloads <= 0 {
+			http.Error(w, "invalid max_downloads: must be a positive integer", http.StatusBadRequest)
+			return
+		}
+	}
+
+	p, err := s.rpo.InsertPermalink(r.Context(), fileId, int64(duration.Seconds()), opts)
 	if err != nil {
-		s.logger.Errorw("error generating permalink", "error", err)
+		if errors.Is(err, repo.ErrNotFound) {
+			http.Error(w, "file not found", http.StatusNotFound)
+			return
+		}
+		logging.FromRequest(s.logger, r).Errorw("error generating permalink", "error", err)
 		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
 		return
 	}
@@ -122,7 +152,7 @@ func (s *handler) generatePermalinkHandler(w http.ResponseWriter, r *http.Reques
 
 	rsp, err := json.MarshalIndent(resp, "", " \t")
 	if err != nil {
-		s.logger.Errorw("error marshalling response", "error", err)
+		logging.FromRequest(s.logger, r).Errorw("error marshalling response", "error", err)
 		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
 		return
 	}
@@ -138,27 +168,48 @@ func (s *handler) servePermalinkHandler(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	p, err := s.rpo.GetPermalink(r.Context(), permalinkId)
+	p, err := s.rpo.AccessPermalink(r.Context(), permalinkId, repo.PermalinkRequest{
+		Ip:        ipdata.GetIp(r).String(),
+		UserAgent: r.UserAgent(),
+		Password:  r.PostFormValue("password"),
+		Range:     r.Header.Get("Range"),
+		IfRange:   r.Header.Get("If-Range"),
+	})
 	if err != nil {
-		if errors.Is(err, sql.ErrNoRows) {
+		if errors.Is(err, repo.ErrNotFound) {
 			http.Error(w, "permalink not found", http.StatusNotFound)
 			return
 		}
-		s.logger.Errorw("error getting permalink", "error", err)
+		if errors.Is(err, repo.ErrPasswordRequired) {
+			s.renderPasswordPage(w, r, permalinkId, "", http.StatusUnauthorized)
+			return
+		}
+		if errors.Is(err, repo.ErrInvalidPassword) {
+			s.renderPasswordPage(w, r, permalinkId, "wrong password", http.StatusUnauthorized)
+			return
+		}
+		if errors.Is(err, repo.ErrUnavailable) {
+			http.Error(w, err.Error(), http.StatusGone)
+			return
+		}
+		logging.FromRequest(s.logger, r).Errorw("error getting permalink", "error", err)
 		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
 		return
 	}
 
-	if p.Expires.Before(time.Now()) {
-		http.Error(w, fmt.Sprintf("permalink expired at %s", p.Expires), http.StatusBadRequest)
-		return
-	}
+	storage.ServeContent(w, r, s.rpo.Blobs(), p.File.Content())
+}
 
-	if _, err := os.Stat(p.File.Url); err != nil {
-		s.logger.Errorw("error getting file", "error", err)
-		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
-		return
+func (s *handler) renderPasswordPage(w http.ResponseWriter, r *http.Request, permalinkId string, errMsg string, status int) {
+	data := permalinkTemplateData{
+		Title:       "Password Required",
+		PermalinkId: permalinkId,
+		Error:       errMsg,
 	}
 
-	http.ServeFile(w, r, p.File.Url)
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.WriteHeader(status)
+	if err := tmpl.ExecuteTemplate(w, "permalink.html.tmpl", data); err != nil {
+		logging.FromRequest(s.logger, r).Errorw("error executing template", "error", err)
+	}
 }
@@ -1,15 +1,22 @@
 package drive
 
 import (
+	"context"
+	"fmt"
+
 	"github.com/go-chi/chi/v5"
 	"go.uber.org/zap"
+	"golang.org/x/time/rate"
 
+	"github.com/btschwartz12/site/internal/health"
+	"github.com/btschwartz12/site/internal/ratelimit"
 	"github.com/btschwartz12/site/internal/repo"
 )
 
 type DriveServer struct {
 	router     *chi.Mux
 	mountPoint string
+	rpo        *repo.Repo
 }
 
 type handler struct {
@@ -19,17 +26,41 @@ type handler struct {
 
 func (s *DriveServer) Init(mountPoint string, logger *zap.SugaredLogger, rpo *repo.Repo) error {
 	s.mountPoint = mountPoint
+	s.rpo = rpo
 	s.router = chi.NewRouter()
 
+	config, err := newConfig()
+	if err != nil {
+		return fmt.Errorf("failed to create config: %w", err)
+	}
+
 	h := handler{
 		logger: logger,
 		rpo:    rpo,
 	}
 
+	// permalink ids are the only thing protecting the files, so
+	// make them expensive to guess
+	permalinkLimiter := ratelimit.New(ratelimit.Options{
+		Rate:             rate.Limit(config.PermalinkRate / 60),
+		Burst:            config.PermalinkBurst,
+		LockoutThreshold: config.PermalinkLockoutThreshold,
+		LockoutWindow:    config.PermalinkLockoutWindow,
+		LockoutDuration:  config.PermalinkLockoutDuration,
+	})
+
 	s.router.HandleFunc("/", h.indexHandler)
 	s.router.Post("/upload", h.uploadFileHandler)
+	s.router.Post("/uploads", h.createUploadHandler)
+	s.router.Get("/uploads/{upload_id}", h.getUploadHandler)
+	s.router.Patch("/uploads/{upload_id}", h.appendUploadHandler)
+	s.router.Post("/uploads/{upload_id}/complete", h.completeUploadHandler)
 	s.router.Post("/generate_permalink", h.generatePermalinkHandler)
-	s.router.Get("/permalinks/{permalink_id}", h.servePermalinkHandler)
+	s.router.Group(func(r chi.Router) {
+		r.Use(permalinkLimiter.Middleware)
+		r.Get("/permalinks/{permalink_id}", h.servePermalinkHandler)
+		r.Post("/permalinks/{permalink_id}", h.servePermalinkHandler)
+	})
 
 	return nil
 }
@@ -41,3 +72,19 @@ func (s *DriveServer) GetRouter() chi.Router {
 func (s *DriveServer) GetMountPoint() string {
 	return s.mountPoint
 }
+
+func (s *DriveServer) Start(ctx context.Context) error {
+	return nil
+}
+
+func (s *DriveServer) Shutdown(ctx context.Context) error {
+	return nil
+}
+
+// ReadinessChecks reports the drive degraded once storage is full.
+// Only uploads stop working, so it stays in rotation.
+func (s *DriveServer) ReadinessChecks() []health.Check {
+	return []health.Check{
+		{Name: "storage", Run: s.rpo.CheckStorage, Degraded: true},
+	}
+}
@@ -0,0 +1,165 @@
+package drive
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+
+	"github.com/btschwartz12/site/internal/ipdata"
+	"github.com/btschwartz12/site/internal/logging"
+	"github.com/btschwartz12/site/internal/repo"
+)
+
+const uploadOffsetHeader = "Upload-Offset"
+
+func (s *handler) createUploadHandler(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Name  string `json:"name"`
+		Notes string `json:"notes"`
+		Size  int64  `json:"size"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Bad Request", http.StatusBadRequest)
+		return
+	}
+	if req.Notes == "" {
+		http.Error(w, "notes is required", http.StatusBadRequest)
+		return
+	}
+
+	u, err := s.rpo.CreateUpload(r.Context(), repo.UploadOptions{
+		Name:     req.Name,
+		Notes:    req.Notes,
+		Size:     req.Size,
+		Uploader: ipdata.GetIp(r).String(),
+	})
+	if err != nil {
+		if errors.Is(err, repo.ErrInvalidInput) {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if errors.Is(err, repo.ErrTooLarge) {
+			http.Error(w, err.Error(), http.StatusRequestEntityTooLarge)
+			return
+		}
+		if errors.Is(err, repo.ErrTooManyUploads) {
+			http.Error(w, err.Error(), http.StatusTooManyRequests)
+			return
+		}
+		if errors.Is(err, repo.ErrStorageFull) {
+			http.Error(w, "Storage Full", http.StatusInsufficientStorage)
+			return
+		}
+		logging.FromRequest(s.logger, r).Errorw("error creating upload", "error", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+
+	s.writeUpload(w, r, u, http.StatusCreated)
+}
+
+func (s *handler) getUploadHandler(w http.ResponseWriter, r *http.Request) {
+	u, err := s.rpo.GetUpload(r.Context(), chi.URLParam(r, "upload_id"))
+	if err != nil {
+		if errors.Is(err, repo.ErrNotFound) {
+			http.Error(w, "Not Found", http.StatusNotFound)
+			return
+		}
+		logging.FromRequest(s.logger, r).Errorw("error getting upload", "error", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+
+	s.writeUpload(w, r, u, http.StatusOK)
+}
+
+func (s *handler) appendUploadHandler(w http.ResponseWriter, r *http.Request) {
+	offset, err := strconv.ParseInt(r.Header.Get(uploadOffsetHeader), 10, 64)
+	if err != nil || offset < 0 {
+		http.Error(w, "invalid Upload-Offset header", http.StatusBadRequest)
+		return
+	}
+	if r.ContentLength <= 0 {
+		http.Error(w, "Content-Length is required", http.StatusLengthRequired)
+		return
+	}
+
+	u, err := s.rpo.AppendUpload(r.Context(), chi.URLParam(r, "upload_id"), offset, r.Body, r.ContentLength)
+	if err != nil {
+		if errors.Is(err, repo.ErrNotFound) {
+			http.Error(w, "Not Found", http.StatusNotFound)
+			return
+		}
+		if errors.Is(err, repo.ErrOffsetMismatch) {
+			http.Error(w, err.Error(), http.StatusConflict)
+			return
+		}
+		if errors.Is(err, repo.ErrInvalidInput) {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		logging.FromRequest(s.logger, r).Errorw("error appending to upload", "error", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+
+	s.writeUpload(w, r, u, http.StatusOK)
+}
+
+func (s *handler) completeUploadHandler(w http.ResponseWriter, r *http.Request) {
+	f, err := s.rpo.CompleteUpload(r.Context(), chi.URLParam(r, "upload_id"))
+	if err != nil {
+		if errors.Is(err, repo.ErrNotFound) {
+			http.Error(w, "Not Found", http.StatusNotFound)
+			return
+		}
+		if errors.Is(err, repo.ErrUploadIncomplete) {
+			http.Error(w, err.Error(), http.StatusConflict)
+			return
+		}
+		if errors.Is(err, repo.ErrStorageFull) {
+			http.Error(w, "Storage Full", http.StatusInsufficientStorage)
+			return
+		}
+		logging.FromRequest(s.logger, r).Errorw("error completing upload", "error", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+
+	resp := struct {
+		Success bool   `json:"success"`
+		Message string `json:"message"`
+		ID      string `json:"file_id"`
+	}{
+		Success: true,
+		Message: "uploaded successfully. don't lose this id!",
+		ID:      f.Uuid.String(),
+	}
+
+	rsp, err := json.MarshalIndent(resp, "", " \t")
+	if err != nil {
+		logging.FromRequest(s.logger, r).Errorw("error marshalling response", "error", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(rsp)
+}
+
+func (s *handler) writeUpload(w http.ResponseWriter, r *http.Request, u *repo.Upload, status int) {
+	rsp, err := json.MarshalIndent(u, "", " \t")
+	if err != nil {
+		logging.FromRequest(s.logger, r).Errorw("error marshalling upload", "error", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set(uploadOffsetHeader, strconv.FormatInt(u.Received, 10))
+	w.WriteHeader(status)
+	w.Write(rsp)
+}
@@ -0,0 +1,139 @@
+// Package health serves the endpoints that tell whether the server is
+// alive, ready for traffic, and which build it is.
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"runtime/debug"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// checkTimeout bounds how long a readiness check can take.
+const checkTimeout = 5 * time.Second
+
+// buildTime is set at build time with
+// -ldflags "-X github.com/btschwartz12/site/internal/health.buildTime=...".
+var buildTime string
+
+// Check is something the server needs in order to serve traffic. Run
+// returns an error saying what's wrong if it can't.
+type Check struct {
+	Name string
+	Run  func(ctx context.Context) error
+	// Degraded marks a check the server can still serve most traffic
+	// without. When it fails, /readyz reports the server degraded but
+	// still answers 200, so it isn't taken out of rotation.
+	Degraded bool
+}
+
+type status struct {
+	Status string            `json:"status"`
+	Checks map[string]string `json:"checks,omitempty"`
+}
+
+// BuildInfo describes the running binary.
+type BuildInfo struct {
+	Commit     string `json:"commit"`
+	CommitTime string `json:"commit_time,omitempty"`
+	Modified   bool   `json:"modified"`
+	BuildTime  string `json:"build_time,omitempty"`
+	GoVersion  string `json:"go_version"`
+}
+
+// Live replies that the process is alive, which answering at all
+// shows.
+func Live(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, status{Status: "ok"})
+}
+
+// Ready runs every check at once and replies 503 if any of them fail,
+// or 200 with a degraded status if only degraded checks fail, with
+// what each one found.
+func Ready(logger *zap.SugaredLogger, checks []Check) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx, cancel := context.WithTimeout(r.Context(), checkTimeout)
+		defer cancel()
+
+		var (
+			wg      sync.WaitGroup
+			mu      sync.Mutex
+			results = make(map[string]string, len(checks))
+			ready   = true
+			healthy = true
+		)
+		for _, c := range checks {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				result := "ok"
+				if err := c.Run(ctx); err != nil {
+					logger.Warnw("readiness check failed", "check", c.Name, "error", err)
+					result = err.Error()
+				}
+				mu.Lock()
+				defer mu.Unlock()
+				results[c.Name] = result
+				switch {
+				case result == "ok":
+				case c.Degraded:
+					healthy = false
+				default:
+					ready = false
+				}
+			}()
+		}
+		wg.Wait()
+
+		if !ready {
+			writeJSON(w, http.StatusServiceUnavailable, status{Status: "unavailable", Checks: results})
+			return
+		}
+		if !healthy {
+			writeJSON(w, http.StatusOK, status{Status: "degraded", Checks: results})
+			return
+		}
+		writeJSON(w, http.StatusOK, status{Status: "ok", Checks: results})
+	}
+}
+
+// Version replies with the BuildInfo of the running binary.
+func Version(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, ReadBuildInfo())
+}
+
+// ReadBuildInfo gets the commit the binary was built from out of the
+// version control info the go tool stamps it with.
+func ReadBuildInfo() BuildInfo {
+	info := BuildInfo{
+		Commit:    "unknown",
+		BuildTime: buildTime,
+	}
+	bi, ok := debug.ReadBuildInfo()
+	if !ok {
+		return info
+	}
+	info.GoVersion = bi.GoVersion
+	for _, s := range bi.Settings {
+		switch s.Key {
+		case "vcs.revision":
+			info.Commit = s.Value
+		case "vcs.time":
+			info.CommitTime = s.Value
+		case "vcs.modified":
+			info.Modified = s.Value == "true"
+		}
+	}
+	return info
+}
+
+func writeJSON(w http.ResponseWriter, code int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(v)
+}
@@ -0,0 +1,41 @@
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+)
+
+func TestReady(t *testing.T) {
+	ok := Check{Name: "database", Run: func(ctx context.Context) error { return nil }}
+	full := Check{Name: "storage", Run: func(ctx context.Context) error { return errors.New("storage full") }}
+
+	ready := func(checks ...Check) (int, status) {
+		w := httptest.NewRecorder()
+		Ready(zap.NewNop().Sugar(), checks)(w, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+		var s status
+		assert.NoError(t, json.NewDecoder(w.Body).Decode(&s))
+		return w.Code, s
+	}
+
+	code, s := ready(ok)
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, "ok", s.Status)
+
+	code, s = ready(ok, full)
+	assert.Equal(t, http.StatusServiceUnavailable, code)
+	assert.Equal(t, map[string]string{"database": "ok", "storage": "storage full"}, s.Checks)
+
+	// a failing degraded check keeps the server in rotation
+	full.Degraded = true
+	code, s = ready(ok, full)
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, "degraded", s.Status)
+	assert.Equal(t, map[string]string{"database": "ok", "storage": "storage full"}, s.Checks)
+}
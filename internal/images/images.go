@@ -0,0 +1,219 @@
+// Package images validates uploaded pictures and prepares the
+// versions of them that get stored and served.
+package images
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	"image/draw"
+	_ "image/gif"
+	"image/jpeg"
+	"image/png"
+
+	"github.com/HugoSmits86/nativewebp"
+	xdraw "golang.org/x/image/draw"
+)
+
+const (
+	// maxPixels guards against images that are small to upload but
+	// huge to decode.
+	maxPixels = 50_000_000
+
+	jpegQuality        = 90
+	jpegVariantQuality = 80
+)
+
+// VariantWidths are the thumbnail widths made for each picture, for
+// the ones narrower than the picture itself.
+var VariantWidths = []int{320, 640, 1280}
+
+// ErrInvalid is returned by Process for data that isn't an image it
+// accepts.
+var ErrInvalid = errors.New("invalid image")
+
+// Image is an uploaded picture ready to be stored.
+type Image struct {
+	// Data is the picture with its metadata removed.
+	Data        []byte
+	ContentType string
+	Width       int
+	Height      int
+	Variants    []Variant
+}
+
+// Variant is a resized or re-encoded copy of an Image.
+type Variant struct {
+	Data        []byte
+	ContentType string
+	Width       int
+	Height      int
+}
+
+// Process decodes data to make sure it is an image, strips its EXIF
+// and other metadata, and renders its variants.
+func Process(data []byte) (*Image, error) {
+	img, m, err := decode(data)
+	if err != nil {
+		return nil, err
+	}
+	variants, err := renderVariants(m, img.ContentType, len(img.Data))
+	if err != nil {
+		return nil, err
+	}
+	img.Variants = variants
+	return img, nil
+}
+
+func decode(data []byte) (*Image, image.Image, error) {
+	cfg, format, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, nil, fmt.Errorf("%w: %w", ErrInvalid, err)
+	}
+	if cfg.Width*cfg.Height > maxPixels {
+		return nil, nil, fmt.Errorf("%w: %dx%d is too many pixels", ErrInvalid, cfg.Width, cfg.Height)
+	}
+	m, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, nil, fmt.Errorf("%w: %w", ErrInvalid, err)
+	}
+
+	img := &Image{Width: cfg.Width, Height: cfg.Height}
+	switch format {
+	case "jpeg":
+		img.ContentType = "image/jpeg"
+		if o := jpegOrientation(data); o > 1 && o <= 8 {
+			// the orientation goes with the metadata, so bake it
+			// into the pixels instead
+			m = orient(m, o)
+			b := m.Bounds()
+			img.Width, img.Height = b.Dx(), b.Dy()
+			var buf bytes.Buffer
+			if err := jpeg.Encode(&buf, m, &jpeg.Options{Quality: jpegQuality}); err != nil {
+				return nil, nil, fmt.Errorf("error encoding image: %w", err)
+			}
+			img.Data = buf.Bytes()
+		} else {
+			img.Data = stripJpeg(data)
+		}
+	case "png":
+		img.ContentType = "image/png"
+		img.Data = stripPng(data)
+	case "gif":
+		img.ContentType = "image/gif"
+		img.Data = stripGif(data)
+	default:
+		return nil, nil, fmt.Errorf("%w: unsupported format %s", ErrInvalid, format)
+	}
+	return img, m, nil
+}
+
+// renderVariants makes a thumbnail for every variant width narrower
+// than m, in the same kind of format as the original, and a lossless
+// WebP of each size where that comes out smaller.
+func renderVariants(m image.Image, contentType string, size int) ([]Variant, error) {
+	b := m.Bounds()
+	var variants []Variant
+	sizes := []image.Image{}
+	for _, w := range VariantWidths {
+		if w >= b.Dx() {
+			continue
+		}
+		h := max(1, b.Dy()*w/b.Dx())
+		dst := image.NewNRGBA(image.Rect(0, 0, w, h))
+		xdraw.CatmullRom.Scale(dst, dst.Rect, m, b, draw.Src, nil)
+		sizes = append(sizes, dst)
+	}
+
+	for _, s := range sizes {
+		var buf bytes.Buffer
+		v := Variant{Width: s.Bounds().Dx(), Height: s.Bounds().Dy()}
+		if contentType == "image/jpeg" {
+			v.ContentType = "image/jpeg"
+			if err := jpeg.Encode(&buf, s, &jpeg.Options{Quality: jpegVariantQuality}); err != nil {
+				return nil, fmt.Errorf("error encoding variant: %w", err)
+			}
+		} else {
+			v.ContentType = "image/png"
+			if err := png.Encode(&buf, s); err != nil {
+				return nil, fmt.Errorf("error encoding variant: %w", err)
+			}
+		}
+		v.Data = buf.Bytes()
+		variants = append(variants, v)
+	}
+
+	// a full size WebP competes with the original, except for photos
+	// where lossless never beats JPEG and would only cost memory
+	if contentType != "image/jpeg" {
+		sizes = append(sizes, m)
+	}
+	thumbnails := len(variants)
+	for i, s := range sizes {
+		var buf bytes.Buffer
+		if err := nativewebp.Encode(&buf, s, nil); err != nil {
+			return nil, fmt.Errorf("error encoding webp variant: %w", err)
+		}
+		limit := size
+		if i < thumbnails {
+			limit = len(variants[i].Data)
+		}
+		if buf.Len() >= limit {
+			continue
+		}
+		variants = append(variants, Variant{
+			Data:        buf.Bytes(),
+			ContentType: "image/webp",
+			Width:       s.Bounds().Dx(),
+			Height:      s.Bounds().Dy(),
+		})
+	}
+	return variants, nil
+}
+
+// orient applies an EXIF orientation to m.
+func orient(m image.Image, orientation int) image.Image {
+	src := toNRGBA(m)
+	w, h := src.Rect.Dx(), src.Rect.Dy()
+	dw, dh := w, h
+	if orientation >= 5 {
+		dw, dh = h, w
+	}
+	dst := image.NewNRGBA(image.Rect(0, 0, dw, dh))
+	for y := 0; y < dh; y++ {
+		for x := 0; x < dw; x++ {
+			var sx, sy int
+			switch orientation {
+			case 2:
+				sx, sy = w-1-x, y
+			case 3:
+				sx, sy = w-1-x, h-1-y
+			case 4:
+				sx, sy = x, h-1-y
+			case 5:
+				sx, sy = y, x
+			case 6:
+				sx, sy = y, h-1-x
+			case 7:
+				sx, sy = w-1-y, h-1-x
+			case 8:
+				sx, sy = w-1-y, x
+			default:
+				sx, sy = x, y
+			}
+			copy(dst.Pix[y*dst.Stride+4*x:y*dst.Stride+4*x+4], src.Pix[sy*src.Stride+4*sx:])
+		}
+	}
+	return dst
+}
+
+func toNRGBA(m image.Image) *image.NRGBA {
+	if n, ok := m.(*image.NRGBA); ok && n.Rect.Min == (image.Point{}) {
+		return n
+	}
+	b := m.Bounds()
+	dst := image.NewNRGBA(image.Rect(0, 0, b.Dx(), b.Dy()))
+	draw.Draw(dst, dst.Rect, m, b.Min, draw.Src)
+	return dst
+}
@@ -0,0 +1,207 @@
+package images
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"image"
+	"image/color"
+	"image/color/palette"
+	"image/gif"
+	"image/jpeg"
+	"image/png"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/image/webp"
+)
+
+func testImage(w, h int) *image.NRGBA {
+	m := image.NewNRGBA(image.Rect(0, 0, w, h))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			m.SetNRGBA(x, y, color.NRGBA{uint8(x), uint8(y), 0x80, 0xff})
+		}
+	}
+	return m
+}
+
+// withExif inserts an APP1 segment with the given orientation and a
+// GPS-looking payload after the SOI marker of a JPEG.
+func withExif(t *testing.T, data []byte, orientation uint16) []byte {
+	var tiff bytes.Buffer
+	tiff.WriteString("II*\x00")
+	binary.Write(&tiff, binary.LittleEndian, uint32(8))
+	binary.Write(&tiff, binary.LittleEndian, uint16(1))
+	binary.Write(&tiff, binary.LittleEndian, []uint16{exifOrientationTag, 3})
+	binary.Write(&tiff, binary.LittleEndian, uint32(1))
+	binary.Write(&tiff, binary.LittleEndian, []uint16{orientation, 0})
+	tiff.WriteString("GPS 40.7128N 74.0060W")
+
+	payload := append([]byte("Exif\x00\x00"), tiff.Bytes()...)
+	segment := []byte{0xff, jpegAPP1, 0, 0}
+	binary.BigEndian.PutUint16(segment[2:], uint16(len(payload)+2))
+	segment = append(segment, payload...)
+
+	out := append([]byte{}, data[:2]...)
+	out = append(out, segment...)
+	return append(out, data[2:]...)
+}
+
+func TestProcess_JpegStripsExif(t *testing.T) {
+	var buf bytes.Buffer
+	assert.NoError(t, jpeg.Encode(&buf, testImage(700, 400), nil))
+
+	img, err := Process(withExif(t, buf.Bytes(), 1))
+	assert.NoError(t, err)
+	assert.Equal(t, "image/jpeg", img.ContentType)
+	assert.Equal(t, buf.Bytes(), img.Data)
+	assert.NotContains(t, string(img.Data), "GPS")
+	assert.Equal(t, 700, img.Width)
+	assert.Equal(t, 400, img.Height)
+
+	var widths []int
+	for _, v := range img.Variants {
+		assert.Equal(t, "image/jpeg", v.ContentType)
+		widths = append(widths, v.Width)
+	}
+	assert.Equal(t, []int{320, 640}, widths)
+	assert.Equal(t, 182, img.Variants[0].Height)
+}
+
+func TestProcess_JpegAppliesOrientation(t *testing.T) {
+	var buf bytes.Buffer
+	assert.NoError(t, jpeg.Encode(&buf, testImage(60, 30), nil))
+
+	img, err := Process(withExif(t, buf.Bytes(), 6))
+	assert.NoError(t, err)
+	assert.Equal(t, 30, img.Width)
+	assert.Equal(t, 60, img.Height)
+	assert.NotContains(t, string(img.Data), "GPS")
+	assert.Equal(t, 0, jpegOrientation(img.Data))
+}
+
+func TestProcess_PngStripsText(t *testing.T) {
+	var buf bytes.Buffer
+	assert.NoError(t, png.Encode(&buf, testImage(10, 10)))
+	data := buf.Bytes()
+
+	// insert a tEXt chunk after IHDR; stripping doesn't check CRCs
+	ihdrEnd := len(pngSignature) + 12 + 13
+	chunk := []byte{0, 0, 0, 8}
+	chunk = append(chunk, "tEXtGPS here\x00\x00\x00\x00"...)
+	withText := append(append(append([]byte{}, data[:ihdrEnd]...), chunk...), data[ihdrEnd:]...)
+
+	assert.Equal(t, data, stripPng(withText))
+}
+
+// testGif returns a two frame animated GIF that loops forever, with a
+// comment and an XMP packet inserted before its first frame.
+func testGif(t *testing.T) (clean, tagged []byte) {
+	g := &gif.GIF{LoopCount: 0}
+	for i := 0; i < 2; i++ {
+		m := image.NewPaletted(image.Rect(0, 0, 7, 5), palette.Plan9)
+		for j := range m.Pix {
+			m.Pix[j] = uint8(i*40 + j)
+		}
+		g.Image = append(g.Image, m)
+		g.Delay = append(g.Delay, 10)
+	}
+	var buf bytes.Buffer
+	assert.NoError(t, gif.EncodeAll(&buf, g))
+	clean = buf.Bytes()
+
+	i := 13 + gifColorTableSize(clean[10])
+	var ext []byte
+	ext = append(ext, gifExtension, gifComment, 12)
+	ext = append(ext, "GPS 40.7128N"...)
+	ext = append(ext, 0)
+	ext = append(ext, gifExtension, gifApplication, 11)
+	ext = append(ext, "XMP DataXMP"...)
+	ext = append(ext, 10)
+	ext = append(ext, "<x:xmpmeta"...)
+	ext = append(ext, 0)
+	tagged = append(append(append([]byte{}, clean[:i]...), ext...), clean[i:]...)
+	return clean, tagged
+}
+
+func TestProcess_GifStripsMetadata(t *testing.T) {
+	clean, tagged := testGif(t)
+
+	img, err := Process(tagged)
+	assert.NoError(t, err)
+	assert.Equal(t, "image/gif", img.ContentType)
+	assert.Equal(t, clean, img.Data)
+	assert.NotContains(t, string(img.Data), "GPS")
+	assert.NotContains(t, string(img.Data), "xmpmeta")
+
+	// the animation and its looping survive
+	g, err := gif.DecodeAll(bytes.NewReader(img.Data))
+	assert.NoError(t, err)
+	assert.Len(t, g.Image, 2)
+	assert.Equal(t, 0, g.LoopCount)
+
+	// a truncated GIF is cut at the last whole block
+	truncated := stripGif(tagged[:len(tagged)-20])
+	assert.NotContains(t, string(truncated), "GPS")
+	assert.Equal(t, byte(gifTrailer), truncated[len(truncated)-1])
+}
+
+// FuzzProcess checks that no upload makes processing panic, and that
+// what it keeps of a picture still decodes.
+func FuzzProcess(f *testing.F) {
+	var buf bytes.Buffer
+	jpeg.Encode(&buf, testImage(33, 17), nil)
+	f.Add(withExif(&testing.T{}, buf.Bytes(), 6))
+	buf.Reset()
+	png.Encode(&buf, testImage(1, 3))
+	f.Add(buf.Bytes())
+	_, tagged := testGif(&testing.T{})
+	f.Add(tagged)
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		img, err := Process(data)
+		if err != nil {
+			return
+		}
+		if _, _, err := image.Decode(bytes.NewReader(img.Data)); err != nil {
+			t.Fatalf("processed %s doesn't decode: %v", img.ContentType, err)
+		}
+		for _, v := range img.Variants {
+			// only a WebP can be as wide as the original
+			full := v.Width == img.Width && v.ContentType == "image/webp"
+			if v.Width <= 0 || v.Height <= 0 || v.Width > img.Width || v.Width == img.Width && !full {
+				t.Fatalf("bad variant %dx%d of %dx%d", v.Width, v.Height, img.Width, img.Height)
+			}
+			if _, _, err := image.Decode(bytes.NewReader(v.Data)); err != nil {
+				t.Fatalf("%s variant doesn't decode: %v", v.ContentType, err)
+			}
+		}
+	})
+}
+
+func TestProcess_WebpVariants(t *testing.T) {
+	var buf bytes.Buffer
+	assert.NoError(t, png.Encode(&buf, testImage(700, 400)))
+	img, err := Process(buf.Bytes())
+	assert.NoError(t, err)
+
+	var webps []string
+	for _, v := range img.Variants {
+		if v.ContentType != "image/webp" {
+			continue
+		}
+		webps = append(webps, fmt.Sprintf("%dx%d", v.Width, v.Height))
+		m, err := webp.Decode(bytes.NewReader(v.Data))
+		assert.NoError(t, err)
+		assert.Equal(t, v.Width, m.Bounds().Dx())
+		assert.Equal(t, v.Height, m.Bounds().Dy())
+	}
+	// lossless WebP beats PNG at every size, the original's included
+	assert.Equal(t, []string{"320x182", "640x365", "700x400"}, webps)
+}
+
+func TestProcess_RejectsNonImages(t *testing.T) {
+	_, err := Process([]byte("not really a png"))
+	assert.ErrorContains(t, err, "invalid image")
+}
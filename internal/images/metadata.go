@@ -0,0 +1,236 @@
+package images
+
+import (
+	"bytes"
+	"encoding/binary"
+)
+
+const (
+	jpegSOI   = 0xd8
+	jpegSOS   = 0xda
+	jpegAPP1  = 0xe1
+	jpegAPP13 = 0xed
+	jpegCOM   = 0xfe
+
+	gifExtension   = 0x21
+	gifImage       = 0x2c
+	gifTrailer     = 0x3b
+	gifComment     = 0xfe
+	gifApplication = 0xff
+
+	exifOrientationTag = 0x0112
+)
+
+var (
+	exifHeader   = []byte("Exif\x00\x00")
+	pngSignature = []byte("\x89PNG\r\n\x1a\n")
+
+	// gifKeptApplications are the application extensions that change
+	// how a GIF plays or looks: looping and ICC profiles.
+	gifKeptApplications = map[string]bool{
+		"NETSCAPE2.0": true,
+		"ANIMEXTS1.0": true,
+		"ICCRGBG1012": true,
+	}
+)
+
+// jpegSegments calls fn with each marker and its segment, including
+// the marker and length, up to the start of the scan. It returns the
+// offset of the start of scan marker, or -1 if the JPEG is malformed.
+func jpegSegments(data []byte, fn func(marker byte, segment []byte)) int {
+	if len(data) < 4 || data[0] != 0xff || data[1] != jpegSOI {
+		return -1
+	}
+	i := 2
+	for i+4 <= len(data) {
+		if data[i] != 0xff {
+			return -1
+		}
+		marker := data[i+1]
+		if marker == 0xff {
+			// fill byte
+			i++
+			continue
+		}
+		if marker == jpegSOS {
+			return i
+		}
+		n := int(binary.BigEndian.Uint16(data[i+2:]))
+		if n < 2 || i+2+n > len(data) {
+			return -1
+		}
+		fn(marker, data[i:i+2+n])
+		i += 2 + n
+	}
+	return -1
+}
+
+// stripJpeg removes the EXIF, XMP and IPTC segments and comments from
+// a JPEG without re-encoding it. Segments the image needs to display
+// correctly, like ICC profiles, are kept.
+func stripJpeg(data []byte) []byte {
+	out := []byte{0xff, jpegSOI}
+	sos := jpegSegments(data, func(marker byte, segment []byte) {
+		switch marker {
+		case jpegAPP1, jpegAPP13, jpegCOM:
+			return
+		}
+		out = append(out, segment...)
+	})
+	if sos < 0 {
+		return data
+	}
+	return append(out, data[sos:]...)
+}
+
+// jpegOrientation returns the EXIF orientation of a JPEG, or 0 if it
+// has none.
+func jpegOrientation(data []byte) int {
+	orientation := 0
+	jpegSegments(data, func(marker byte, segment []byte) {
+		if marker != jpegAPP1 || orientation != 0 {
+			return
+		}
+		tiff, ok := bytes.CutPrefix(segment[4:], exifHeader)
+		if !ok {
+			return
+		}
+		orientation = exifOrientation(tiff)
+	})
+	return orientation
+}
+
+func exifOrientation(tiff []byte) int {
+	if len(tiff) < 8 {
+		return 0
+	}
+	var order binary.ByteOrder
+	switch string(tiff[:2]) {
+	case "II":
+		order = binary.LittleEndian
+	case "MM":
+		order = binary.BigEndian
+	default:
+		return 0
+	}
+	ifd := int(order.Uint32(tiff[4:]))
+	if ifd < 8 || ifd+2 > len(tiff) {
+		return 0
+	}
+	n := int(order.Uint16(tiff[ifd:]))
+	for i := 0; i < n; i++ {
+		entry := ifd + 2 + 12*i
+		if entry+12 > len(tiff) {
+			return 0
+		}
+		if order.Uint16(tiff[entry:]) == exifOrientationTag {
+			return int(order.Uint16(tiff[entry+8:]))
+		}
+	}
+	return 0
+}
+
+// stripPng removes the EXIF, text and timestamp chunks from a PNG.
+func stripPng(data []byte) []byte {
+	if !bytes.HasPrefix(data, pngSignature) {
+		return data
+	}
+	out := append([]byte(nil), pngSignature...)
+	i := len(pngSignature)
+	for i+12 <= len(data) {
+		n := int(binary.BigEndian.Uint32(data[i:]))
+		end := i + 12 + n
+		if n < 0 || end > len(data) {
+			return data
+		}
+		switch string(data[i+4 : i+8]) {
+		case "eXIf", "tEXt", "zTXt", "iTXt", "tIME":
+		default:
+			out = append(out, data[i:end]...)
+		}
+		i = end
+	}
+	return out
+}
+
+// stripGif removes the comment extensions from a GIF, and application
+// extensions like XMP that don't affect how it plays, without
+// re-encoding its frames. Anything after a malformed block is dropped,
+// since decoders stop there too.
+func stripGif(data []byte) []byte {
+	if len(data) < 13 || !bytes.HasPrefix(data, []byte("GIF8")) {
+		return data
+	}
+	i := 13 + gifColorTableSize(data[10])
+	if i > len(data) {
+		return data
+	}
+	out := append([]byte(nil), data[:i]...)
+	for i < len(data) {
+		start := i
+		switch data[i] {
+		case gifExtension:
+			if i+2 > len(data) {
+				return append(out, gifTrailer)
+			}
+			label := data[i+1]
+			end := gifSubBlocks(data, i+2)
+			if end < 0 {
+				return append(out, gifTrailer)
+			}
+			i = end
+			switch label {
+			case gifComment:
+				continue
+			case gifApplication:
+				// the first sub-block holds the application's
+				// identifier and authentication code
+				if data[start+2] != 11 || !gifKeptApplications[string(data[start+3:start+14])] {
+					continue
+				}
+			}
+		case gifImage:
+			if i+10 > len(data) {
+				return append(out, gifTrailer)
+			}
+			// the descriptor, local color table and the LZW minimum
+			// code size come before the image data
+			i += 10 + gifColorTableSize(data[i+9])
+			if i+1 > len(data) {
+				return append(out, gifTrailer)
+			}
+			end := gifSubBlocks(data, i+1)
+			if end < 0 {
+				return append(out, gifTrailer)
+			}
+			i = end
+		default:
+			return append(out, gifTrailer)
+		}
+		out = append(out, data[start:i]...)
+	}
+	return append(out, gifTrailer)
+}
+
+// gifColorTableSize returns the size of the color table a GIF's
+// packed screen or image descriptor field says follows it.
+func gifColorTableSize(packed byte) int {
+	if packed&0x80 == 0 {
+		return 0
+	}
+	return 3 << (packed&0x07 + 1)
+}
+
+// gifSubBlocks returns the offset just past the data sub-blocks
+// starting at i, or -1 if they run past the end of data.
+func gifSubBlocks(data []byte, i int) int {
+	for i < len(data) {
+		n := int(data[i])
+		i++
+		if n == 0 {
+			return i
+		}
+		i += n
+	}
+	return -1
+}
@@ -0,0 +1,23 @@
+package ipdata
+
+import (
+	"fmt"
+
+	env "github.com/Netflix/go-env"
+)
+
+type config struct {
+	// TrustedProxies lists, comma separated, the CIDRs or IPs of the
+	// proxies in front of the server, whose forwarding headers are
+	// believed. Requests from anywhere else are taken at their
+	// connection's address.
+	TrustedProxies string `env:"TRUSTED_PROXIES"`
+}
+
+func newConfig() (*config, error) {
+	conf := config{}
+	if _, err := env.UnmarshalFromEnviron(&conf); err != nil {
+		return nil, fmt.Errorf("failed to load config: %w", err)
+	}
+	return &conf, nil
+}
@@ -5,37 +5,145 @@ import (
 	"net"
 	"net/http"
 	"os"
+	"strings"
+	"sync/atomic"
+	"time"
 
+	"github.com/btschwartz12/site/internal/metrics"
 	"github.com/btschwartz12/site/internal/slack"
 	"github.com/ipinfo/go/v2/ipinfo"
 )
 
 var (
-	ipinfoToken string
+	ipinfoToken    string
+	trustedProxies atomic.Pointer[[]*net.IPNet]
 )
 
 func init() {
 	ipinfoToken = os.Getenv("IPINFO_TOKEN")
+	trustedProxies.Store(&[]*net.IPNet{})
 }
 
 func GetIpinfoRecord(ip net.IP) *ipinfo.Core {
 	client := ipinfo.NewClient(nil, nil, ipinfoToken)
+	start := time.Now()
 	info, err := client.GetIPInfo(ip)
+	metrics.IpinfoLookupDuration.Observe(time.Since(start).Seconds())
 	if err != nil {
+		metrics.IpinfoLookupFailures.Inc()
 		return nil
 	}
 	return info
 }
 
+// Init loads the trusted proxies from the environment.
+func Init() error {
+	conf, err := newConfig()
+	if err != nil {
+		return err
+	}
+	var proxies []string
+	for _, p := range strings.Split(conf.TrustedProxies, ",") {
+		if p = strings.TrimSpace(p); p != "" {
+			proxies = append(proxies, p)
+		}
+	}
+	return SetTrustedProxies(proxies)
+}
+
+// SetTrustedProxies sets the CIDRs or IPs of the proxies whose
+// forwarding headers GetIp believes.
+func SetTrustedProxies(proxies []string) error {
+	nets := make([]*net.IPNet, 0, len(proxies))
+	for _, p := range proxies {
+		if !strings.Contains(p, "/") {
+			ip := net.ParseIP(p)
+			if ip == nil {
+				return fmt.Errorf("invalid trusted proxy %q", p)
+			}
+			bits := 8 * net.IPv6len
+			if ip4 := ip.To4(); ip4 != nil {
+				ip, bits = ip4, 8*net.IPv4len
+			}
+			nets = append(nets, &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)})
+			continue
+		}
+		_, n, err := net.ParseCIDR(p)
+		if err != nil {
+			return fmt.Errorf("invalid trusted proxy %q: %w", p, err)
+		}
+		nets = append(nets, n)
+	}
+	trustedProxies.Store(&nets)
+	return nil
+}
+
+func isTrusted(ip net.IP) bool {
+	for _, n := range *trustedProxies.Load() {
+		if n.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// GetIp returns the IP of the client behind r. Forwarding headers are
+// only believed when they come from a trusted proxy, and
+// X-Forwarded-For is read right to left, so the client can't spoof
+// its IP by sending the headers itself. It returns nil if r has no
+// usable address at all.
 func GetIp(r *http.Request) net.IP {
-	ip := r.Header.Get("X-Real-Ip")
-	if ip == "" {
-		ip = r.Header.Get("X-Forwarded-For")
+	ip := parseIp(r.RemoteAddr)
+	if ip == nil || !isTrusted(ip) {
+		return ip
+	}
+
+	if cf := parseIp(r.Header.Get("CF-Connecting-IP")); cf != nil {
+		return cf
+	}
+
+	// each proxy appends the address it got the request from, so
+	// the client is the rightmost hop that isn't one of ours
+	var hops []string
+	for _, v := range r.Header.Values("X-Forwarded-For") {
+		hops = append(hops, strings.Split(v, ",")...)
+	}
+	for i := len(hops) - 1; i >= 0; i-- {
+		hop := parseIp(hops[i])
+		if hop == nil {
+			// whatever is left of a garbled hop can't be believed
+			return ip
+		}
+		ip = hop
+		if !isTrusted(hop) {
+			return hop
+		}
+	}
+	if len(hops) > 0 {
+		return ip
+	}
+
+	if real := parseIp(r.Header.Get("X-Real-Ip")); real != nil {
+		return real
+	}
+	return ip
+}
+
+// parseIp parses an IP with or without a port, brackets or zone.
+func parseIp(s string) net.IP {
+	s = strings.TrimSpace(s)
+	if host, _, err := net.SplitHostPort(s); err == nil {
+		s = host
+	}
+	s = strings.TrimSuffix(strings.TrimPrefix(s, "["), "]")
+	if i := strings.IndexByte(s, '%'); i >= 0 {
+		s = s[:i]
 	}
-	if ip == "" {
-		ip = r.RemoteAddr
+	ip := net.ParseIP(s)
+	if ip4 := ip.To4(); ip4 != nil {
+		return ip4
 	}
-	return net.ParseIP(ip)
+	return ip
 }
 
 func GetVisitBlocks(r *http.Request, ip net.IP, info *ipinfo.Core) []slack.Block {
@@ -0,0 +1,56 @@
+package ipdata
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetIp(t *testing.T) {
+	assert.NoError(t, SetTrustedProxies([]string{"172.16.0.0/12", "fd00::1"}))
+	t.Cleanup(func() { SetTrustedProxies(nil) })
+	assert.Error(t, SetTrustedProxies([]string{"not an ip"}))
+
+	tests := []struct {
+		name    string
+		remote  string
+		headers map[string]string
+		want    string
+	}{
+		{"port stripped", "1.2.3.4:5678", nil, "1.2.3.4"},
+		{"ipv6", "[2001:db8::1]:443", nil, "2001:db8::1"},
+		{"ipv6 zone", "[fe80::1%eth0]:443", nil, "fe80::1"},
+		{"headers from untrusted peer ignored", "1.2.3.4:1", map[string]string{
+			"X-Forwarded-For":  "9.9.9.9",
+			"X-Real-Ip":        "9.9.9.9",
+			"CF-Connecting-IP": "9.9.9.9",
+		}, "1.2.3.4"},
+		{"cloudflare", "172.18.0.5:1", map[string]string{
+			"CF-Connecting-IP": "5.6.7.8",
+			"X-Forwarded-For":  "9.9.9.9",
+		}, "5.6.7.8"},
+		{"spoofed hop skipped", "172.18.0.5:1", map[string]string{
+			"X-Forwarded-For": "9.9.9.9, 5.6.7.8, 172.18.0.2",
+		}, "5.6.7.8"},
+		{"hop with port", "[fd00::1]:1", map[string]string{
+			"X-Forwarded-For": "[2001:db8::2]:1234",
+		}, "2001:db8::2"},
+		{"garbled hop", "172.18.0.5:1", map[string]string{
+			"X-Forwarded-For": "5.6.7.8, garbage",
+		}, "172.18.0.5"},
+		{"real ip", "172.18.0.5:1", map[string]string{
+			"X-Real-Ip": "5.6.7.8",
+		}, "5.6.7.8"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest("GET", "/", nil)
+			r.RemoteAddr = tt.remote
+			for k, v := range tt.headers {
+				r.Header.Set(k, v)
+			}
+			assert.Equal(t, tt.want, GetIp(r).String())
+		})
+	}
+}
@@ -0,0 +1,109 @@
+package logging
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"runtime/debug"
+	"time"
+
+	"github.com/go-chi/chi/v5/middleware"
+	"go.uber.org/zap"
+
+	"github.com/btschwartz12/site/internal/ipdata"
+)
+
+type ctxKey struct{}
+
+// quietPaths are polled by probes, so they are only logged when they
+// fail.
+var quietPaths = map[string]bool{
+	"/healthz": true,
+	"/readyz":  true,
+}
+
+// FromContext returns the logger for the request ctx belongs to, or
+// fallback outside of a request.
+func FromContext(ctx context.Context, fallback *zap.SugaredLogger) *zap.SugaredLogger {
+	if l, ok := ctx.Value(ctxKey{}).(*zap.SugaredLogger); ok {
+		return l
+	}
+	return fallback
+}
+
+// FromRequest returns the logger for r, tagged with its request id,
+// or base if r didn't come through Middleware.
+func FromRequest(base *zap.SugaredLogger, r *http.Request) *zap.SugaredLogger {
+	return FromContext(r.Context(), base)
+}
+
+// Middleware gives every request a logger tagged with its request id,
+// available through FromContext and FromRequest, and logs each request once it has
+// been served. It must run after middleware.RequestID.
+func Middleware(logger *zap.SugaredLogger) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			start := time.Now()
+			reqID := middleware.GetReqID(r.Context())
+			l := logger.With("request_id", reqID)
+			w.Header().Set(middleware.RequestIDHeader, reqID)
+
+			ww := middleware.NewWrapResponseWriter(w, r.ProtoMajor)
+			next.ServeHTTP(ww, r.WithContext(context.WithValue(r.Context(), ctxKey{}, l)))
+
+			status := ww.Status()
+			if status == 0 {
+				status = http.StatusOK
+			}
+			if quietPaths[r.URL.Path] && status < http.StatusBadRequest {
+				return
+			}
+			fields := []any{
+				"method", r.Method,
+				"path", r.URL.Path,
+				"status", status,
+				"bytes", ww.BytesWritten(),
+				"duration", time.Since(start),
+				"ip", ipdata.GetIp(r),
+				"user_agent", r.UserAgent(),
+			}
+			if status >= http.StatusInternalServerError {
+				l.Errorw("request", fields...)
+			} else {
+				l.Infow("request", fields...)
+			}
+		})
+	}
+}
+
+// Recoverer turns a panic in a handler into a 500, logging it with
+// its stack. Run after Middleware, the panic is logged with the
+// request id.
+func Recoverer(logger *zap.SugaredLogger) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			defer func() {
+				rec := recover()
+				if rec == nil {
+					return
+				}
+				if err, ok := rec.(error); ok && errors.Is(err, http.ErrAbortHandler) {
+					// net/http uses this to abort a response quietly
+					panic(rec)
+				}
+				FromContext(r.Context(), logger).Errorw("panic serving request",
+					"panic", rec,
+					"method", r.Method,
+					"path", r.URL.Path,
+					"stack", string(debug.Stack()),
+				)
+				// a hijacked connection, like a websocket, can't be
+				// written to anymore
+				if r.Header.Get("Connection") != "Upgrade" {
+					w.WriteHeader(http.StatusInternalServerError)
+				}
+			}()
+			next.ServeHTTP(w, r)
+		})
+	}
+}
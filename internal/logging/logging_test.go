@@ -0,0 +1,52 @@
+package logging
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+func TestMiddleware(t *testing.T) {
+	core, logs := observer.New(zapcore.InfoLevel)
+	logger := zap.New(core).Sugar()
+
+	h := middleware.RequestID(Middleware(logger)(Recoverer(logger)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		FromRequest(nil, r).Infow("handling")
+		if r.URL.Path == "/panic" {
+			panic("boom")
+		}
+		w.Write([]byte("ok"))
+	}))))
+
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+	assert.Equal(t, http.StatusOK, rec.Code)
+	reqID := rec.Header().Get(middleware.RequestIDHeader)
+	assert.NotEmpty(t, reqID)
+
+	// the handler's own lines and the access log share the request id
+	entries := logs.TakeAll()
+	if assert.Len(t, entries, 2) {
+		assert.Equal(t, "handling", entries[0].Message)
+		assert.Equal(t, reqID, entries[0].ContextMap()["request_id"])
+		access := entries[1].ContextMap()
+		assert.Equal(t, reqID, access["request_id"])
+		assert.Equal(t, int64(http.StatusOK), access["status"])
+		assert.Equal(t, int64(2), access["bytes"])
+	}
+
+	rec = httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/panic", nil))
+	assert.Equal(t, http.StatusInternalServerError, rec.Code)
+	entries = logs.FilterMessage("panic serving request").TakeAll()
+	if assert.Len(t, entries, 1) {
+		assert.Equal(t, "boom", entries[0].ContextMap()["panic"])
+		assert.Contains(t, entries[0].ContextMap()["stack"], "logging.TestMiddleware")
+	}
+}
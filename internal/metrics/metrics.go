@@ -0,0 +1,104 @@
+package metrics
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "site"
+
+var (
+	requests = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "http_requests_total",
+		Help:      "HTTP requests by route and status.",
+	}, []string{"method", "route", "status"})
+	requestDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "http_request_duration_seconds",
+		Help:      "How long HTTP requests took to serve, by route.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"method", "route"})
+
+	// SurveyClients is the number of connected survey websockets.
+	SurveyClients = promauto.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "survey_websocket_clients",
+		Help:      "Connected survey websocket clients.",
+	})
+	// SurveyQueueDepth is the number of messages waiting in each of the
+	// survey's broadcast queues.
+	SurveyQueueDepth = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "survey_broadcast_queue_depth",
+		Help:      "Messages waiting to be broadcast to survey clients, by queue.",
+	}, []string{"queue"})
+
+	// UploadSize is the size of uploaded files, by storage area.
+	UploadSize = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "upload_size_bytes",
+		Help:      "Size of uploaded files, by storage area.",
+		// 1 KB to 1 GB
+		Buckets: prometheus.ExponentialBuckets(1<<10, 4, 11),
+	}, []string{"area"})
+
+	// IpinfoLookupDuration is how long ipinfo lookups take.
+	IpinfoLookupDuration = promauto.NewHistogram(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "ipinfo_lookup_duration_seconds",
+		Help:      "How long ipinfo lookups took.",
+		Buckets:   prometheus.DefBuckets,
+	})
+	// IpinfoLookupFailures counts failed ipinfo lookups.
+	IpinfoLookupFailures = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "ipinfo_lookup_failures_total",
+		Help:      "Failed ipinfo lookups.",
+	})
+
+	// SlackWebhooks counts Slack webhook calls by result, either
+	// "success" or "failure".
+	SlackWebhooks = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "slack_webhooks_total",
+		Help:      "Slack webhook calls, by result.",
+	}, []string{"result"})
+)
+
+// Handler serves the metrics in the Prometheus text format.
+func Handler() http.Handler {
+	return promhttp.Handler()
+}
+
+// Middleware records the count and latency of requests. Requests are
+// labelled with chi's route pattern rather than the raw path, so ids in
+// paths don't blow up the number of series.
+func Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		ww := middleware.NewWrapResponseWriter(w, r.ProtoMajor)
+		next.ServeHTTP(ww, r)
+
+		route := "unmatched"
+		if rctx := chi.RouteContext(r.Context()); rctx != nil {
+			if pattern := rctx.RoutePattern(); pattern != "" {
+				route = pattern
+			}
+		}
+		status := ww.Status()
+		if status == 0 {
+			// nothing was written, which net/http sends as a 200
+			status = http.StatusOK
+		}
+		requests.WithLabelValues(r.Method, route, strconv.Itoa(status)).Inc()
+		requestDuration.WithLabelValues(r.Method, route).Observe(time.Since(start).Seconds())
+	})
+}
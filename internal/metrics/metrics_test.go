@@ -0,0 +1,29 @@
+package metrics
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMiddleware(t *testing.T) {
+	sub := chi.NewRouter()
+	sub.Get("/picture/{id}", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusTeapot)
+	})
+	r := chi.NewRouter()
+	r.Use(Middleware)
+	r.Mount("/pics", sub)
+
+	for _, path := range []string{"/pics/picture/1", "/pics/picture/2"} {
+		r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, path, nil))
+	}
+
+	// both requests count towards the pattern, not their own paths
+	assert.Equal(t, 2.0, testutil.ToFloat64(requests.WithLabelValues("GET", "/pics/picture/{id}", "418")))
+	assert.Equal(t, 0.0, testutil.ToFloat64(requests.WithLabelValues("GET", "/pics/picture/1", "418")))
+}
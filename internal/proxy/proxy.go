@@ -1,6 +1,8 @@
 package proxy
 
 import (
+	"context"
+	"fmt"
 	"net/http"
 	"net/http/httputil"
 	"net/url"
@@ -18,3 +20,19 @@ func Proxy(target, prefix string) http.HandlerFunc {
 		proxy.ServeHTTP(w, r)
 	}
 }
+
+// Reachable checks that target answers HTTP requests at all. Any
+// response counts, since the target may not serve anything at its
+// root.
+func Reachable(ctx context.Context, target string) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodHead, target, nil)
+	if err != nil {
+		return fmt.Errorf("error creating request: %w", err)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("error reaching %s: %w", target, err)
+	}
+	resp.Body.Close()
+	return nil
+}
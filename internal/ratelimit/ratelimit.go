@@ -0,0 +1,155 @@
+package ratelimit
+
+import (
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"golang.org/x/time/rate"
+
+	"github.com/btschwartz12/site/internal/ipdata"
+)
+
+// Options configures a Limiter.
+type Options struct {
+	// Rate and Burst bound how many requests a client can make.
+	Rate  rate.Limit
+	Burst int
+	// A client that gets LockoutThreshold 404s within LockoutWindow
+	// is locked out for LockoutDuration. Zero disables lockouts.
+	LockoutThreshold int
+	LockoutWindow    time.Duration
+	LockoutDuration  time.Duration
+}
+
+type client struct {
+	limiter     *rate.Limiter
+	misses      int
+	windowStart time.Time
+	lockedUntil time.Time
+	lastSeen    time.Time
+}
+
+// Limiter rate limits requests per client IP and locks out clients
+// that keep asking for things that don't exist, so that ids can't
+// be enumerated.
+type Limiter struct {
+	opts Options
+	now  func() time.Time
+
+	mu      sync.Mutex
+	clients map[string]*client
+}
+
+func New(opts Options) *Limiter {
+	return &Limiter{
+		opts:    opts,
+		now:     time.Now,
+		clients: map[string]*client{},
+	}
+}
+
+// Middleware rejects requests from clients that are over their rate
+// limit or locked out, and counts 404 responses towards lockouts.
+func (l *Limiter) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		key := ClientKey(r)
+		if retry, ok := l.allow(key); !ok {
+			w.Header().Set("Retry-After", strconv.Itoa(int(retry.Seconds())+1))
+			http.Error(w, "Too Many Requests", http.StatusTooManyRequests)
+			return
+		}
+
+		sw := &statusWriter{ResponseWriter: w, status: http.StatusOK}
+		next.ServeHTTP(sw, r)
+		if sw.status == http.StatusNotFound {
+			l.miss(key)
+		}
+	})
+}
+
+// allow reports whether the client may make a request now, and if
+// not, how long until it may.
+func (l *Limiter) allow(key string) (time.Duration, bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	c := l.client(key, now)
+	if now.Before(c.lockedUntil) {
+		return c.lockedUntil.Sub(now), false
+	}
+	res := c.limiter.ReserveN(now, 1)
+	if delay := res.DelayFrom(now); delay > 0 {
+		res.CancelAt(now)
+		return delay, false
+	}
+	return 0, true
+}
+
+func (l *Limiter) miss(key string) {
+	if l.opts.LockoutThreshold <= 0 {
+		return
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	c := l.client(key, now)
+	if now.Sub(c.windowStart) > l.opts.LockoutWindow {
+		c.windowStart = now
+		c.misses = 0
+	}
+	c.misses++
+	if c.misses >= l.opts.LockoutThreshold {
+		c.lockedUntil = now.Add(l.opts.LockoutDuration)
+		c.misses = 0
+	}
+}
+
+func (l *Limiter) client(key string, now time.Time) *client {
+	c, ok := l.clients[key]
+	if !ok {
+		l.evict(now)
+		c = &client{
+			limiter:     rate.NewLimiter(l.opts.Rate, l.opts.Burst),
+			windowStart: now,
+		}
+		l.clients[key] = c
+	}
+	c.lastSeen = now
+	return c
+}
+
+// evict drops clients that have been idle long enough that they
+// have a full burst again and no lockout, so the map stays small.
+func (l *Limiter) evict(now time.Time) {
+	idle := l.opts.LockoutWindow + l.opts.LockoutDuration
+	if refill := time.Duration(float64(l.opts.Burst) / float64(l.opts.Rate) * float64(time.Second)); refill > idle {
+		idle = refill
+	}
+	for key, c := range l.clients {
+		if now.Sub(c.lastSeen) > idle && now.After(c.lockedUntil) {
+			delete(l.clients, key)
+		}
+	}
+}
+
+// ClientKey identifies the client making a request, by its IP.
+func ClientKey(r *http.Request) string {
+	if ip := ipdata.GetIp(r); ip != nil {
+		return ip.String()
+	}
+	return r.RemoteAddr
+}
+
+type statusWriter struct {
+	http.ResponseWriter
+	status int
+}
+
+func (w *statusWriter) WriteHeader(status int) {
+	w.status = status
+	w.ResponseWriter.WriteHeader(status)
+}
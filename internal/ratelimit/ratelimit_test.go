@@ -0,0 +1,56 @@
+package ratelimit
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/time/rate"
+)
+
+func do(h http.Handler, ip string) int {
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.RemoteAddr = ip + ":1234"
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, req)
+	return w.Code
+}
+
+func TestLimiter_Rate(t *testing.T) {
+	l := New(Options{Rate: rate.Every(time.Minute), Burst: 2})
+	now := time.Now()
+	l.now = func() time.Time { return now }
+	h := l.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	assert.Equal(t, http.StatusOK, do(h, "1.1.1.1"))
+	assert.Equal(t, http.StatusOK, do(h, "1.1.1.1"))
+	assert.Equal(t, http.StatusTooManyRequests, do(h, "1.1.1.1"))
+	// other clients have their own budget
+	assert.Equal(t, http.StatusOK, do(h, "2.2.2.2"))
+
+	now = now.Add(time.Minute)
+	assert.Equal(t, http.StatusOK, do(h, "1.1.1.1"))
+}
+
+func TestLimiter_Lockout(t *testing.T) {
+	l := New(Options{
+		Rate:             rate.Inf,
+		LockoutThreshold: 3,
+		LockoutWindow:    time.Minute,
+		LockoutDuration:  time.Hour,
+	})
+	now := time.Now()
+	l.now = func() time.Time { return now }
+	h := l.Middleware(http.NotFoundHandler())
+
+	for i := 0; i < 3; i++ {
+		assert.Equal(t, http.StatusNotFound, do(h, "1.1.1.1"))
+	}
+	assert.Equal(t, http.StatusTooManyRequests, do(h, "1.1.1.1"))
+	assert.Equal(t, http.StatusNotFound, do(h, "2.2.2.2"))
+
+	now = now.Add(time.Hour + time.Second)
+	assert.Equal(t, http.StatusNotFound, do(h, "1.1.1.1"))
+}
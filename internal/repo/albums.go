@@ -0,0 +1,237 @@
+package repo
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/btschwartz12/site/internal/repo/db"
+)
+
+const maxTagLength = 32
+
+var (
+	albumSlugRe = regexp.MustCompile(`^[a-z0-9]+(-[a-z0-9]+)*$`)
+	tagRe       = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]*$`)
+)
+
+type Album struct {
+	ID          int64
+	Slug        string
+	Title       string
+	Description string
+	Pit         time.Time
+}
+
+func (a *Album) fromDb(row *db.Album) {
+	a.ID = row.ID
+	a.Slug = row.Slug
+	a.Title = row.Title
+	a.Description = row.Description
+	a.Pit = row.Pit
+}
+
+// NormalizeTag lowercases a tag and joins its words with dashes,
+// returning an error if what's left isn't a valid tag.
+func NormalizeTag(tag string) (string, error) {
+	tag = strings.Join(strings.Fields(strings.ToLower(tag)), "-")
+	if len(tag) > maxTagLength || !tagRe.MatchString(tag) {
+		return "", errorf(ErrInvalidInput, "invalid tag: %q", tag)
+	}
+	return tag, nil
+}
+
+func (r *Repo) CreateAlbum(ctx context.Context, slug string, title string, description string) (*Album, error) {
+	if !albumSlugRe.MatchString(slug) {
+		return nil, errorf(ErrInvalidInput, "invalid slug: %q", slug)
+	}
+	if title = strings.TrimSpace(title); title == "" {
+		return nil, errorf(ErrInvalidInput, "title is required")
+	}
+
+	q := db.New(r.db)
+	row, err := q.InsertAlbum(ctx, db.InsertAlbumParams{
+		Slug:        slug,
+		Title:       title,
+		Description: description,
+	})
+	if err != nil {
+		if isUniqueViolation(err) {
+			return nil, errorf(ErrAlreadyExists, "album already exists: %s", slug)
+		}
+		return nil, fmt.Errorf("error inserting album: %w", err)
+	}
+	a := Album{}
+	a.fromDb(&row)
+	return &a, nil
+}
+
+func (r *Repo) GetAlbum(ctx context.Context, slug string) (*Album, error) {
+	q := db.New(r.db)
+	row, err := q.GetAlbum(ctx, slug)
+	if err != nil {
+		return nil, fmt.Errorf("error getting album: %w", err)
+	}
+	a := Album{}
+	a.fromDb(&row)
+	return &a, nil
+}
+
+func (r *Repo) GetAllAlbums(ctx context.Context) ([]Album, error) {
+	q := db.New(r.db)
+	rows, err := q.GetAllAlbums(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("error getting albums: %w", err)
+	}
+	albums := make([]Album, len(rows))
+	for i, row := range rows {
+		albums[i].fromDb(&row)
+	}
+	return albums, nil
+}
+
+// DeleteAlbum deletes an album, leaving its pictures in the gallery.
+func (r *Repo) DeleteAlbum(ctx context.Context, slug string) error {
+	q := db.New(r.db)
+	n, err := q.DeleteAlbum(ctx, slug)
+	if err != nil {
+		return fmt.Errorf("error deleting album: %w", err)
+	}
+	if n == 0 {
+		return fmt.Errorf("error deleting album: %w", sql.ErrNoRows)
+	}
+	return nil
+}
+
+// AddPictureToAlbum puts a picture in an album, doing nothing if it's
+// already there.
+func (r *Repo) AddPictureToAlbum(ctx context.Context, slug string, idStr string) error {
+	q := db.New(r.db)
+	album, pictureID, err := albumAndPicture(ctx, q, slug, idStr)
+	if err != nil {
+		return err
+	}
+	err = q.AddAlbumPicture(ctx, db.AddAlbumPictureParams{AlbumID: album.ID, PictureID: pictureID})
+	if err != nil {
+		return fmt.Errorf("error adding picture to album: %w", err)
+	}
+	return nil
+}
+
+func (r *Repo) RemovePictureFromAlbum(ctx context.Context, slug string, idStr string) error {
+	q := db.New(r.db)
+	album, pictureID, err := albumAndPicture(ctx, q, slug, idStr)
+	if err != nil {
+		return err
+	}
+	n, err := q.RemoveAlbumPicture(ctx, db.RemoveAlbumPictureParams{AlbumID: album.ID, PictureID: pictureID})
+	if err != nil {
+		return fmt.Errorf("error removing picture from album: %w", err)
+	}
+	if n == 0 {
+		return fmt.Errorf("error removing picture from album: %w", sql.ErrNoRows)
+	}
+	return nil
+}
+
+func albumAndPicture(ctx context.Context, q *db.Queries, slug string, idStr string) (db.Album, int64, error) {
+	id, err := strconv.ParseInt(idStr, 10, 64)
+	if err != nil {
+		return db.Album{}, 0, fmt.Errorf("%w: %w", ErrInvalidID, err)
+	}
+	album, err := q.GetAlbum(ctx, slug)
+	if err != nil {
+		return db.Album{}, 0, fmt.Errorf("error getting album: %w", err)
+	}
+	if _, err := q.GetPicture(ctx, id); err != nil {
+		return db.Album{}, 0, fmt.Errorf("error getting picture: %w", err)
+	}
+	return album, id, nil
+}
+
+// GetPictureAlbums returns the albums a picture is in.
+func (r *Repo) GetPictureAlbums(ctx context.Context, pictureID int64) ([]Album, error) {
+	q := db.New(r.db)
+	rows, err := q.GetPictureAlbums(ctx, pictureID)
+	if err != nil {
+		return nil, fmt.Errorf("error getting picture albums: %w", err)
+	}
+	albums := make([]Album, len(rows))
+	for i, row := range rows {
+		albums[i].fromDb(&row)
+	}
+	return albums, nil
+}
+
+// TagPicture adds tags to a picture and returns all of its tags.
+func (r *Repo) TagPicture(ctx context.Context, idStr string, tags []string) ([]string, error) {
+	id, err := strconv.ParseInt(idStr, 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidID, err)
+	}
+	normalized := make([]string, len(tags))
+	for i, tag := range tags {
+		if normalized[i], err = NormalizeTag(tag); err != nil {
+			return nil, err
+		}
+	}
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("error beginning transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	q := db.New(tx)
+	if _, err := q.GetPicture(ctx, id); err != nil {
+		return nil, fmt.Errorf("error getting picture: %w", err)
+	}
+	for _, tag := range normalized {
+		if err := q.AddPictureTag(ctx, db.AddPictureTagParams{PictureID: id, Tag: tag}); err != nil {
+			return nil, fmt.Errorf("error tagging picture: %w", err)
+		}
+	}
+	all, err := q.GetPictureTags(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("error getting picture tags: %w", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("error committing transaction: %w", err)
+	}
+	return all, nil
+}
+
+// UntagPicture removes a tag from a picture and returns the tags it
+// has left.
+func (r *Repo) UntagPicture(ctx context.Context, idStr string, tag string) ([]string, error) {
+	id, err := strconv.ParseInt(idStr, 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidID, err)
+	}
+	if tag, err = NormalizeTag(tag); err != nil {
+		return nil, err
+	}
+
+	q := db.New(r.db)
+	n, err := q.RemovePictureTag(ctx, db.RemovePictureTagParams{PictureID: id, Tag: tag})
+	if err != nil {
+		return nil, fmt.Errorf("error untagging picture: %w", err)
+	}
+	if n == 0 {
+		return nil, fmt.Errorf("error untagging picture: %w", sql.ErrNoRows)
+	}
+	return r.GetPictureTags(ctx, id)
+}
+
+func (r *Repo) GetPictureTags(ctx context.Context, pictureID int64) ([]string, error) {
+	q := db.New(r.db)
+	tags, err := q.GetPictureTags(ctx, pictureID)
+	if err != nil {
+		return nil, fmt.Errorf("error getting picture tags: %w", err)
+	}
+	return tags, nil
+}
@@ -0,0 +1,78 @@
+package repo
+
+import (
+	"context"
+	"database/sql"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAlbumsAndTags(t *testing.T) {
+	ctx := context.Background()
+	r := newTestRepo(t)
+	r.autoApprove = newAutoApproveRules("", "1.2.3.4")
+
+	var ids []string
+	for i := 0; i < 4; i++ {
+		file, header := newTestUpload(t, "a.png", testPng(t, uint8(i)))
+		p, err := r.InsertPicture(ctx, file, header, "author", "desc", "1.2.3.4")
+		assert.NoError(t, err)
+		ids = append(ids, strconv.FormatInt(p.ID, 10))
+	}
+
+	_, err := r.CreateAlbum(ctx, "Not A Slug", "title", "")
+	assert.ErrorContains(t, err, "invalid slug")
+	album, err := r.CreateAlbum(ctx, "summer-2024", "Summer 2024", "")
+	assert.NoError(t, err)
+	_, err = r.CreateAlbum(ctx, "summer-2024", "again", "")
+	assert.ErrorIs(t, err, ErrAlreadyExists)
+
+	assert.NoError(t, r.AddPictureToAlbum(ctx, "summer-2024", ids[0]))
+	assert.NoError(t, r.AddPictureToAlbum(ctx, "summer-2024", ids[2]))
+	assert.NoError(t, r.AddPictureToAlbum(ctx, "summer-2024", ids[2]))
+	assert.ErrorIs(t, r.AddPictureToAlbum(ctx, "winter", ids[0]), sql.ErrNoRows)
+
+	tags, err := r.TagPicture(ctx, ids[2], []string{"Beach Day", "dogs"})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"beach-day", "dogs"}, tags)
+	_, err = r.TagPicture(ctx, ids[3], []string{"dogs"})
+	assert.NoError(t, err)
+	_, err = r.TagPicture(ctx, ids[3], []string{"#!"})
+	assert.ErrorContains(t, err, "invalid tag")
+
+	gallery := func(opts GalleryOptions) []string {
+		pictures, _, err := r.GetGalleryPictures(ctx, opts)
+		assert.NoError(t, err)
+		var got []string
+		for _, p := range pictures {
+			got = append(got, strconv.FormatInt(p.ID, 10))
+		}
+		return got
+	}
+	assert.Equal(t, []string{ids[2], ids[0]}, gallery(GalleryOptions{Order: OrderNewest, AlbumID: album.ID}))
+	assert.Equal(t, []string{ids[2], ids[3]}, gallery(GalleryOptions{Order: OrderOldest, Tag: "dogs"}))
+	assert.Equal(t, []string{ids[2]}, gallery(GalleryOptions{Order: OrderRandom, AlbumID: album.ID, Tag: "dogs"}))
+	assert.Len(t, gallery(GalleryOptions{Order: OrderLikes}), 4)
+
+	tags, err = r.UntagPicture(ctx, ids[2], "dogs")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"beach-day"}, tags)
+	_, err = r.UntagPicture(ctx, ids[2], "dogs")
+	assert.ErrorIs(t, err, sql.ErrNoRows)
+
+	assert.NoError(t, r.RemovePictureFromAlbum(ctx, "summer-2024", ids[0]))
+	assert.Equal(t, []string{ids[2]}, gallery(GalleryOptions{Order: OrderNewest, AlbumID: album.ID}))
+
+	// deleting an album leaves its pictures alone
+	pictureID, _ := strconv.ParseInt(ids[2], 10, 64)
+	albums, err := r.GetPictureAlbums(ctx, pictureID)
+	assert.NoError(t, err)
+	assert.Len(t, albums, 1)
+	assert.NoError(t, r.DeleteAlbum(ctx, "summer-2024"))
+	albums, err = r.GetPictureAlbums(ctx, pictureID)
+	assert.NoError(t, err)
+	assert.Empty(t, albums)
+	assert.Len(t, gallery(GalleryOptions{Order: OrderNewest}), 4)
+}
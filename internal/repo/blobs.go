@@ -0,0 +1,407 @@
+package repo
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/btschwartz12/site/internal/repo/db"
+	"github.com/btschwartz12/site/internal/storage"
+)
+
+const (
+	blobDir = "blobs"
+	// sniffLen is how much of a blob http.DetectContentType looks at.
+	sniffLen = 512
+)
+
+// blobSuffixes keep each stored copy of some content at its own key,
+// so deleting a copy whose last reference just went away can never
+// remove one stored again for a new upload.
+var blobSuffixes = alphabetIdGenerator(base62Alphabet, 64)
+
+// errBlobReleased means the blob found for some content lost its last
+// reference before it could be claimed.
+var errBlobReleased = errors.New("blob released")
+
+// Blob is stored content, shared by every file and picture with the
+// same SHA-256.
+type Blob struct {
+	Sha256      string
+	Size        int64
+	ContentType string
+	Refs        int64
+	Pit         time.Time
+}
+
+func (b *Blob) fromDb(row *db.Blob) {
+	b.Sha256 = row.Sha256
+	b.Size = row.Size
+	b.ContentType = row.ContentType
+	b.Refs = row.Refs
+	b.Pit = row.Pit
+}
+
+type blobMeta struct {
+	Key         string
+	ContentType string
+	Sha256      string
+	Size        int64
+}
+
+// GetBlob looks up stored content by its hex SHA-256, so clients can
+// skip uploading what is already here.
+func (r *Repo) GetBlob(ctx context.Context, sha256 string) (*Blob, error) {
+	q := db.New(r.db)
+	row, err := q.GetBlob(ctx, strings.ToLower(sha256))
+	if err != nil {
+		return nil, fmt.Errorf("error getting blob: %w", err)
+	}
+	b := &Blob{}
+	b.fromDb(&row)
+	return b, nil
+}
+
+// hashBlob reads all of src to find its size, hash and content type.
+func hashBlob(src io.Reader, name string) (*blobMeta, error) {
+	br := bufio.NewReaderSize(src, sniffLen)
+	head, err := br.Peek(sniffLen)
+	if err != nil && !errors.Is(err, io.EOF) && !errors.Is(err, bufio.ErrBufferFull) {
+		return nil, fmt.Errorf("error reading file: %w", err)
+	}
+	h := sha256.New()
+	n, err := io.Copy(h, br)
+	if err != nil {
+		return nil, fmt.Errorf("error reading file: %w", err)
+	}
+	return &blobMeta{
+		ContentType: sniffContentType(head, name),
+		Sha256:      hex.EncodeToString(h.Sum(nil)),
+		Size:        n,
+	}, nil
+}
+
+// rewind reopens src from the start, for storeBlob.
+func rewind(src io.ReadSeeker) func() (io.ReadCloser, error) {
+	return func() (io.ReadCloser, error) {
+		if _, err := src.Seek(0, io.SeekStart); err != nil {
+			return nil, fmt.Errorf("error rewinding file: %w", err)
+		}
+		return io.NopCloser(src), nil
+	}
+}
+
+func openBytes(data []byte) func() (io.ReadCloser, error) {
+	return func() (io.ReadCloser, error) {
+		return io.NopCloser(bytes.NewReader(data)), nil
+	}
+}
+
+// storeBlob takes a reference to the blob with meta's content,
+// storing the content from open only if it isn't stored already,
+// then runs insert in the same transaction. meta.Key is set to the
+// key of the blob for insert to use.
+func (r *Repo) storeBlob(
+	ctx context.Context,
+	meta *blobMeta,
+	open func() (io.ReadCloser, error),
+	insert func(q *db.Queries) error,
+) error {
+	q := db.New(r.db)
+	existing, err := q.GetBlob(ctx, meta.Sha256)
+	if err == nil {
+		meta.Key = existing.Key
+		err = r.claimBlob(ctx, meta, false, insert)
+		if !errors.Is(err, errBlobReleased) {
+			return err
+		}
+		// it was deleted since the lookup, so store it again
+	} else if !errors.Is(err, sql.ErrNoRows) {
+		return fmt.Errorf("error getting blob: %w", err)
+	}
+
+	suffix, err := blobSuffixes()
+	if err != nil {
+		return err
+	}
+	key := path.Join(blobDir, meta.Sha256[:2], meta.Sha256+"-"+suffix)
+	src, err := open()
+	if err != nil {
+		return err
+	}
+	err = r.blobs.Put(ctx, key, src, meta.Size)
+	src.Close()
+	if err != nil {
+		return fmt.Errorf("error storing file: %w", err)
+	}
+
+	meta.Key = key
+	err = r.claimBlob(ctx, meta, true, insert)
+	// a concurrent upload of the same content may have claimed the
+	// hash first, in which case the copy stored here is unused
+	if err != nil || meta.Key != key {
+		if err := r.blobs.Delete(ctx, key); err != nil {
+			r.logger.Errorw("error removing unused blob", "error", err, "key", key)
+		}
+	}
+	return err
+}
+
+func (r *Repo) claimBlob(ctx context.Context, meta *blobMeta, stored bool, insert func(q *db.Queries) error) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("error beginning transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	q := db.New(tx)
+	var row db.Blob
+	if stored {
+		row, err = q.InsertBlob(ctx, db.InsertBlobParams{
+			Sha256:      meta.Sha256,
+			Key:         meta.Key,
+			Size:        meta.Size,
+			ContentType: meta.ContentType,
+		})
+	} else {
+		row, err = q.AcquireBlob(ctx, meta.Sha256)
+		if errors.Is(err, sql.ErrNoRows) {
+			return errBlobReleased
+		}
+	}
+	if err != nil {
+		return fmt.Errorf("error referencing blob: %w", err)
+	}
+	meta.Key = row.Key
+
+	if err := insert(q); err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("error committing transaction: %w", err)
+	}
+	return nil
+}
+
+// releaseBlob drops a row's reference to its blob, returning the key
+// to delete once the transaction commits if it was the last one.
+func releaseBlob(ctx context.Context, q *db.Queries, sha256 string, key string) (string, error) {
+	row, err := q.ReleaseBlob(ctx, db.ReleaseBlobParams{Sha256: sha256, Key: key})
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			// stored before blobs were shared, so the row owns it
+			return key, nil
+		}
+		return "", fmt.Errorf("error releasing blob: %w", err)
+	}
+	if row.Refs > 0 {
+		return "", nil
+	}
+	if err := q.DeleteBlob(ctx, sha256); err != nil {
+		return "", fmt.Errorf("error deleting blob: %w", err)
+	}
+	return row.Key, nil
+}
+
+// deleteReleasedBlob deletes the blob releaseBlob returned, if any.
+func (r *Repo) deleteReleasedBlob(ctx context.Context, key string) error {
+	if key == "" {
+		return nil
+	}
+	err := r.blobs.Delete(ctx, key)
+	if err != nil && !errors.Is(err, storage.ErrNotFound) {
+		return fmt.Errorf("error removing file: %w", err)
+	}
+	return nil
+}
+
+// readBlobMeta computes the metadata of an already stored blob.
+func (r *Repo) readBlobMeta(ctx context.Context, key string, name string) (*blobMeta, error) {
+	rc, err := r.blobs.Get(ctx, key)
+	if err != nil {
+		return nil, fmt.Errorf("error opening blob: %w", err)
+	}
+	defer rc.Close()
+
+	br := bufio.NewReaderSize(rc, sniffLen)
+	head, err := br.Peek(sniffLen)
+	if err != nil && !errors.Is(err, io.EOF) && !errors.Is(err, bufio.ErrBufferFull) {
+		return nil, fmt.Errorf("error reading blob: %w", err)
+	}
+	h := sha256.New()
+	if _, err := io.Copy(h, br); err != nil {
+		return nil, fmt.Errorf("error reading blob: %w", err)
+	}
+	return &blobMeta{
+		ContentType: sniffContentType(head, name),
+		Sha256:      hex.EncodeToString(h.Sum(nil)),
+	}, nil
+}
+
+// sniffContentType trusts the content over the name, falling back
+// to the extension only when the content is unrecognized.
+func sniffContentType(head []byte, name string) string {
+	ct := http.DetectContentType(head)
+	if ct == "application/octet-stream" {
+		if byExt := mime.TypeByExtension(path.Ext(name)); byExt != "" {
+			return byExt
+		}
+	}
+	return ct
+}
+
+// backfillBlobMetadata fills in the hash and content type of rows
+// stored before they were recorded at upload time.
+func (r *Repo) backfillBlobMetadata(ctx context.Context) error {
+	q := db.New(r.db)
+
+	files, err := q.GetAllFiles(ctx)
+	if err != nil {
+		return fmt.Errorf("error getting files: %w", err)
+	}
+	for _, f := range files {
+		if f.Sha256 != "" {
+			continue
+		}
+		meta, err := r.readBlobMeta(ctx, f.Url, f.Url)
+		if err != nil {
+			r.logger.Warnw("error reading file metadata", "error", err, "uuid", f.Uuid)
+			continue
+		}
+		err = q.UpdateFileMetadata(ctx, db.UpdateFileMetadataParams{
+			ContentType: meta.ContentType,
+			Sha256:      meta.Sha256,
+			Uuid:        f.Uuid,
+		})
+		if err != nil {
+			return fmt.Errorf("error updating file metadata: %w", err)
+		}
+	}
+
+	pictures, err := q.GetAllPictures(ctx)
+	if err != nil {
+		return fmt.Errorf("error getting pictures: %w", err)
+	}
+	for _, p := range pictures {
+		if p.Sha256 != "" {
+			continue
+		}
+		meta, err := r.readBlobMeta(ctx, p.Url, p.Url)
+		if err != nil {
+			r.logger.Warnw("error reading picture metadata", "error", err, "id", p.ID)
+			continue
+		}
+		err = q.UpdatePictureMetadata(ctx, db.UpdatePictureMetadataParams{
+			ContentType: meta.ContentType,
+			Sha256:      meta.Sha256,
+			ID:          p.ID,
+		})
+		if err != nil {
+			return fmt.Errorf("error updating picture metadata: %w", err)
+		}
+	}
+	return nil
+}
+
+// adoptBlobs starts sharing the blobs of rows stored before blobs
+// were deduplicated, merging rows with the same content onto one.
+func (r *Repo) adoptBlobs(ctx context.Context) error {
+	q := db.New(r.db)
+	blobs, err := q.GetAllBlobs(ctx)
+	if err != nil {
+		return fmt.Errorf("error getting blobs: %w", err)
+	}
+	adopted := map[string]bool{}
+	for _, b := range blobs {
+		adopted[b.Key] = true
+	}
+
+	files, err := q.GetAllFiles(ctx)
+	if err != nil {
+		return fmt.Errorf("error getting files: %w", err)
+	}
+	for _, f := range files {
+		if f.Sha256 == "" || adopted[f.Url] {
+			continue
+		}
+		key, err := r.adoptBlob(ctx, db.InsertBlobParams{
+			Sha256:      f.Sha256,
+			Key:         f.Url,
+			Size:        f.Size,
+			ContentType: f.ContentType,
+		}, func(q *db.Queries, key string) error {
+			return q.UpdateFileUrl(ctx, db.UpdateFileUrlParams{Url: key, Uuid: f.Uuid})
+		})
+		if err != nil {
+			return err
+		}
+		adopted[key] = true
+	}
+
+	pictures, err := q.GetAllPictures(ctx)
+	if err != nil {
+		return fmt.Errorf("error getting pictures: %w", err)
+	}
+	for _, p := range pictures {
+		if p.Sha256 == "" || adopted[p.Url] {
+			continue
+		}
+		key, err := r.adoptBlob(ctx, db.InsertBlobParams{
+			Sha256:      p.Sha256,
+			Key:         p.Url,
+			Size:        p.Size,
+			ContentType: p.ContentType,
+		}, func(q *db.Queries, key string) error {
+			return q.UpdatePictureUrl(ctx, db.UpdatePictureUrlParams{Url: key, ID: p.ID})
+		})
+		if err != nil {
+			return err
+		}
+		adopted[key] = true
+	}
+	return nil
+}
+
+// adoptBlob references the blob at params.Key, or if the content is
+// already shared, points the row at that blob with relink and deletes
+// its own copy.
+func (r *Repo) adoptBlob(ctx context.Context, params db.InsertBlobParams, relink func(q *db.Queries, key string) error) (string, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return "", fmt.Errorf("error beginning transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	q := db.New(tx)
+	row, err := q.InsertBlob(ctx, params)
+	if err != nil {
+		return "", fmt.Errorf("error referencing blob: %w", err)
+	}
+	if row.Key != params.Key {
+		if err := relink(q, row.Key); err != nil {
+			return "", fmt.Errorf("error relinking duplicate: %w", err)
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return "", fmt.Errorf("error committing transaction: %w", err)
+	}
+
+	if row.Key != params.Key {
+		if err := r.deleteReleasedBlob(ctx, params.Key); err != nil {
+			return "", err
+		}
+		r.logger.Infow("merged duplicate blob", "key", params.Key, "into", row.Key)
+	}
+	return row.Key, nil
+}
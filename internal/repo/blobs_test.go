@@ -0,0 +1,108 @@
+package repo
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/btschwartz12/site/internal/repo/db"
+	"github.com/btschwartz12/site/internal/storage"
+)
+
+func TestBlobs_DuplicatesShareOneBlob(t *testing.T) {
+	ctx := context.Background()
+	r := newTestRepo(t)
+
+	content := testPng(t, 0)
+	file, header := newTestUpload(t, "a.png", content)
+	f1, err := r.InsertFile(ctx, file, header, "notes", "1.2.3.4", 0)
+	assert.NoError(t, err)
+	file, header = newTestUpload(t, "b.png", content)
+	f2, err := r.InsertFile(ctx, file, header, "notes", "1.2.3.4", 0)
+	assert.NoError(t, err)
+	file, header = newTestUpload(t, "c.png", content)
+	p, err := r.InsertPicture(ctx, file, header, "author", "desc", "1.2.3.4")
+	assert.NoError(t, err)
+
+	assert.Equal(t, f1.Url, f2.Url)
+	assert.Equal(t, f1.Url, p.Url)
+	stored, err := r.blobs.List(ctx, blobDir+"/")
+	assert.NoError(t, err)
+	assert.Len(t, stored, 1+len(p.Variants))
+
+	b, err := r.GetBlob(ctx, strings.ToUpper(f1.Sha256))
+	assert.NoError(t, err)
+	assert.Equal(t, int64(3), b.Refs)
+
+	// the blob outlives every reference but the last
+	assert.NoError(t, r.PurgeFile(ctx, f1.Uuid.String()))
+	assert.NoError(t, r.PurgePicture(ctx, strconv.FormatInt(p.ID, 10)))
+	_, err = r.blobs.Stat(ctx, f2.Url)
+	assert.NoError(t, err)
+
+	assert.NoError(t, r.PurgeFile(ctx, f2.Uuid.String()))
+	_, err = r.blobs.Stat(ctx, f2.Url)
+	assert.ErrorIs(t, err, storage.ErrNotFound)
+	_, err = r.GetBlob(ctx, f2.Sha256)
+	assert.ErrorIs(t, err, sql.ErrNoRows)
+}
+
+func TestBlobs_InsertFileFromBlob(t *testing.T) {
+	ctx := context.Background()
+	r := newTestRepo(t)
+
+	_, err := r.InsertFileFromBlob(ctx, strings.Repeat("0", 64), "a.txt", "notes", "1.2.3.4", 0)
+	assert.ErrorIs(t, err, sql.ErrNoRows)
+
+	file, header := newTestUpload(t, "a.txt", "hello")
+	f1, err := r.InsertFile(ctx, file, header, "notes", "1.2.3.4", 0)
+	assert.NoError(t, err)
+	f2, err := r.InsertFileFromBlob(ctx, f1.Sha256, "b.txt", "notes", "1.2.3.4", 0)
+	assert.NoError(t, err)
+	assert.Equal(t, "b.txt", f2.Name)
+	assert.Equal(t, f1.Url, f2.Url)
+	assert.Equal(t, f1.Size, f2.Size)
+	assert.Equal(t, 2*f1.Size, areaBytes(t, r, areaDrive))
+}
+
+func TestBlobs_AdoptMergesDuplicates(t *testing.T) {
+	ctx := context.Background()
+	r := newTestRepo(t)
+
+	// rows stored before blobs were shared each own a copy
+	content := "legacy"
+	sum := sha256.Sum256([]byte(content))
+	q := db.New(r.db)
+	var rows []db.File
+	for _, url := range []string{"drive/a.txt", "drive/b.txt"} {
+		assert.NoError(t, r.blobs.Put(ctx, url, strings.NewReader(content), int64(len(content))))
+		row, err := q.InsertFile(ctx, db.InsertFileParams{
+			Uuid:   url[len("drive/"):],
+			Url:    url,
+			Name:   url,
+			Size:   int64(len(content)),
+			Sha256: hex.EncodeToString(sum[:]),
+		})
+		assert.NoError(t, err)
+		rows = append(rows, row)
+	}
+
+	assert.NoError(t, r.adoptBlobs(ctx))
+	a, err := q.GetFile(ctx, rows[0].Uuid)
+	assert.NoError(t, err)
+	b, err := q.GetFile(ctx, rows[1].Uuid)
+	assert.NoError(t, err)
+	assert.Equal(t, a.Url, b.Url)
+	_, err = r.blobs.Stat(ctx, "drive/b.txt")
+	assert.ErrorIs(t, err, storage.ErrNotFound)
+
+	blob, err := r.GetBlob(ctx, a.Sha256)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(2), blob.Refs)
+}
@@ -0,0 +1,210 @@
+package repo
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	goaway "github.com/TwiN/go-away"
+
+	"github.com/btschwartz12/site/internal/repo/db"
+)
+
+const (
+	MaxCommentLength       = 1000
+	MaxCommentAuthorLength = 50
+)
+
+// Moderation states of a comment. Comments are shown as soon as they
+// are posted, until a moderator hides them.
+const (
+	CommentVisible = "visible"
+	CommentHidden  = "hidden"
+)
+
+type Comment struct {
+	ID        int64
+	PictureID int64
+	Author    string
+	Body      string
+	Pit       time.Time
+
+	// Status is CommentVisible or CommentHidden.
+	Status           string
+	ModerationReason string
+	ModeratedBy      string
+	ModeratedAt      *time.Time
+}
+
+func (c *Comment) fromDb(row *db.PictureComment) {
+	c.ID = row.ID
+	c.PictureID = row.PictureID
+	c.Author = row.Author
+	c.Body = row.Body
+	c.Pit = row.Pit
+	c.Status = row.Status
+	c.ModerationReason = row.ModerationReason
+	c.ModeratedBy = row.ModeratedBy
+	c.ModeratedAt = nullTimePtr(row.ModeratedAt)
+}
+
+// AddComment posts v's comment on a picture, censoring any profanity
+// in it the way survey answers are.
+func (r *Repo) AddComment(ctx context.Context, idStr string, v Voter, author string, body string) (*Comment, error) {
+	if v.ID == "" || v.IpHash == "" {
+		return nil, errorf(ErrInvalidInput, "invalid commenter")
+	}
+	author = strings.TrimSpace(author)
+	body = strings.TrimSpace(body)
+	if author == "" || body == "" {
+		return nil, errorf(ErrInvalidInput, "empty comment")
+	}
+	if utf8.RuneCountInString(author) > MaxCommentAuthorLength {
+		return nil, errorf(ErrInvalidInput, "comment too long: author is over %d characters", MaxCommentAuthorLength)
+	}
+	if utf8.RuneCountInString(body) > MaxCommentLength {
+		return nil, errorf(ErrInvalidInput, "comment too long: over %d characters", MaxCommentLength)
+	}
+	id, err := strconv.ParseInt(idStr, 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidID, err)
+	}
+
+	q := db.New(r.db)
+	picture, err := q.GetPicture(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("error getting picture: %w", err)
+	}
+	if picture.Status != PictureApproved {
+		// pictures outside the gallery can't be commented on
+		return nil, fmt.Errorf("error getting picture: %w", sql.ErrNoRows)
+	}
+
+	row, err := q.InsertPictureComment(ctx, db.InsertPictureCommentParams{
+		PictureID: id,
+		Author:    goaway.Censor(author),
+		Body:      goaway.Censor(body),
+		Voter:     v.ID,
+		IpHash:    v.IpHash,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error inserting comment: %w", err)
+	}
+	c := Comment{}
+	c.fromDb(&row)
+	return &c, nil
+}
+
+// GetPictureComments returns the visible comments on a picture,
+// oldest first.
+func (r *Repo) GetPictureComments(ctx context.Context, pictureID int64) ([]Comment, error) {
+	q := db.New(r.db)
+	rows, err := q.GetPictureComments(ctx, pictureID)
+	if err != nil {
+		return nil, fmt.Errorf("error getting comments: %w", err)
+	}
+	comments := make([]Comment, len(rows))
+	for i, row := range rows {
+		comments[i].fromDb(&row)
+	}
+	return comments, nil
+}
+
+// CommentFilter selects the comments to list.
+type CommentFilter struct {
+	ListOptions
+	// PictureID matches the comments on one picture.
+	PictureID int64
+	// Status matches comments in one moderation state.
+	Status string
+}
+
+// ListComments returns a page of comments matching f, whatever their
+// status, and the cursor for the next page, which is empty on the
+// last one.
+func (r *Repo) ListComments(ctx context.Context, f CommentFilter) ([]Comment, string, error) {
+	b, err := f.bounds()
+	if err != nil {
+		return nil, "", err
+	}
+	cursorID, err := b.cursorID()
+	if err != nil {
+		return nil, "", err
+	}
+	params := db.ListPictureCommentsDescParams{
+		CursorPit: b.cursorPit,
+		CursorID:  cursorID,
+		Since:     b.since,
+		Until:     b.until,
+		PictureID: sql.NullInt64{Int64: f.PictureID, Valid: f.PictureID != 0},
+		Status:    nullIfEmpty(f.Status),
+		Limit:     b.limit,
+	}
+
+	q := db.New(r.db)
+	var rows []db.PictureComment
+	if f.Ascending {
+		rows, err = q.ListPictureCommentsAsc(ctx, db.ListPictureCommentsAscParams(params))
+	} else {
+		rows, err = q.ListPictureCommentsDesc(ctx, params)
+	}
+	if err != nil {
+		return nil, "", fmt.Errorf("error listing comments: %w", err)
+	}
+	rows, next := page(rows, b, func(c db.PictureComment) (time.Time, string) {
+		return c.Pit, strconv.FormatInt(c.ID, 10)
+	})
+	comments := make([]Comment, len(rows))
+	for i, row := range rows {
+		comments[i].fromDb(&row)
+	}
+	return comments, next, nil
+}
+
+// ModerateComment hides a comment or shows it again, recording who
+// did it and why.
+func (r *Repo) ModerateComment(ctx context.Context, idStr string, status string, reason string, moderator string) (*Comment, error) {
+	if status != CommentVisible && status != CommentHidden {
+		return nil, errorf(ErrInvalidInput, "invalid status: %s", status)
+	}
+	id, err := strconv.ParseInt(idStr, 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidID, err)
+	}
+
+	q := db.New(r.db)
+	row, err := q.ModeratePictureComment(ctx, db.ModeratePictureCommentParams{
+		Status:           status,
+		ModerationReason: reason,
+		ModeratedBy:      moderator,
+		ModeratedAt:      sql.NullTime{Time: time.Now().UTC(), Valid: true},
+		ID:               id,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error moderating comment: %w", err)
+	}
+	c := Comment{}
+	c.fromDb(&row)
+	r.logger.Infow("moderated comment", "id", id, "status", status, "moderator", moderator)
+	return &c, nil
+}
+
+func (r *Repo) DeleteComment(ctx context.Context, idStr string) error {
+	id, err := strconv.ParseInt(idStr, 10, 64)
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrInvalidID, err)
+	}
+	q := db.New(r.db)
+	n, err := q.DeletePictureComment(ctx, id)
+	if err != nil {
+		return fmt.Errorf("error deleting comment: %w", err)
+	}
+	if n == 0 {
+		return fmt.Errorf("error deleting comment: %w", sql.ErrNoRows)
+	}
+	return nil
+}
@@ -0,0 +1,61 @@
+package repo
+
+import (
+	"context"
+	"database/sql"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAddComment(t *testing.T) {
+	ctx := context.Background()
+	r := newTestRepo(t)
+	r.autoApprove = newAutoApproveRules("", "1.2.3.4")
+
+	file, header := newTestUpload(t, "a.png", testPng(t, 0))
+	p, err := r.InsertPicture(ctx, file, header, "author", "desc", "1.2.3.4")
+	assert.NoError(t, err)
+	id := strconv.FormatInt(p.ID, 10)
+	v := Voter{ID: "a", IpHash: "a"}
+
+	c, err := r.AddComment(ctx, id, v, " ben ", "what the fuck")
+	assert.NoError(t, err)
+	assert.Equal(t, "ben", c.Author)
+	assert.Equal(t, "what the ****", c.Body)
+	assert.Equal(t, CommentVisible, c.Status)
+	second, err := r.AddComment(ctx, id, v, "ben", "nice")
+	assert.NoError(t, err)
+
+	_, err = r.AddComment(ctx, id, v, "ben", " ")
+	assert.ErrorContains(t, err, "empty comment")
+	_, err = r.AddComment(ctx, id, v, "ben", strings.Repeat("a", MaxCommentLength+1))
+	assert.ErrorContains(t, err, "comment too long")
+	_, err = r.AddComment(ctx, "999", v, "ben", "hi")
+	assert.ErrorIs(t, err, sql.ErrNoRows)
+
+	// hidden comments drop off the picture but can still be listed
+	_, err = r.ModerateComment(ctx, strconv.FormatInt(c.ID, 10), CommentHidden, "rude", "api:admin")
+	assert.NoError(t, err)
+	comments, err := r.GetPictureComments(ctx, p.ID)
+	assert.NoError(t, err)
+	if assert.Len(t, comments, 1) {
+		assert.Equal(t, second.ID, comments[0].ID)
+	}
+	hidden, _, err := r.ListComments(ctx, CommentFilter{PictureID: p.ID, Status: CommentHidden})
+	assert.NoError(t, err)
+	if assert.Len(t, hidden, 1) {
+		assert.Equal(t, "rude", hidden[0].ModerationReason)
+	}
+
+	assert.NoError(t, r.DeleteComment(ctx, strconv.FormatInt(second.ID, 10)))
+	assert.ErrorIs(t, r.DeleteComment(ctx, strconv.FormatInt(second.ID, 10)), sql.ErrNoRows)
+
+	// comments go with their picture
+	assert.NoError(t, r.PurgePicture(ctx, id))
+	all, _, err := r.ListComments(ctx, CommentFilter{})
+	assert.NoError(t, err)
+	assert.Empty(t, all)
+}
@@ -0,0 +1,41 @@
+package repo
+
+import (
+	"fmt"
+	"time"
+
+	env "github.com/Netflix/go-env"
+)
+
+type config struct {
+	// PermalinkIdScheme is one of "base62", "hex" or "words".
+	PermalinkIdScheme string `env:"PERMALINK_ID_SCHEME,default=base62"`
+	// PermalinkIdBits is how much randomness each permalink id has.
+	PermalinkIdBits int `env:"PERMALINK_ID_BITS,default=128"`
+	// MaxUploadMb caps resumable uploads through api tokens without
+	// a limit of their own.
+	MaxUploadMb int64 `env:"MAX_UPLOAD_MB,default=1000"`
+	// AnonymousMaxUploadMb caps resumable uploads from callers that
+	// aren't authenticated, like the drive page.
+	AnonymousMaxUploadMb int64 `env:"ANONYMOUS_MAX_UPLOAD_MB,default=5"`
+	// MaxOpenUploads caps how many resumable uploads each uploader can
+	// have open at once.
+	MaxOpenUploads int64 `env:"MAX_OPEN_UPLOADS,default=5"`
+	// PicsAutoApproveAuthors and PicsAutoApproveUploaders list, comma
+	// separated, the authors and uploaders whose pictures skip the
+	// moderation queue. Uploaders are IPs, or api:<token name> for
+	// uploads through the api.
+	PicsAutoApproveAuthors   string `env:"PICS_AUTO_APPROVE_AUTHORS"`
+	PicsAutoApproveUploaders string `env:"PICS_AUTO_APPROVE_UPLOADERS"`
+	// TrashRetention is how long deleted pictures and files stay in
+	// the trash before the janitor purges them.
+	TrashRetention time.Duration `env:"TRASH_RETENTION,default=720h"`
+}
+
+func newConfig() (*config, error) {
+	conf := config{}
+	if _, err := env.UnmarshalFromEnviron(&conf); err != nil {
+		return nil, fmt.Errorf("failed to load config: %w", err)
+	}
+	return &conf, nil
+}
@@ -7,12 +7,73 @@ package db
 
 import (
 	"context"
+	"database/sql"
 	"time"
 )
 
+const deleteExpiredPermalinks = `-- name: DeleteExpiredPermalinks :many
+DELETE FROM
+    permalinks
+WHERE
+    expires <= ?
+RETURNING
+    uuid
+`
+
+func (q *Queries) DeleteExpiredPermalinks(ctx context.Context, expires time.Time) ([]string, error) {
+	rows, err := q.db.QueryContext(ctx, deleteExpiredPermalinks, expires)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []string
+	for rows.Next() {
+		var uuid string
+		if err := rows.Scan(&uuid); err != nil {
+			return nil, err
+		}
+		items = append(items, uuid)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const deleteFile = `-- name: DeleteFile :one
+DELETE FROM
+    files
+WHERE
+    uuid = ?
+RETURNING
+    url,
+    size,
+    sha256
+`
+
+type DeleteFileRow struct {
+	Url    string
+	Size   int64
+	Sha256 string
+}
+
+func (q *Queries) DeleteFile(ctx context.Context, uuid string) (DeleteFileRow, error) {
+	row := q.db.QueryRowContext(ctx, deleteFile, uuid)
+	var i DeleteFileRow
+	err := row.Scan(
+		&i.Url,
+		&i.Size,
+		&i.Sha256,
+	)
+	return i, err
+}
+
 const getAllFiles = `-- name: GetAllFiles :many
 SELECT
-    uuid, url, notes, extension, pit
+    uuid, url, notes, extension, pit, size, uploader, name, expires, content_type, sha256, deleted_at
 FROM
     files
 `
@@ -32,6 +93,13 @@ func (q *Queries) GetAllFiles(ctx context.Context) ([]File, error) {
 			&i.Notes,
 			&i.Extension,
 			&i.Pit,
+			&i.Size,
+			&i.Uploader,
+			&i.Name,
+			&i.Expires,
+			&i.ContentType,
+			&i.Sha256,
+			&i.DeletedAt,
 		); err != nil {
 			return nil, err
 		}
@@ -48,7 +116,7 @@ func (q *Queries) GetAllFiles(ctx context.Context) ([]File, error) {
 
 const getAllPermalinks = `-- name: GetAllPermalinks :many
 SELECT
-    uuid, file_uuid, duration_seconds, expires, pit
+    uuid, file_uuid, duration_seconds, expires, pit, max_downloads, downloads, password_hash, revoked
 FROM
     permalinks
 `
@@ -68,6 +136,10 @@ func (q *Queries) GetAllPermalinks(ctx context.Context) ([]Permalink, error) {
 			&i.DurationSeconds,
 			&i.Expires,
 			&i.Pit,
+			&i.MaxDownloads,
+			&i.Downloads,
+			&i.PasswordHash,
+			&i.Revoked,
 		); err != nil {
 			return nil, err
 		}
@@ -82,13 +154,92 @@ func (q *Queries) GetAllPermalinks(ctx context.Context) ([]Permalink, error) {
 	return items, nil
 }
 
+const getExpiredFiles = `-- name: GetExpiredFiles :many
+SELECT
+    uuid, url, notes, extension, pit, size, uploader, name, expires, content_type, sha256, deleted_at
+FROM
+    files
+WHERE
+    expires IS NOT NULL
+    AND expires <= ?
+`
+
+func (q *Queries) GetExpiredFiles(ctx context.Context, expires sql.NullTime) ([]File, error) {
+	rows, err := q.db.QueryContext(ctx, getExpiredFiles, expires)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []File
+	for rows.Next() {
+		var i File
+		if err := rows.Scan(
+			&i.Uuid,
+			&i.Url,
+			&i.Notes,
+			&i.Extension,
+			&i.Pit,
+			&i.Size,
+			&i.Uploader,
+			&i.Name,
+			&i.Expires,
+			&i.ContentType,
+			&i.Sha256,
+			&i.DeletedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getExpiredPermalinks = `-- name: GetExpiredPermalinks :many
+SELECT
+    uuid
+FROM
+    permalinks
+WHERE
+    expires <= ?
+`
+
+func (q *Queries) GetExpiredPermalinks(ctx context.Context, expires time.Time) ([]string, error) {
+	rows, err := q.db.QueryContext(ctx, getExpiredPermalinks, expires)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []string
+	for rows.Next() {
+		var uuid string
+		if err := rows.Scan(&uuid); err != nil {
+			return nil, err
+		}
+		items = append(items, uuid)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const getFile = `-- name: GetFile :one
 SELECT
-    uuid, url, notes, extension, pit
+    uuid, url, notes, extension, pit, size, uploader, name, expires, content_type, sha256, deleted_at
 FROM
     files
 WHERE
     uuid = ?
+    AND deleted_at IS NULL
 `
 
 func (q *Queries) GetFile(ctx context.Context, uuid string) (File, error) {
@@ -100,13 +251,49 @@ func (q *Queries) GetFile(ctx context.Context, uuid string) (File, error) {
 		&i.Notes,
 		&i.Extension,
 		&i.Pit,
+		&i.Size,
+		&i.Uploader,
+		&i.Name,
+		&i.Expires,
+		&i.ContentType,
+		&i.Sha256,
+		&i.DeletedAt,
+	)
+	return i, err
+}
+
+const getFileIncludingTrashed = `-- name: GetFileIncludingTrashed :one
+SELECT
+    uuid, url, notes, extension, pit, size, uploader, name, expires, content_type, sha256, deleted_at
+FROM
+    files
+WHERE
+    uuid = ?
+`
+
+func (q *Queries) GetFileIncludingTrashed(ctx context.Context, uuid string) (File, error) {
+	row := q.db.QueryRowContext(ctx, getFileIncludingTrashed, uuid)
+	var i File
+	err := row.Scan(
+		&i.Uuid,
+		&i.Url,
+		&i.Notes,
+		&i.Extension,
+		&i.Pit,
+		&i.Size,
+		&i.Uploader,
+		&i.Name,
+		&i.Expires,
+		&i.ContentType,
+		&i.Sha256,
+		&i.DeletedAt,
 	)
 	return i, err
 }
 
 const getPermalink = `-- name: GetPermalink :one
 SELECT
-    uuid, file_uuid, duration_seconds, expires, pit
+    uuid, file_uuid, duration_seconds, expires, pit, max_downloads, downloads, password_hash, revoked
 FROM
     permalinks
 WHERE
@@ -122,32 +309,154 @@ func (q *Queries) GetPermalink(ctx context.Context, uuid string) (Permalink, err
 		&i.DurationSeconds,
 		&i.Expires,
 		&i.Pit,
+		&i.MaxDownloads,
+		&i.Downloads,
+		&i.PasswordHash,
+		&i.Revoked,
 	)
 	return i, err
 }
 
+const getPermalinkAccesses = `-- name: GetPermalinkAccesses :many
+SELECT
+    id, permalink_uuid, ip, user_agent, outcome, pit
+FROM
+    permalink_accesses
+WHERE
+    permalink_uuid = ?
+ORDER BY
+    pit DESC,
+    id DESC
+`
+
+func (q *Queries) GetPermalinkAccesses(ctx context.Context, permalinkUuid string) ([]PermalinkAccess, error) {
+	rows, err := q.db.QueryContext(ctx, getPermalinkAccesses, permalinkUuid)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []PermalinkAccess
+	for rows.Next() {
+		var i PermalinkAccess
+		if err := rows.Scan(
+			&i.ID,
+			&i.PermalinkUuid,
+			&i.Ip,
+			&i.UserAgent,
+			&i.Outcome,
+			&i.Pit,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getTrashedFilesBefore = `-- name: GetTrashedFilesBefore :many
+SELECT
+    uuid
+FROM
+    files
+WHERE
+    deleted_at < ?
+`
+
+func (q *Queries) GetTrashedFilesBefore(ctx context.Context, deletedAt string) ([]string, error) {
+	rows, err := q.db.QueryContext(ctx, getTrashedFilesBefore, deletedAt)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []string
+	for rows.Next() {
+		var uuid string
+		if err := rows.Scan(&uuid); err != nil {
+			return nil, err
+		}
+		items = append(items, uuid)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const incrementPermalinkDownloads = `-- name: IncrementPermalinkDownloads :execrows
+UPDATE
+    permalinks
+SET
+    downloads = downloads + 1
+WHERE
+    uuid = ?
+    AND (
+        max_downloads IS NULL
+        OR downloads < max_downloads
+    )
+`
+
+func (q *Queries) IncrementPermalinkDownloads(ctx context.Context, uuid string) (int64, error) {
+	result, err := q.db.ExecContext(ctx, incrementPermalinkDownloads, uuid)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
 const insertFile = `-- name: InsertFile :one
 INSERT INTO
-    files (uuid, url, notes, extension)
+    files (
+        uuid,
+        url,
+        name,
+        notes,
+        extension,
+        size,
+        uploader,
+        expires,
+        content_type,
+        sha256
+    )
 VALUES
-    (?, ?, ?, ?)
+    (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
 RETURNING
-    uuid, url, notes, extension, pit
+    uuid, url, notes, extension, pit, size, uploader, name, expires, content_type, sha256, deleted_at
 `
 
 type InsertFileParams struct {
-	Uuid      string
-	Url       string
-	Notes     string
-	Extension string
+	Uuid        string
+	Url         string
+	Name        string
+	Notes       string
+	Extension   string
+	Size        int64
+	Uploader    string
+	Expires     sql.NullTime
+	ContentType string
+	Sha256      string
 }
 
 func (q *Queries) InsertFile(ctx context.Context, arg InsertFileParams) (File, error) {
 	row := q.db.QueryRowContext(ctx, insertFile,
 		arg.Uuid,
 		arg.Url,
+		arg.Name,
 		arg.Notes,
 		arg.Extension,
+		arg.Size,
+		arg.Uploader,
+		arg.Expires,
+		arg.ContentType,
+		arg.Sha256,
 	)
 	var i File
 	err := row.Scan(
@@ -156,17 +465,31 @@ func (q *Queries) InsertFile(ctx context.Context, arg InsertFileParams) (File, e
 		&i.Notes,
 		&i.Extension,
 		&i.Pit,
+		&i.Size,
+		&i.Uploader,
+		&i.Name,
+		&i.Expires,
+		&i.ContentType,
+		&i.Sha256,
+		&i.DeletedAt,
 	)
 	return i, err
 }
 
 const insertPermalink = `-- name: InsertPermalink :one
 INSERT INTO
-    permalinks (uuid, file_uuid, duration_seconds, expires)
+    permalinks (
+        uuid,
+        file_uuid,
+        duration_seconds,
+        expires,
+        max_downloads,
+        password_hash
+    )
 VALUES
-    (?, ?, ?, ?)
+    (?, ?, ?, ?, ?, ?)
 RETURNING
-    uuid, file_uuid, duration_seconds, expires, pit
+    uuid, file_uuid, duration_seconds, expires, pit, max_downloads, downloads, password_hash, revoked
 `
 
 type InsertPermalinkParams struct {
@@ -174,6 +497,8 @@ type InsertPermalinkParams struct {
 	FileUuid        string
 	DurationSeconds int64
 	Expires         time.Time
+	MaxDownloads    sql.NullInt64
+	PasswordHash    sql.NullString
 }
 
 func (q *Queries) InsertPermalink(ctx context.Context, arg InsertPermalinkParams) (Permalink, error) {
@@ -182,7 +507,386 @@ func (q *Queries) InsertPermalink(ctx context.Context, arg InsertPermalinkParams
 		arg.FileUuid,
 		arg.DurationSeconds,
 		arg.Expires,
+		arg.MaxDownloads,
+		arg.PasswordHash,
+	)
+	var i Permalink
+	err := row.Scan(
+		&i.Uuid,
+		&i.FileUuid,
+		&i.DurationSeconds,
+		&i.Expires,
+		&i.Pit,
+		&i.MaxDownloads,
+		&i.Downloads,
+		&i.PasswordHash,
+		&i.Revoked,
+	)
+	return i, err
+}
+
+const insertPermalinkAccess = `-- name: InsertPermalinkAccess :exec
+INSERT INTO
+    permalink_accesses (permalink_uuid, ip, user_agent, outcome)
+VALUES
+    (?, ?, ?, ?)
+`
+
+type InsertPermalinkAccessParams struct {
+	PermalinkUuid string
+	Ip            string
+	UserAgent     string
+	Outcome       string
+}
+
+func (q *Queries) InsertPermalinkAccess(ctx context.Context, arg InsertPermalinkAccessParams) error {
+	_, err := q.db.ExecContext(ctx, insertPermalinkAccess,
+		arg.PermalinkUuid,
+		arg.Ip,
+		arg.UserAgent,
+		arg.Outcome,
+	)
+	return err
+}
+
+const listFilesAsc = `-- name: ListFilesAsc :many
+SELECT
+    uuid, url, notes, extension, pit, size, uploader, name, expires, content_type, sha256, deleted_at
+FROM
+    files
+WHERE
+    (pit, uuid) > (?, ?)
+    AND pit >= ?
+    AND pit < ?
+    AND extension = COALESCE(?, extension)
+    AND (deleted_at IS NOT NULL) = ?
+ORDER BY
+    pit,
+    uuid
+LIMIT
+    ?
+`
+
+type ListFilesAscParams struct {
+	CursorPit  string
+	CursorUuid string
+	Since      string
+	Until      string
+	Extension  sql.NullString
+	Deleted    bool
+	Limit      int64
+}
+
+func (q *Queries) ListFilesAsc(ctx context.Context, arg ListFilesAscParams) ([]File, error) {
+	rows, err := q.db.QueryContext(ctx, listFilesAsc,
+		arg.CursorPit,
+		arg.CursorUuid,
+		arg.Since,
+		arg.Until,
+		arg.Extension,
+		arg.Deleted,
+		arg.Limit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []File
+	for rows.Next() {
+		var i File
+		if err := rows.Scan(
+			&i.Uuid,
+			&i.Url,
+			&i.Notes,
+			&i.Extension,
+			&i.Pit,
+			&i.Size,
+			&i.Uploader,
+			&i.Name,
+			&i.Expires,
+			&i.ContentType,
+			&i.Sha256,
+			&i.DeletedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listFilesDesc = `-- name: ListFilesDesc :many
+SELECT
+    uuid, url, notes, extension, pit, size, uploader, name, expires, content_type, sha256, deleted_at
+FROM
+    files
+WHERE
+    (pit, uuid) < (?, ?)
+    AND pit >= ?
+    AND pit < ?
+    AND extension = COALESCE(?, extension)
+    AND (deleted_at IS NOT NULL) = ?
+ORDER BY
+    pit DESC,
+    uuid DESC
+LIMIT
+    ?
+`
+
+type ListFilesDescParams struct {
+	CursorPit  string
+	CursorUuid string
+	Since      string
+	Until      string
+	Extension  sql.NullString
+	Deleted    bool
+	Limit      int64
+}
+
+func (q *Queries) ListFilesDesc(ctx context.Context, arg ListFilesDescParams) ([]File, error) {
+	rows, err := q.db.QueryContext(ctx, listFilesDesc,
+		arg.CursorPit,
+		arg.CursorUuid,
+		arg.Since,
+		arg.Until,
+		arg.Extension,
+		arg.Deleted,
+		arg.Limit,
 	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []File
+	for rows.Next() {
+		var i File
+		if err := rows.Scan(
+			&i.Uuid,
+			&i.Url,
+			&i.Notes,
+			&i.Extension,
+			&i.Pit,
+			&i.Size,
+			&i.Uploader,
+			&i.Name,
+			&i.Expires,
+			&i.ContentType,
+			&i.Sha256,
+			&i.DeletedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listPermalinksAsc = `-- name: ListPermalinksAsc :many
+SELECT
+    uuid, file_uuid, duration_seconds, expires, pit, max_downloads, downloads, password_hash, revoked
+FROM
+    permalinks
+WHERE
+    (pit, uuid) > (?, ?)
+    AND pit >= ?
+    AND pit < ?
+    AND file_uuid = COALESCE(?, file_uuid)
+    AND file_uuid IN (
+        SELECT
+            uuid
+        FROM
+            files
+        WHERE
+            deleted_at IS NULL
+    )
+ORDER BY
+    pit,
+    uuid
+LIMIT
+    ?
+`
+
+type ListPermalinksAscParams struct {
+	CursorPit  string
+	CursorUuid string
+	Since      string
+	Until      string
+	FileUuid   sql.NullString
+	Limit      int64
+}
+
+func (q *Queries) ListPermalinksAsc(ctx context.Context, arg ListPermalinksAscParams) ([]Permalink, error) {
+	rows, err := q.db.QueryContext(ctx, listPermalinksAsc,
+		arg.CursorPit,
+		arg.CursorUuid,
+		arg.Since,
+		arg.Until,
+		arg.FileUuid,
+		arg.Limit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Permalink
+	for rows.Next() {
+		var i Permalink
+		if err := rows.Scan(
+			&i.Uuid,
+			&i.FileUuid,
+			&i.DurationSeconds,
+			&i.Expires,
+			&i.Pit,
+			&i.MaxDownloads,
+			&i.Downloads,
+			&i.PasswordHash,
+			&i.Revoked,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listPermalinksDesc = `-- name: ListPermalinksDesc :many
+SELECT
+    uuid, file_uuid, duration_seconds, expires, pit, max_downloads, downloads, password_hash, revoked
+FROM
+    permalinks
+WHERE
+    (pit, uuid) < (?, ?)
+    AND pit >= ?
+    AND pit < ?
+    AND file_uuid = COALESCE(?, file_uuid)
+    AND file_uuid IN (
+        SELECT
+            uuid
+        FROM
+            files
+        WHERE
+            deleted_at IS NULL
+    )
+ORDER BY
+    pit DESC,
+    uuid DESC
+LIMIT
+    ?
+`
+
+type ListPermalinksDescParams struct {
+	CursorPit  string
+	CursorUuid string
+	Since      string
+	Until      string
+	FileUuid   sql.NullString
+	Limit      int64
+}
+
+func (q *Queries) ListPermalinksDesc(ctx context.Context, arg ListPermalinksDescParams) ([]Permalink, error) {
+	rows, err := q.db.QueryContext(ctx, listPermalinksDesc,
+		arg.CursorPit,
+		arg.CursorUuid,
+		arg.Since,
+		arg.Until,
+		arg.FileUuid,
+		arg.Limit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Permalink
+	for rows.Next() {
+		var i Permalink
+		if err := rows.Scan(
+			&i.Uuid,
+			&i.FileUuid,
+			&i.DurationSeconds,
+			&i.Expires,
+			&i.Pit,
+			&i.MaxDownloads,
+			&i.Downloads,
+			&i.PasswordHash,
+			&i.Revoked,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const restoreFile = `-- name: RestoreFile :one
+UPDATE
+    files
+SET
+    deleted_at = NULL
+WHERE
+    uuid = ?
+    AND deleted_at IS NOT NULL
+RETURNING
+    uuid, url, notes, extension, pit, size, uploader, name, expires, content_type, sha256, deleted_at
+`
+
+func (q *Queries) RestoreFile(ctx context.Context, uuid string) (File, error) {
+	row := q.db.QueryRowContext(ctx, restoreFile, uuid)
+	var i File
+	err := row.Scan(
+		&i.Uuid,
+		&i.Url,
+		&i.Notes,
+		&i.Extension,
+		&i.Pit,
+		&i.Size,
+		&i.Uploader,
+		&i.Name,
+		&i.Expires,
+		&i.ContentType,
+		&i.Sha256,
+		&i.DeletedAt,
+	)
+	return i, err
+}
+
+const revokePermalink = `-- name: RevokePermalink :one
+UPDATE
+    permalinks
+SET
+    revoked = CURRENT_TIMESTAMP
+WHERE
+    uuid = ?
+    AND revoked IS NULL
+RETURNING
+    uuid, file_uuid, duration_seconds, expires, pit, max_downloads, downloads, password_hash, revoked
+`
+
+func (q *Queries) RevokePermalink(ctx context.Context, uuid string) (Permalink, error) {
+	row := q.db.QueryRowContext(ctx, revokePermalink, uuid)
 	var i Permalink
 	err := row.Scan(
 		&i.Uuid,
@@ -190,6 +894,144 @@ func (q *Queries) InsertPermalink(ctx context.Context, arg InsertPermalinkParams
 		&i.DurationSeconds,
 		&i.Expires,
 		&i.Pit,
+		&i.MaxDownloads,
+		&i.Downloads,
+		&i.PasswordHash,
+		&i.Revoked,
 	)
 	return i, err
 }
+
+const trashFile = `-- name: TrashFile :execrows
+UPDATE
+    files
+SET
+    deleted_at = CURRENT_TIMESTAMP
+WHERE
+    uuid = ?
+    AND deleted_at IS NULL
+`
+
+func (q *Queries) TrashFile(ctx context.Context, uuid string) (int64, error) {
+	result, err := q.db.ExecContext(ctx, trashFile, uuid)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const updateFileExpires = `-- name: UpdateFileExpires :exec
+UPDATE
+    files
+SET
+    expires = ?
+WHERE
+    uuid = ?
+`
+
+type UpdateFileExpiresParams struct {
+	Expires sql.NullTime
+	Uuid    string
+}
+
+func (q *Queries) UpdateFileExpires(ctx context.Context, arg UpdateFileExpiresParams) error {
+	_, err := q.db.ExecContext(ctx, updateFileExpires, arg.Expires, arg.Uuid)
+	return err
+}
+
+const updateFileMetadata = `-- name: UpdateFileMetadata :exec
+UPDATE
+    files
+SET
+    content_type = ?,
+    sha256 = ?
+WHERE
+    uuid = ?
+`
+
+type UpdateFileMetadataParams struct {
+	ContentType string
+	Sha256      string
+	Uuid        string
+}
+
+func (q *Queries) UpdateFileMetadata(ctx context.Context, arg UpdateFileMetadataParams) error {
+	_, err := q.db.ExecContext(ctx, updateFileMetadata, arg.ContentType, arg.Sha256, arg.Uuid)
+	return err
+}
+
+const updateFileName = `-- name: UpdateFileName :exec
+UPDATE
+    files
+SET
+    name = ?
+WHERE
+    uuid = ?
+`
+
+type UpdateFileNameParams struct {
+	Name string
+	Uuid string
+}
+
+func (q *Queries) UpdateFileName(ctx context.Context, arg UpdateFileNameParams) error {
+	_, err := q.db.ExecContext(ctx, updateFileName, arg.Name, arg.Uuid)
+	return err
+}
+
+const updateFileNotes = `-- name: UpdateFileNotes :exec
+UPDATE
+    files
+SET
+    notes = ?
+WHERE
+    uuid = ?
+`
+
+type UpdateFileNotesParams struct {
+	Notes string
+	Uuid  string
+}
+
+func (q *Queries) UpdateFileNotes(ctx context.Context, arg UpdateFileNotesParams) error {
+	_, err := q.db.ExecContext(ctx, updateFileNotes, arg.Notes, arg.Uuid)
+	return err
+}
+
+const updateFileSize = `-- name: UpdateFileSize :exec
+UPDATE
+    files
+SET
+    size = ?
+WHERE
+    uuid = ?
+`
+
+type UpdateFileSizeParams struct {
+	Size int64
+	Uuid string
+}
+
+func (q *Queries) UpdateFileSize(ctx context.Context, arg UpdateFileSizeParams) error {
+	_, err := q.db.ExecContext(ctx, updateFileSize, arg.Size, arg.Uuid)
+	return err
+}
+
+const updateFileUrl = `-- name: UpdateFileUrl :exec
+UPDATE
+    files
+SET
+    url = ?
+WHERE
+    uuid = ?
+`
+
+type UpdateFileUrlParams struct {
+	Url  string
+	Uuid string
+}
+
+func (q *Queries) UpdateFileUrl(ctx context.Context, arg UpdateFileUrlParams) error {
+	_, err := q.db.ExecContext(ctx, updateFileUrl, arg.Url, arg.Uuid)
+	return err
+}
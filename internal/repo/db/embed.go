@@ -1,8 +1,8 @@
 package db
 
 import (
-	_ "embed"
+	"embed"
 )
 
-//go:embed sql/schema.sql
-var Schema []byte
+//go:embed migrations/*.sql
+var Migrations embed.FS
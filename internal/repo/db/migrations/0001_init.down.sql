@@ -0,0 +1,9 @@
+DROP TABLE IF EXISTS permalinks;
+
+DROP TABLE IF EXISTS files;
+
+DROP TABLE IF EXISTS survey_state;
+
+DROP TABLE IF EXISTS pictures;
+
+DROP TABLE IF EXISTS visitors;
@@ -0,0 +1,13 @@
+UPDATE
+    files
+SET
+    url = 'var/' || url
+WHERE
+    url NOT LIKE 'var/%';
+
+UPDATE
+    pictures
+SET
+    url = 'var/' || url
+WHERE
+    url NOT LIKE 'var/%';
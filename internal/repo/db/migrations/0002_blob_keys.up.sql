@@ -0,0 +1,14 @@
+-- urls used to be paths under the var dir; they are now blob store keys
+UPDATE
+    files
+SET
+    url = substr(url, length('var/') + 1)
+WHERE
+    url LIKE 'var/%';
+
+UPDATE
+    pictures
+SET
+    url = substr(url, length('var/') + 1)
+WHERE
+    url LIKE 'var/%';
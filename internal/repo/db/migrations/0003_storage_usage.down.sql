@@ -0,0 +1,13 @@
+DROP TABLE storage_usage;
+
+DROP INDEX pictures_uploader_idx;
+
+DROP INDEX files_uploader_idx;
+
+ALTER TABLE pictures DROP COLUMN uploader;
+
+ALTER TABLE pictures DROP COLUMN size;
+
+ALTER TABLE files DROP COLUMN uploader;
+
+ALTER TABLE files DROP COLUMN size;
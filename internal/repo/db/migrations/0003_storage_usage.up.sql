@@ -0,0 +1,17 @@
+ALTER TABLE files ADD COLUMN size INTEGER NOT NULL DEFAULT 0;
+
+ALTER TABLE files ADD COLUMN uploader TEXT NOT NULL DEFAULT '';
+
+ALTER TABLE pictures ADD COLUMN size INTEGER NOT NULL DEFAULT 0;
+
+ALTER TABLE pictures ADD COLUMN uploader TEXT NOT NULL DEFAULT '';
+
+CREATE INDEX files_uploader_idx ON files (uploader);
+
+CREATE INDEX pictures_uploader_idx ON pictures (uploader);
+
+CREATE TABLE storage_usage (
+	area TEXT PRIMARY KEY,
+	bytes INTEGER NOT NULL DEFAULT 0,
+	updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP NOT NULL
+);
@@ -0,0 +1,10 @@
+CREATE TABLE api_tokens (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	name TEXT NOT NULL,
+	token_hash TEXT NOT NULL UNIQUE,
+	scopes TEXT NOT NULL,
+	expires TIMESTAMP,
+	last_used TIMESTAMP,
+	revoked TIMESTAMP,
+	pit TIMESTAMP DEFAULT CURRENT_TIMESTAMP NOT NULL
+);
@@ -0,0 +1,5 @@
+DROP INDEX files_expires_idx;
+
+ALTER TABLE files DROP COLUMN expires;
+
+ALTER TABLE files DROP COLUMN name;
@@ -0,0 +1,5 @@
+ALTER TABLE files ADD COLUMN name TEXT NOT NULL DEFAULT '';
+
+ALTER TABLE files ADD COLUMN expires TIMESTAMP;
+
+CREATE INDEX files_expires_idx ON files (expires);
@@ -0,0 +1,9 @@
+DROP TABLE permalink_accesses;
+
+ALTER TABLE permalinks DROP COLUMN revoked;
+
+ALTER TABLE permalinks DROP COLUMN password_hash;
+
+ALTER TABLE permalinks DROP COLUMN downloads;
+
+ALTER TABLE permalinks DROP COLUMN max_downloads;
@@ -0,0 +1,19 @@
+ALTER TABLE permalinks ADD COLUMN max_downloads INTEGER;
+
+ALTER TABLE permalinks ADD COLUMN downloads INTEGER NOT NULL DEFAULT 0;
+
+ALTER TABLE permalinks ADD COLUMN password_hash TEXT;
+
+ALTER TABLE permalinks ADD COLUMN revoked TIMESTAMP;
+
+CREATE TABLE permalink_accesses (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	permalink_uuid TEXT NOT NULL,
+	ip TEXT NOT NULL,
+	user_agent TEXT NOT NULL,
+	outcome TEXT NOT NULL,
+	pit TIMESTAMP DEFAULT CURRENT_TIMESTAMP NOT NULL,
+	FOREIGN KEY (permalink_uuid) REFERENCES permalinks(uuid) ON DELETE CASCADE
+);
+
+CREATE INDEX permalink_accesses_permalink_uuid_idx ON permalink_accesses (permalink_uuid);
@@ -0,0 +1,9 @@
+ALTER TABLE pictures DROP COLUMN sha256;
+
+ALTER TABLE pictures DROP COLUMN content_type;
+
+ALTER TABLE pictures DROP COLUMN name;
+
+ALTER TABLE files DROP COLUMN sha256;
+
+ALTER TABLE files DROP COLUMN content_type;
@@ -0,0 +1,9 @@
+ALTER TABLE files ADD COLUMN content_type TEXT NOT NULL DEFAULT '';
+
+ALTER TABLE files ADD COLUMN sha256 TEXT NOT NULL DEFAULT '';
+
+ALTER TABLE pictures ADD COLUMN name TEXT NOT NULL DEFAULT '';
+
+ALTER TABLE pictures ADD COLUMN content_type TEXT NOT NULL DEFAULT '';
+
+ALTER TABLE pictures ADD COLUMN sha256 TEXT NOT NULL DEFAULT '';
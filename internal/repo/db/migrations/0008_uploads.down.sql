@@ -0,0 +1,5 @@
+ALTER TABLE api_tokens DROP COLUMN max_upload_bytes;
+
+DROP TABLE upload_parts;
+
+DROP TABLE uploads;
@@ -0,0 +1,24 @@
+CREATE TABLE uploads (
+	id TEXT PRIMARY KEY,
+	name TEXT NOT NULL,
+	notes TEXT NOT NULL,
+	size INTEGER NOT NULL,
+	received INTEGER NOT NULL DEFAULT 0,
+	hash_state BLOB,
+	content_type TEXT NOT NULL DEFAULT '',
+	uploader TEXT NOT NULL,
+	ttl_seconds INTEGER NOT NULL DEFAULT 0,
+	updated_at TIMESTAMP NOT NULL,
+	pit TIMESTAMP DEFAULT CURRENT_TIMESTAMP NOT NULL
+);
+
+CREATE TABLE upload_parts (
+	upload_id TEXT NOT NULL,
+	offset_bytes INTEGER NOT NULL,
+	size INTEGER NOT NULL,
+	key TEXT NOT NULL,
+	PRIMARY KEY (upload_id, offset_bytes),
+	FOREIGN KEY (upload_id) REFERENCES uploads(id) ON DELETE CASCADE
+);
+
+ALTER TABLE api_tokens ADD COLUMN max_upload_bytes INTEGER;
@@ -0,0 +1,8 @@
+CREATE TABLE blobs (
+	sha256 TEXT PRIMARY KEY,
+	key TEXT NOT NULL UNIQUE,
+	size INTEGER NOT NULL,
+	content_type TEXT NOT NULL,
+	refs INTEGER NOT NULL DEFAULT 0,
+	pit TIMESTAMP DEFAULT CURRENT_TIMESTAMP NOT NULL
+);
@@ -0,0 +1,5 @@
+DROP TABLE picture_variants;
+
+ALTER TABLE pictures DROP COLUMN height;
+
+ALTER TABLE pictures DROP COLUMN width;
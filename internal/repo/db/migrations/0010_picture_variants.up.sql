@@ -0,0 +1,15 @@
+ALTER TABLE pictures ADD COLUMN width INTEGER NOT NULL DEFAULT 0;
+
+ALTER TABLE pictures ADD COLUMN height INTEGER NOT NULL DEFAULT 0;
+
+CREATE TABLE picture_variants (
+	picture_id INTEGER NOT NULL,
+	width INTEGER NOT NULL,
+	height INTEGER NOT NULL,
+	content_type TEXT NOT NULL,
+	key TEXT NOT NULL,
+	size INTEGER NOT NULL,
+	sha256 TEXT NOT NULL,
+	PRIMARY KEY (picture_id, width, content_type),
+	FOREIGN KEY (picture_id) REFERENCES pictures(id) ON DELETE CASCADE
+);
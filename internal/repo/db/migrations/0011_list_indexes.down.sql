@@ -0,0 +1,9 @@
+DROP INDEX permalinks_file_uuid_idx;
+
+DROP INDEX permalinks_pit_idx;
+
+DROP INDEX files_pit_idx;
+
+DROP INDEX pictures_pit_idx;
+
+DROP INDEX visitors_pit_idx;
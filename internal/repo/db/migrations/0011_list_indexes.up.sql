@@ -0,0 +1,9 @@
+CREATE INDEX visitors_pit_idx ON visitors (pit, id);
+
+CREATE INDEX pictures_pit_idx ON pictures (pit, id);
+
+CREATE INDEX files_pit_idx ON files (pit, uuid);
+
+CREATE INDEX permalinks_pit_idx ON permalinks (pit, uuid);
+
+CREATE INDEX permalinks_file_uuid_idx ON permalinks (file_uuid);
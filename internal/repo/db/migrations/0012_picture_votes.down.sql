@@ -0,0 +1,5 @@
+DROP INDEX pictures_num_dislikes_idx;
+
+DROP INDEX pictures_num_likes_idx;
+
+DROP TABLE picture_votes;
@@ -0,0 +1,13 @@
+CREATE TABLE picture_votes (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	picture_id INTEGER NOT NULL,
+	vote INTEGER NOT NULL,
+	pit TIMESTAMP DEFAULT CURRENT_TIMESTAMP NOT NULL,
+	FOREIGN KEY (picture_id) REFERENCES pictures(id) ON DELETE CASCADE
+);
+
+CREATE INDEX picture_votes_picture_id_pit_idx ON picture_votes (picture_id, pit);
+
+CREATE INDEX pictures_num_likes_idx ON pictures (num_likes, id);
+
+CREATE INDEX pictures_num_dislikes_idx ON pictures (num_dislikes, id);
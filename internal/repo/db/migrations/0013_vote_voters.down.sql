@@ -0,0 +1,27 @@
+CREATE TABLE picture_votes_old (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	picture_id INTEGER NOT NULL,
+	vote INTEGER NOT NULL,
+	pit TIMESTAMP DEFAULT CURRENT_TIMESTAMP NOT NULL,
+	FOREIGN KEY (picture_id) REFERENCES pictures(id) ON DELETE CASCADE
+);
+
+INSERT INTO
+	picture_votes_old (id, picture_id, vote, pit)
+SELECT
+	id,
+	picture_id,
+	vote,
+	pit
+FROM
+	picture_votes;
+
+DROP TABLE picture_votes;
+
+ALTER TABLE picture_votes_old RENAME TO picture_votes;
+
+CREATE INDEX picture_votes_picture_id_pit_idx ON picture_votes (picture_id, pit);
+
+ALTER TABLE pictures DROP COLUMN base_dislikes;
+
+ALTER TABLE pictures DROP COLUMN base_likes;
@@ -0,0 +1,60 @@
+ALTER TABLE pictures ADD COLUMN base_likes INTEGER NOT NULL DEFAULT 0;
+
+ALTER TABLE pictures ADD COLUMN base_dislikes INTEGER NOT NULL DEFAULT 0;
+
+CREATE TABLE picture_votes_new (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	picture_id INTEGER NOT NULL,
+	voter TEXT NOT NULL,
+	ip_hash TEXT NOT NULL,
+	vote INTEGER NOT NULL,
+	pit TIMESTAMP DEFAULT CURRENT_TIMESTAMP NOT NULL,
+	UNIQUE (picture_id, voter),
+	FOREIGN KEY (picture_id) REFERENCES pictures(id) ON DELETE CASCADE
+);
+
+-- votes from before voters were tracked can't be attributed to
+-- anyone, so each one stands alone
+INSERT INTO
+	picture_votes_new (id, picture_id, voter, ip_hash, vote, pit)
+SELECT
+	id,
+	picture_id,
+	'legacy:' || id,
+	'',
+	vote,
+	pit
+FROM
+	picture_votes;
+
+DROP TABLE picture_votes;
+
+ALTER TABLE picture_votes_new RENAME TO picture_votes;
+
+CREATE INDEX picture_votes_picture_id_pit_idx ON picture_votes (picture_id, pit);
+
+CREATE INDEX picture_votes_picture_id_ip_hash_idx ON picture_votes (picture_id, ip_hash);
+
+-- whatever the counts hold beyond the votes, from before there were
+-- votes or set through the api, becomes the base they count up from
+UPDATE
+	pictures
+SET
+	base_likes = num_likes - (
+		SELECT
+			COUNT(*)
+		FROM
+			picture_votes
+		WHERE
+			picture_id = pictures.id
+			AND vote > 0
+	),
+	base_dislikes = num_dislikes - (
+		SELECT
+			COUNT(*)
+		FROM
+			picture_votes
+		WHERE
+			picture_id = pictures.id
+			AND vote < 0
+	);
@@ -0,0 +1,9 @@
+DROP INDEX pictures_status_pit_idx;
+
+ALTER TABLE pictures DROP COLUMN moderated_at;
+
+ALTER TABLE pictures DROP COLUMN moderated_by;
+
+ALTER TABLE pictures DROP COLUMN moderation_reason;
+
+ALTER TABLE pictures DROP COLUMN status;
@@ -0,0 +1,10 @@
+-- pictures already in the gallery stay there
+ALTER TABLE pictures ADD COLUMN status TEXT NOT NULL DEFAULT 'approved';
+
+ALTER TABLE pictures ADD COLUMN moderation_reason TEXT NOT NULL DEFAULT '';
+
+ALTER TABLE pictures ADD COLUMN moderated_by TEXT NOT NULL DEFAULT '';
+
+ALTER TABLE pictures ADD COLUMN moderated_at TIMESTAMP;
+
+CREATE INDEX pictures_status_pit_idx ON pictures (status, pit, id);
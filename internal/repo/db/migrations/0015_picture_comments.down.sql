@@ -0,0 +1 @@
+DROP TABLE picture_comments;
@@ -0,0 +1,18 @@
+CREATE TABLE picture_comments (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	picture_id INTEGER NOT NULL,
+	author TEXT NOT NULL,
+	body TEXT NOT NULL,
+	voter TEXT NOT NULL,
+	ip_hash TEXT NOT NULL,
+	status TEXT NOT NULL DEFAULT 'visible',
+	moderation_reason TEXT NOT NULL DEFAULT '',
+	moderated_by TEXT NOT NULL DEFAULT '',
+	moderated_at TIMESTAMP,
+	pit TIMESTAMP DEFAULT CURRENT_TIMESTAMP NOT NULL,
+	FOREIGN KEY (picture_id) REFERENCES pictures(id) ON DELETE CASCADE
+);
+
+CREATE INDEX picture_comments_picture_id_pit_idx ON picture_comments (picture_id, pit, id);
+
+CREATE INDEX picture_comments_pit_idx ON picture_comments (pit, id);
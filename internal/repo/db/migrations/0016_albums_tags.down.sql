@@ -0,0 +1,5 @@
+DROP TABLE picture_tags;
+
+DROP TABLE album_pictures;
+
+DROP TABLE albums;
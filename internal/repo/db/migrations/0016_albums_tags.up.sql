@@ -0,0 +1,28 @@
+CREATE TABLE albums (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	slug TEXT NOT NULL UNIQUE,
+	title TEXT NOT NULL,
+	description TEXT NOT NULL DEFAULT '',
+	pit TIMESTAMP DEFAULT CURRENT_TIMESTAMP NOT NULL
+);
+
+CREATE TABLE album_pictures (
+	album_id INTEGER NOT NULL,
+	picture_id INTEGER NOT NULL,
+	pit TIMESTAMP DEFAULT CURRENT_TIMESTAMP NOT NULL,
+	PRIMARY KEY (album_id, picture_id),
+	FOREIGN KEY (album_id) REFERENCES albums(id) ON DELETE CASCADE,
+	FOREIGN KEY (picture_id) REFERENCES pictures(id) ON DELETE CASCADE
+);
+
+CREATE INDEX album_pictures_picture_id_idx ON album_pictures (picture_id, album_id);
+
+CREATE TABLE picture_tags (
+	picture_id INTEGER NOT NULL,
+	tag TEXT NOT NULL,
+	pit TIMESTAMP DEFAULT CURRENT_TIMESTAMP NOT NULL,
+	PRIMARY KEY (picture_id, tag),
+	FOREIGN KEY (picture_id) REFERENCES pictures(id) ON DELETE CASCADE
+);
+
+CREATE INDEX picture_tags_tag_idx ON picture_tags (tag, picture_id);
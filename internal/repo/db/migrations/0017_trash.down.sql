@@ -0,0 +1,7 @@
+DROP INDEX files_deleted_at_idx;
+
+DROP INDEX pictures_deleted_at_idx;
+
+ALTER TABLE files DROP COLUMN deleted_at;
+
+ALTER TABLE pictures DROP COLUMN deleted_at;
@@ -0,0 +1,7 @@
+ALTER TABLE pictures ADD COLUMN deleted_at TIMESTAMP;
+
+ALTER TABLE files ADD COLUMN deleted_at TIMESTAMP;
+
+CREATE INDEX pictures_deleted_at_idx ON pictures (deleted_at);
+
+CREATE INDEX files_deleted_at_idx ON files (deleted_at);
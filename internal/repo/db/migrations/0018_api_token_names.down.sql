@@ -0,0 +1 @@
+DROP INDEX api_tokens_name_idx;
@@ -0,0 +1,7 @@
+-- quotas and auto-approval key on token names, so they must be unique;
+-- later duplicates get their id appended
+UPDATE api_tokens
+SET name = name || '-' || id
+WHERE id NOT IN (SELECT MIN(id) FROM api_tokens GROUP BY name);
+
+CREATE UNIQUE INDEX api_tokens_name_idx ON api_tokens (name);
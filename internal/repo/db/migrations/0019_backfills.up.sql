@@ -0,0 +1,6 @@
+-- backfills that only need to run once record here that they are done,
+-- so they aren't repeated on every start
+CREATE TABLE backfills (
+	name TEXT PRIMARY KEY,
+	completed_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP NOT NULL
+);
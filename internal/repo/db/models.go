@@ -9,31 +9,150 @@ import (
 	"time"
 )
 
-type File struct {
-	Uuid      string
-	Url       string
-	Notes     string
-	Extension string
+type AlbumPicture struct {
+	AlbumID   int64
+	PictureID int64
 	Pit       time.Time
 }
 
+type Album struct {
+	ID          int64
+	Slug        string
+	Title       string
+	Description string
+	Pit         time.Time
+}
+
+type ApiToken struct {
+	ID             int64
+	Name           string
+	TokenHash      string
+	Scopes         string
+	Expires        sql.NullTime
+	LastUsed       sql.NullTime
+	Revoked        sql.NullTime
+	Pit            time.Time
+	MaxUploadBytes sql.NullInt64
+}
+
+type Backfill struct {
+	Name        string
+	CompletedAt time.Time
+}
+
+type Blob struct {
+	Sha256      string
+	Key         string
+	Size        int64
+	ContentType string
+	Refs        int64
+	Pit         time.Time
+}
+
+type File struct {
+	Uuid        string
+	Url         string
+	Notes       string
+	Extension   string
+	Pit         time.Time
+	Size        int64
+	Uploader    string
+	Name        string
+	Expires     sql.NullTime
+	ContentType string
+	Sha256      string
+	DeletedAt   sql.NullTime
+}
+
+type PermalinkAccess struct {
+	ID            int64
+	PermalinkUuid string
+	Ip            string
+	UserAgent     string
+	Outcome       string
+	Pit           time.Time
+}
+
 type Permalink struct {
 	Uuid            string
 	FileUuid        string
 	DurationSeconds int64
 	Expires         time.Time
 	Pit             time.Time
+	MaxDownloads    sql.NullInt64
+	Downloads       int64
+	PasswordHash    sql.NullString
+	Revoked         sql.NullTime
+}
+
+type PictureComment struct {
+	ID               int64
+	PictureID        int64
+	Author           string
+	Body             string
+	Voter            string
+	IpHash           string
+	Status           string
+	ModerationReason string
+	ModeratedBy      string
+	ModeratedAt      sql.NullTime
+	Pit              time.Time
+}
+
+type PictureTag struct {
+	PictureID int64
+	Tag       string
+	Pit       time.Time
+}
+
+type PictureVariant struct {
+	PictureID   int64
+	Width       int64
+	Height      int64
+	ContentType string
+	Key         string
+	Size        int64
+	Sha256      string
+}
+
+type PictureVote struct {
+	ID        int64
+	PictureID int64
+	Voter     string
+	IpHash    string
+	Vote      int64
+	Pit       time.Time
 }
 
 type Picture struct {
-	ID          int64
-	Author      string
-	Url         string
-	Description string
-	Extension   string
-	NumLikes    int64
-	NumDislikes int64
-	Pit         time.Time
+	ID               int64
+	Author           string
+	Url              string
+	Description      string
+	Extension        string
+	NumLikes         int64
+	NumDislikes      int64
+	Pit              time.Time
+	Size             int64
+	Uploader         string
+	Name             string
+	ContentType      string
+	Sha256           string
+	Width            int64
+	Height           int64
+	BaseLikes        int64
+	BaseDislikes     int64
+	Status           string
+	ModerationReason string
+	ModeratedBy      string
+	ModeratedAt      sql.NullTime
+	DeletedAt        sql.NullTime
+}
+
+type StorageUsage struct {
+	Area      string
+	Bytes     int64
+	UpdatedAt time.Time
 }
 
 type SurveyState struct {
@@ -42,6 +161,27 @@ type SurveyState struct {
 	Pit  time.Time
 }
 
+type UploadPart struct {
+	UploadID    string
+	OffsetBytes int64
+	Size        int64
+	Key         string
+}
+
+type Upload struct {
+	ID          string
+	Name        string
+	Notes       string
+	Size        int64
+	Received    int64
+	HashState   []byte
+	ContentType string
+	Uploader    string
+	TtlSeconds  int64
+	UpdatedAt   time.Time
+	Pit         time.Time
+}
+
 type Visitor struct {
 	ID      int64
 	Path    string
@@ -7,61 +7,1525 @@ package db
 
 import (
 	"context"
+	"database/sql"
 )
 
-const addDislikeToPicture = `-- name: AddDislikeToPicture :exec
-UPDATE
+const deletePicture = `-- name: DeletePicture :one
+DELETE FROM
+    pictures
+WHERE
+    id = ?
+RETURNING
+    url,
+    size,
+    sha256
+`
+
+type DeletePictureRow struct {
+	Url    string
+	Size   int64
+	Sha256 string
+}
+
+func (q *Queries) DeletePicture(ctx context.Context, id int64) (DeletePictureRow, error) {
+	row := q.db.QueryRowContext(ctx, deletePicture, id)
+	var i DeletePictureRow
+	err := row.Scan(
+		&i.Url,
+		&i.Size,
+		&i.Sha256,
+	)
+	return i, err
+}
+
+const deletePictureComment = `-- name: DeletePictureComment :execrows
+DELETE FROM
+    picture_comments
+WHERE
+    id = ?
+`
+
+func (q *Queries) DeletePictureComment(ctx context.Context, id int64) (int64, error) {
+	result, err := q.db.ExecContext(ctx, deletePictureComment, id)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const deletePictureVariants = `-- name: DeletePictureVariants :many
+DELETE FROM
+    picture_variants
+WHERE
+    picture_id = ?
+RETURNING
+    key,
+    size,
+    sha256
+`
+
+type DeletePictureVariantsRow struct {
+	Key    string
+	Size   int64
+	Sha256 string
+}
+
+func (q *Queries) DeletePictureVariants(ctx context.Context, pictureID int64) ([]DeletePictureVariantsRow, error) {
+	rows, err := q.db.QueryContext(ctx, deletePictureVariants, pictureID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []DeletePictureVariantsRow
+	for rows.Next() {
+		var i DeletePictureVariantsRow
+		if err := rows.Scan(
+			&i.Key,
+			&i.Size,
+			&i.Sha256,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const deletePictureVote = `-- name: DeletePictureVote :exec
+DELETE FROM
+    picture_votes
+WHERE
+    id = ?
+`
+
+func (q *Queries) DeletePictureVote(ctx context.Context, id int64) error {
+	_, err := q.db.ExecContext(ctx, deletePictureVote, id)
+	return err
+}
+
+const getAllPictureVariants = `-- name: GetAllPictureVariants :many
+SELECT
+    picture_id, width, height, content_type, key, size, sha256
+FROM
+    picture_variants
+ORDER BY
+    picture_id,
+    width,
+    content_type
+`
+
+func (q *Queries) GetAllPictureVariants(ctx context.Context) ([]PictureVariant, error) {
+	rows, err := q.db.QueryContext(ctx, getAllPictureVariants)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []PictureVariant
+	for rows.Next() {
+		var i PictureVariant
+		if err := rows.Scan(
+			&i.PictureID,
+			&i.Width,
+			&i.Height,
+			&i.ContentType,
+			&i.Key,
+			&i.Size,
+			&i.Sha256,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getAllPictures = `-- name: GetAllPictures :many
+SELECT
+    id, author, url, description, extension, num_likes, num_dislikes, pit, size, uploader, name, content_type, sha256, width, height, base_likes, base_dislikes, status, moderation_reason, moderated_by, moderated_at, deleted_at
+FROM
+    pictures
+`
+
+func (q *Queries) GetAllPictures(ctx context.Context) ([]Picture, error) {
+	rows, err := q.db.QueryContext(ctx, getAllPictures)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Picture
+	for rows.Next() {
+		var i Picture
+		if err := rows.Scan(
+			&i.ID,
+			&i.Author,
+			&i.Url,
+			&i.Description,
+			&i.Extension,
+			&i.NumLikes,
+			&i.NumDislikes,
+			&i.Pit,
+			&i.Size,
+			&i.Uploader,
+			&i.Name,
+			&i.ContentType,
+			&i.Sha256,
+			&i.Width,
+			&i.Height,
+			&i.BaseLikes,
+			&i.BaseDislikes,
+			&i.Status,
+			&i.ModerationReason,
+			&i.ModeratedBy,
+			&i.ModeratedAt,
+			&i.DeletedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getGalleryPicturesByDislikes = `-- name: GetGalleryPicturesByDislikes :many
+SELECT
+    id, author, url, description, extension, num_likes, num_dislikes, pit, size, uploader, name, content_type, sha256, width, height, base_likes, base_dislikes, status, moderation_reason, moderated_by, moderated_at, deleted_at
+FROM
+    pictures
+WHERE
+    status = 'approved'
+    AND deleted_at IS NULL
+    AND COALESCE(?, 0) IN (
+        SELECT
+            0
+        UNION ALL
+        SELECT
+            album_id
+        FROM
+            album_pictures
+        WHERE
+            album_pictures.picture_id = pictures.id
+    )
+    AND COALESCE(?, '') IN (
+        SELECT
+            ''
+        UNION ALL
+        SELECT
+            tag
+        FROM
+            picture_tags
+        WHERE
+            picture_tags.picture_id = pictures.id
+    )
+ORDER BY
+    num_dislikes DESC,
+    id DESC
+LIMIT
+    ? OFFSET ?
+`
+
+type GetGalleryPicturesByDislikesParams struct {
+	AlbumID sql.NullInt64
+	Tag     sql.NullString
+	Limit   int64
+	Offset  int64
+}
+
+func (q *Queries) GetGalleryPicturesByDislikes(ctx context.Context, arg GetGalleryPicturesByDislikesParams) ([]Picture, error) {
+	rows, err := q.db.QueryContext(ctx, getGalleryPicturesByDislikes,
+		arg.AlbumID,
+		arg.Tag,
+		arg.Limit,
+		arg.Offset,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Picture
+	for rows.Next() {
+		var i Picture
+		if err := rows.Scan(
+			&i.ID,
+			&i.Author,
+			&i.Url,
+			&i.Description,
+			&i.Extension,
+			&i.NumLikes,
+			&i.NumDislikes,
+			&i.Pit,
+			&i.Size,
+			&i.Uploader,
+			&i.Name,
+			&i.ContentType,
+			&i.Sha256,
+			&i.Width,
+			&i.Height,
+			&i.BaseLikes,
+			&i.BaseDislikes,
+			&i.Status,
+			&i.ModerationReason,
+			&i.ModeratedBy,
+			&i.ModeratedAt,
+			&i.DeletedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getGalleryPicturesByLikes = `-- name: GetGalleryPicturesByLikes :many
+SELECT
+    id, author, url, description, extension, num_likes, num_dislikes, pit, size, uploader, name, content_type, sha256, width, height, base_likes, base_dislikes, status, moderation_reason, moderated_by, moderated_at, deleted_at
+FROM
+    pictures
+WHERE
+    status = 'approved'
+    AND deleted_at IS NULL
+    AND COALESCE(?, 0) IN (
+        SELECT
+            0
+        UNION ALL
+        SELECT
+            album_id
+        FROM
+            album_pictures
+        WHERE
+            album_pictures.picture_id = pictures.id
+    )
+    AND COALESCE(?, '') IN (
+        SELECT
+            ''
+        UNION ALL
+        SELECT
+            tag
+        FROM
+            picture_tags
+        WHERE
+            picture_tags.picture_id = pictures.id
+    )
+ORDER BY
+    num_likes DESC,
+    id DESC
+LIMIT
+    ? OFFSET ?
+`
+
+type GetGalleryPicturesByLikesParams struct {
+	AlbumID sql.NullInt64
+	Tag     sql.NullString
+	Limit   int64
+	Offset  int64
+}
+
+func (q *Queries) GetGalleryPicturesByLikes(ctx context.Context, arg GetGalleryPicturesByLikesParams) ([]Picture, error) {
+	rows, err := q.db.QueryContext(ctx, getGalleryPicturesByLikes,
+		arg.AlbumID,
+		arg.Tag,
+		arg.Limit,
+		arg.Offset,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Picture
+	for rows.Next() {
+		var i Picture
+		if err := rows.Scan(
+			&i.ID,
+			&i.Author,
+			&i.Url,
+			&i.Description,
+			&i.Extension,
+			&i.NumLikes,
+			&i.NumDislikes,
+			&i.Pit,
+			&i.Size,
+			&i.Uploader,
+			&i.Name,
+			&i.ContentType,
+			&i.Sha256,
+			&i.Width,
+			&i.Height,
+			&i.BaseLikes,
+			&i.BaseDislikes,
+			&i.Status,
+			&i.ModerationReason,
+			&i.ModeratedBy,
+			&i.ModeratedAt,
+			&i.DeletedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getGalleryPicturesByScoreSince = `-- name: GetGalleryPicturesByScoreSince :many
+SELECT
+    id, author, url, description, extension, num_likes, num_dislikes, pit, size, uploader, name, content_type, sha256, width, height, base_likes, base_dislikes, status, moderation_reason, moderated_by, moderated_at, deleted_at
+FROM
+    pictures
+WHERE
+    status = 'approved'
+    AND deleted_at IS NULL
+    AND COALESCE(?, 0) IN (
+        SELECT
+            0
+        UNION ALL
+        SELECT
+            album_id
+        FROM
+            album_pictures
+        WHERE
+            album_pictures.picture_id = pictures.id
+    )
+    AND COALESCE(?, '') IN (
+        SELECT
+            ''
+        UNION ALL
+        SELECT
+            tag
+        FROM
+            picture_tags
+        WHERE
+            picture_tags.picture_id = pictures.id
+    )
+ORDER BY
+    (
+        SELECT
+            COALESCE(SUM(vote), 0)
+        FROM
+            picture_votes
+        WHERE
+            picture_votes.picture_id = pictures.id
+            AND picture_votes.pit >= ?
+    ) DESC,
+    pit DESC,
+    id DESC
+LIMIT
+    ? OFFSET ?
+`
+
+type GetGalleryPicturesByScoreSinceParams struct {
+	AlbumID sql.NullInt64
+	Tag     sql.NullString
+	Since   string
+	Limit   int64
+	Offset  int64
+}
+
+func (q *Queries) GetGalleryPicturesByScoreSince(ctx context.Context, arg GetGalleryPicturesByScoreSinceParams) ([]Picture, error) {
+	rows, err := q.db.QueryContext(ctx, getGalleryPicturesByScoreSince,
+		arg.AlbumID,
+		arg.Tag,
+		arg.Since,
+		arg.Limit,
+		arg.Offset,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Picture
+	for rows.Next() {
+		var i Picture
+		if err := rows.Scan(
+			&i.ID,
+			&i.Author,
+			&i.Url,
+			&i.Description,
+			&i.Extension,
+			&i.NumLikes,
+			&i.NumDislikes,
+			&i.Pit,
+			&i.Size,
+			&i.Uploader,
+			&i.Name,
+			&i.ContentType,
+			&i.Sha256,
+			&i.Width,
+			&i.Height,
+			&i.BaseLikes,
+			&i.BaseDislikes,
+			&i.Status,
+			&i.ModerationReason,
+			&i.ModeratedBy,
+			&i.ModeratedAt,
+			&i.DeletedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getGalleryPicturesNewest = `-- name: GetGalleryPicturesNewest :many
+SELECT
+    id, author, url, description, extension, num_likes, num_dislikes, pit, size, uploader, name, content_type, sha256, width, height, base_likes, base_dislikes, status, moderation_reason, moderated_by, moderated_at, deleted_at
+FROM
+    pictures
+WHERE
+    status = 'approved'
+    AND deleted_at IS NULL
+    AND COALESCE(?, 0) IN (
+        SELECT
+            0
+        UNION ALL
+        SELECT
+            album_id
+        FROM
+            album_pictures
+        WHERE
+            album_pictures.picture_id = pictures.id
+    )
+    AND COALESCE(?, '') IN (
+        SELECT
+            ''
+        UNION ALL
+        SELECT
+            tag
+        FROM
+            picture_tags
+        WHERE
+            picture_tags.picture_id = pictures.id
+    )
+ORDER BY
+    pit DESC,
+    id DESC
+LIMIT
+    ? OFFSET ?
+`
+
+type GetGalleryPicturesNewestParams struct {
+	AlbumID sql.NullInt64
+	Tag     sql.NullString
+	Limit   int64
+	Offset  int64
+}
+
+func (q *Queries) GetGalleryPicturesNewest(ctx context.Context, arg GetGalleryPicturesNewestParams) ([]Picture, error) {
+	rows, err := q.db.QueryContext(ctx, getGalleryPicturesNewest,
+		arg.AlbumID,
+		arg.Tag,
+		arg.Limit,
+		arg.Offset,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Picture
+	for rows.Next() {
+		var i Picture
+		if err := rows.Scan(
+			&i.ID,
+			&i.Author,
+			&i.Url,
+			&i.Description,
+			&i.Extension,
+			&i.NumLikes,
+			&i.NumDislikes,
+			&i.Pit,
+			&i.Size,
+			&i.Uploader,
+			&i.Name,
+			&i.ContentType,
+			&i.Sha256,
+			&i.Width,
+			&i.Height,
+			&i.BaseLikes,
+			&i.BaseDislikes,
+			&i.Status,
+			&i.ModerationReason,
+			&i.ModeratedBy,
+			&i.ModeratedAt,
+			&i.DeletedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getGalleryPicturesOldest = `-- name: GetGalleryPicturesOldest :many
+SELECT
+    id, author, url, description, extension, num_likes, num_dislikes, pit, size, uploader, name, content_type, sha256, width, height, base_likes, base_dislikes, status, moderation_reason, moderated_by, moderated_at, deleted_at
+FROM
+    pictures
+WHERE
+    status = 'approved'
+    AND deleted_at IS NULL
+    AND COALESCE(?, 0) IN (
+        SELECT
+            0
+        UNION ALL
+        SELECT
+            album_id
+        FROM
+            album_pictures
+        WHERE
+            album_pictures.picture_id = pictures.id
+    )
+    AND COALESCE(?, '') IN (
+        SELECT
+            ''
+        UNION ALL
+        SELECT
+            tag
+        FROM
+            picture_tags
+        WHERE
+            picture_tags.picture_id = pictures.id
+    )
+ORDER BY
+    pit,
+    id
+LIMIT
+    ? OFFSET ?
+`
+
+type GetGalleryPicturesOldestParams struct {
+	AlbumID sql.NullInt64
+	Tag     sql.NullString
+	Limit   int64
+	Offset  int64
+}
+
+func (q *Queries) GetGalleryPicturesOldest(ctx context.Context, arg GetGalleryPicturesOldestParams) ([]Picture, error) {
+	rows, err := q.db.QueryContext(ctx, getGalleryPicturesOldest,
+		arg.AlbumID,
+		arg.Tag,
+		arg.Limit,
+		arg.Offset,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Picture
+	for rows.Next() {
+		var i Picture
+		if err := rows.Scan(
+			&i.ID,
+			&i.Author,
+			&i.Url,
+			&i.Description,
+			&i.Extension,
+			&i.NumLikes,
+			&i.NumDislikes,
+			&i.Pit,
+			&i.Size,
+			&i.Uploader,
+			&i.Name,
+			&i.ContentType,
+			&i.Sha256,
+			&i.Width,
+			&i.Height,
+			&i.BaseLikes,
+			&i.BaseDislikes,
+			&i.Status,
+			&i.ModerationReason,
+			&i.ModeratedBy,
+			&i.ModeratedAt,
+			&i.DeletedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getGalleryPicturesShuffled = `-- name: GetGalleryPicturesShuffled :many
+SELECT
+    id, author, url, description, extension, num_likes, num_dislikes, pit, size, uploader, name, content_type, sha256, width, height, base_likes, base_dislikes, status, moderation_reason, moderated_by, moderated_at, deleted_at
+FROM
+    pictures
+WHERE
+    status = 'approved'
+    AND deleted_at IS NULL
+    AND COALESCE(?, 0) IN (
+        SELECT
+            0
+        UNION ALL
+        SELECT
+            album_id
+        FROM
+            album_pictures
+        WHERE
+            album_pictures.picture_id = pictures.id
+    )
+    AND COALESCE(?, '') IN (
+        SELECT
+            ''
+        UNION ALL
+        SELECT
+            tag
+        FROM
+            picture_tags
+        WHERE
+            picture_tags.picture_id = pictures.id
+    )
+ORDER BY
+    (id * ? + ?) % 2147483647,
+    id
+LIMIT
+    ? OFFSET ?
+`
+
+type GetGalleryPicturesShuffledParams struct {
+	AlbumID    sql.NullInt64
+	Tag        sql.NullString
+	Multiplier int64
+	Increment  int64
+	Limit      int64
+	Offset     int64
+}
+
+func (q *Queries) GetGalleryPicturesShuffled(ctx context.Context, arg GetGalleryPicturesShuffledParams) ([]Picture, error) {
+	rows, err := q.db.QueryContext(ctx, getGalleryPicturesShuffled,
+		arg.AlbumID,
+		arg.Tag,
+		arg.Multiplier,
+		arg.Increment,
+		arg.Limit,
+		arg.Offset,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Picture
+	for rows.Next() {
+		var i Picture
+		if err := rows.Scan(
+			&i.ID,
+			&i.Author,
+			&i.Url,
+			&i.Description,
+			&i.Extension,
+			&i.NumLikes,
+			&i.NumDislikes,
+			&i.Pit,
+			&i.Size,
+			&i.Uploader,
+			&i.Name,
+			&i.ContentType,
+			&i.Sha256,
+			&i.Width,
+			&i.Height,
+			&i.BaseLikes,
+			&i.BaseDislikes,
+			&i.Status,
+			&i.ModerationReason,
+			&i.ModeratedBy,
+			&i.ModeratedAt,
+			&i.DeletedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getPicture = `-- name: GetPicture :one
+SELECT
+    id, author, url, description, extension, num_likes, num_dislikes, pit, size, uploader, name, content_type, sha256, width, height, base_likes, base_dislikes, status, moderation_reason, moderated_by, moderated_at, deleted_at
+FROM
     pictures
-SET
-    num_dislikes = num_dislikes + 1
 WHERE
     id = ?
+    AND deleted_at IS NULL
+`
+
+func (q *Queries) GetPicture(ctx context.Context, id int64) (Picture, error) {
+	row := q.db.QueryRowContext(ctx, getPicture, id)
+	var i Picture
+	err := row.Scan(
+		&i.ID,
+		&i.Author,
+		&i.Url,
+		&i.Description,
+		&i.Extension,
+		&i.NumLikes,
+		&i.NumDislikes,
+		&i.Pit,
+		&i.Size,
+		&i.Uploader,
+		&i.Name,
+		&i.ContentType,
+		&i.Sha256,
+		&i.Width,
+		&i.Height,
+		&i.BaseLikes,
+		&i.BaseDislikes,
+		&i.Status,
+		&i.ModerationReason,
+		&i.ModeratedBy,
+		&i.ModeratedAt,
+		&i.DeletedAt,
+	)
+	return i, err
+}
+
+const getPictureComments = `-- name: GetPictureComments :many
+SELECT
+    id, picture_id, author, body, voter, ip_hash, status, moderation_reason, moderated_by, moderated_at, pit
+FROM
+    picture_comments
+WHERE
+    picture_id = ?
+    AND status = 'visible'
+ORDER BY
+    pit,
+    id
+`
+
+func (q *Queries) GetPictureComments(ctx context.Context, pictureID int64) ([]PictureComment, error) {
+	rows, err := q.db.QueryContext(ctx, getPictureComments, pictureID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []PictureComment
+	for rows.Next() {
+		var i PictureComment
+		if err := rows.Scan(
+			&i.ID,
+			&i.PictureID,
+			&i.Author,
+			&i.Body,
+			&i.Voter,
+			&i.IpHash,
+			&i.Status,
+			&i.ModerationReason,
+			&i.ModeratedBy,
+			&i.ModeratedAt,
+			&i.Pit,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getPictureVariants = `-- name: GetPictureVariants :many
+SELECT
+    picture_id, width, height, content_type, key, size, sha256
+FROM
+    picture_variants
+WHERE
+    picture_id = ?
+ORDER BY
+    width,
+    content_type
+`
+
+func (q *Queries) GetPictureVariants(ctx context.Context, pictureID int64) ([]PictureVariant, error) {
+	rows, err := q.db.QueryContext(ctx, getPictureVariants, pictureID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []PictureVariant
+	for rows.Next() {
+		var i PictureVariant
+		if err := rows.Scan(
+			&i.PictureID,
+			&i.Width,
+			&i.Height,
+			&i.ContentType,
+			&i.Key,
+			&i.Size,
+			&i.Sha256,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getPictureVoteByVoter = `-- name: GetPictureVoteByVoter :one
+SELECT
+    id, picture_id, voter, ip_hash, vote, pit
+FROM
+    picture_votes
+WHERE
+    picture_id = ?
+    AND voter = ?
+`
+
+type GetPictureVoteByVoterParams struct {
+	PictureID int64
+	Voter     string
+}
+
+func (q *Queries) GetPictureVoteByVoter(ctx context.Context, arg GetPictureVoteByVoterParams) (PictureVote, error) {
+	row := q.db.QueryRowContext(ctx, getPictureVoteByVoter, arg.PictureID, arg.Voter)
+	var i PictureVote
+	err := row.Scan(
+		&i.ID,
+		&i.PictureID,
+		&i.Voter,
+		&i.IpHash,
+		&i.Vote,
+		&i.Pit,
+	)
+	return i, err
+}
+
+const getPictureVoteCounts = `-- name: GetPictureVoteCounts :one
+SELECT
+    COALESCE(SUM(vote > 0), 0) AS likes,
+    COALESCE(SUM(vote < 0), 0) AS dislikes
+FROM
+    picture_votes
+WHERE
+    picture_id = ?
+`
+
+type GetPictureVoteCountsRow struct {
+	Likes    int64
+	Dislikes int64
+}
+
+func (q *Queries) GetPictureVoteCounts(ctx context.Context, pictureID int64) (GetPictureVoteCountsRow, error) {
+	row := q.db.QueryRowContext(ctx, getPictureVoteCounts, pictureID)
+	var i GetPictureVoteCountsRow
+	err := row.Scan(
+		&i.Likes,
+		&i.Dislikes,
+	)
+	return i, err
+}
+
+const getTrashedPicturesBefore = `-- name: GetTrashedPicturesBefore :many
+SELECT
+    id
+FROM
+    pictures
+WHERE
+    deleted_at < ?
+`
+
+func (q *Queries) GetTrashedPicturesBefore(ctx context.Context, deletedAt string) ([]int64, error) {
+	rows, err := q.db.QueryContext(ctx, getTrashedPicturesBefore, deletedAt)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []int64
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		items = append(items, id)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getVoterVotes = `-- name: GetVoterVotes :many
+SELECT
+    picture_id,
+    vote
+FROM
+    picture_votes
+WHERE
+    voter = ?
+`
+
+type GetVoterVotesRow struct {
+	PictureID int64
+	Vote      int64
+}
+
+func (q *Queries) GetVoterVotes(ctx context.Context, voter string) ([]GetVoterVotesRow, error) {
+	rows, err := q.db.QueryContext(ctx, getVoterVotes, voter)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetVoterVotesRow
+	for rows.Next() {
+		var i GetVoterVotesRow
+		if err := rows.Scan(
+			&i.PictureID,
+			&i.Vote,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const insertPicture = `-- name: InsertPicture :one
+INSERT INTO
+    pictures (
+        url,
+        author,
+        extension,
+        description,
+        size,
+        uploader,
+        name,
+        content_type,
+        sha256,
+        width,
+        height,
+        status,
+        moderation_reason,
+        moderated_by,
+        moderated_at
+    )
+VALUES
+    (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+RETURNING
+    id, author, url, description, extension, num_likes, num_dislikes, pit, size, uploader, name, content_type, sha256, width, height, base_likes, base_dislikes, status, moderation_reason, moderated_by, moderated_at, deleted_at
+`
+
+type InsertPictureParams struct {
+	Url              string
+	Author           string
+	Extension        string
+	Description      string
+	Size             int64
+	Uploader         string
+	Name             string
+	ContentType      string
+	Sha256           string
+	Width            int64
+	Height           int64
+	Status           string
+	ModerationReason string
+	ModeratedBy      string
+	ModeratedAt      sql.NullTime
+}
+
+func (q *Queries) InsertPicture(ctx context.Context, arg InsertPictureParams) (Picture, error) {
+	row := q.db.QueryRowContext(ctx, insertPicture,
+		arg.Url,
+		arg.Author,
+		arg.Extension,
+		arg.Description,
+		arg.Size,
+		arg.Uploader,
+		arg.Name,
+		arg.ContentType,
+		arg.Sha256,
+		arg.Width,
+		arg.Height,
+		arg.Status,
+		arg.ModerationReason,
+		arg.ModeratedBy,
+		arg.ModeratedAt,
+	)
+	var i Picture
+	err := row.Scan(
+		&i.ID,
+		&i.Author,
+		&i.Url,
+		&i.Description,
+		&i.Extension,
+		&i.NumLikes,
+		&i.NumDislikes,
+		&i.Pit,
+		&i.Size,
+		&i.Uploader,
+		&i.Name,
+		&i.ContentType,
+		&i.Sha256,
+		&i.Width,
+		&i.Height,
+		&i.BaseLikes,
+		&i.BaseDislikes,
+		&i.Status,
+		&i.ModerationReason,
+		&i.ModeratedBy,
+		&i.ModeratedAt,
+		&i.DeletedAt,
+	)
+	return i, err
+}
+
+const insertPictureComment = `-- name: InsertPictureComment :one
+INSERT INTO
+    picture_comments (picture_id, author, body, voter, ip_hash)
+VALUES
+    (?, ?, ?, ?, ?)
+RETURNING
+    id, picture_id, author, body, voter, ip_hash, status, moderation_reason, moderated_by, moderated_at, pit
+`
+
+type InsertPictureCommentParams struct {
+	PictureID int64
+	Author    string
+	Body      string
+	Voter     string
+	IpHash    string
+}
+
+func (q *Queries) InsertPictureComment(ctx context.Context, arg InsertPictureCommentParams) (PictureComment, error) {
+	row := q.db.QueryRowContext(ctx, insertPictureComment,
+		arg.PictureID,
+		arg.Author,
+		arg.Body,
+		arg.Voter,
+		arg.IpHash,
+	)
+	var i PictureComment
+	err := row.Scan(
+		&i.ID,
+		&i.PictureID,
+		&i.Author,
+		&i.Body,
+		&i.Voter,
+		&i.IpHash,
+		&i.Status,
+		&i.ModerationReason,
+		&i.ModeratedBy,
+		&i.ModeratedAt,
+		&i.Pit,
+	)
+	return i, err
+}
+
+const insertPictureVariant = `-- name: InsertPictureVariant :one
+INSERT INTO
+    picture_variants (
+        picture_id,
+        width,
+        height,
+        content_type,
+        key,
+        size,
+        sha256
+    )
+VALUES
+    (?, ?, ?, ?, ?, ?, ?)
+RETURNING
+    picture_id, width, height, content_type, key, size, sha256
+`
+
+type InsertPictureVariantParams struct {
+	PictureID   int64
+	Width       int64
+	Height      int64
+	ContentType string
+	Key         string
+	Size        int64
+	Sha256      string
+}
+
+func (q *Queries) InsertPictureVariant(ctx context.Context, arg InsertPictureVariantParams) (PictureVariant, error) {
+	row := q.db.QueryRowContext(ctx, insertPictureVariant,
+		arg.PictureID,
+		arg.Width,
+		arg.Height,
+		arg.ContentType,
+		arg.Key,
+		arg.Size,
+		arg.Sha256,
+	)
+	var i PictureVariant
+	err := row.Scan(
+		&i.PictureID,
+		&i.Width,
+		&i.Height,
+		&i.ContentType,
+		&i.Key,
+		&i.Size,
+		&i.Sha256,
+	)
+	return i, err
+}
+
+const insertPictureVote = `-- name: InsertPictureVote :exec
+INSERT INTO
+    picture_votes (picture_id, voter, ip_hash, vote)
+VALUES
+    (?, ?, ?, ?)
 `
 
-func (q *Queries) AddDislikeToPicture(ctx context.Context, id int64) error {
-	_, err := q.db.ExecContext(ctx, addDislikeToPicture, id)
+type InsertPictureVoteParams struct {
+	PictureID int64
+	Voter     string
+	IpHash    string
+	Vote      int64
+}
+
+func (q *Queries) InsertPictureVote(ctx context.Context, arg InsertPictureVoteParams) error {
+	_, err := q.db.ExecContext(ctx, insertPictureVote,
+		arg.PictureID,
+		arg.Voter,
+		arg.IpHash,
+		arg.Vote,
+	)
 	return err
 }
 
-const addLikeToPicture = `-- name: AddLikeToPicture :exec
-UPDATE
-    pictures
-SET
-    num_likes = num_likes + 1
+const listPictureCommentsAsc = `-- name: ListPictureCommentsAsc :many
+SELECT
+    id, picture_id, author, body, voter, ip_hash, status, moderation_reason, moderated_by, moderated_at, pit
+FROM
+    picture_comments
+WHERE
+    (pit, id) > (?, ?)
+    AND pit >= ?
+    AND pit < ?
+    AND picture_id = COALESCE(?, picture_id)
+    AND status = COALESCE(?, status)
+ORDER BY
+    pit,
+    id
+LIMIT
+    ?
+`
+
+type ListPictureCommentsAscParams struct {
+	CursorPit string
+	CursorID  int64
+	Since     string
+	Until     string
+	PictureID sql.NullInt64
+	Status    sql.NullString
+	Limit     int64
+}
+
+func (q *Queries) ListPictureCommentsAsc(ctx context.Context, arg ListPictureCommentsAscParams) ([]PictureComment, error) {
+	rows, err := q.db.QueryContext(ctx, listPictureCommentsAsc,
+		arg.CursorPit,
+		arg.CursorID,
+		arg.Since,
+		arg.Until,
+		arg.PictureID,
+		arg.Status,
+		arg.Limit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []PictureComment
+	for rows.Next() {
+		var i PictureComment
+		if err := rows.Scan(
+			&i.ID,
+			&i.PictureID,
+			&i.Author,
+			&i.Body,
+			&i.Voter,
+			&i.IpHash,
+			&i.Status,
+			&i.ModerationReason,
+			&i.ModeratedBy,
+			&i.ModeratedAt,
+			&i.Pit,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listPictureCommentsDesc = `-- name: ListPictureCommentsDesc :many
+SELECT
+    id, picture_id, author, body, voter, ip_hash, status, moderation_reason, moderated_by, moderated_at, pit
+FROM
+    picture_comments
 WHERE
-    id = ?
+    (pit, id) < (?, ?)
+    AND pit >= ?
+    AND pit < ?
+    AND picture_id = COALESCE(?, picture_id)
+    AND status = COALESCE(?, status)
+ORDER BY
+    pit DESC,
+    id DESC
+LIMIT
+    ?
 `
 
-func (q *Queries) AddLikeToPicture(ctx context.Context, id int64) error {
-	_, err := q.db.ExecContext(ctx, addLikeToPicture, id)
-	return err
+type ListPictureCommentsDescParams struct {
+	CursorPit string
+	CursorID  int64
+	Since     string
+	Until     string
+	PictureID sql.NullInt64
+	Status    sql.NullString
+	Limit     int64
 }
 
-const deletePicture = `-- name: DeletePicture :one
-DELETE FROM
+func (q *Queries) ListPictureCommentsDesc(ctx context.Context, arg ListPictureCommentsDescParams) ([]PictureComment, error) {
+	rows, err := q.db.QueryContext(ctx, listPictureCommentsDesc,
+		arg.CursorPit,
+		arg.CursorID,
+		arg.Since,
+		arg.Until,
+		arg.PictureID,
+		arg.Status,
+		arg.Limit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []PictureComment
+	for rows.Next() {
+		var i PictureComment
+		if err := rows.Scan(
+			&i.ID,
+			&i.PictureID,
+			&i.Author,
+			&i.Body,
+			&i.Voter,
+			&i.IpHash,
+			&i.Status,
+			&i.ModerationReason,
+			&i.ModeratedBy,
+			&i.ModeratedAt,
+			&i.Pit,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listPicturesAsc = `-- name: ListPicturesAsc :many
+SELECT
+    id, author, url, description, extension, num_likes, num_dislikes, pit, size, uploader, name, content_type, sha256, width, height, base_likes, base_dislikes, status, moderation_reason, moderated_by, moderated_at, deleted_at
+FROM
     pictures
 WHERE
-    id = ?
-RETURNING
-    url
+    (pit, id) > (?, ?)
+    AND pit >= ?
+    AND pit < ?
+    AND author = COALESCE(?, author)
+    AND extension = COALESCE(?, extension)
+    AND status = COALESCE(?, status)
+    AND (deleted_at IS NOT NULL) = ?
+ORDER BY
+    pit,
+    id
+LIMIT
+    ?
 `
 
-func (q *Queries) DeletePicture(ctx context.Context, id int64) (string, error) {
-	row := q.db.QueryRowContext(ctx, deletePicture, id)
-	var url string
-	err := row.Scan(&url)
-	return url, err
+type ListPicturesAscParams struct {
+	CursorPit string
+	CursorID  int64
+	Since     string
+	Until     string
+	Author    sql.NullString
+	Extension sql.NullString
+	Status    sql.NullString
+	Deleted   bool
+	Limit     int64
 }
 
-const getAllPictures = `-- name: GetAllPictures :many
+func (q *Queries) ListPicturesAsc(ctx context.Context, arg ListPicturesAscParams) ([]Picture, error) {
+	rows, err := q.db.QueryContext(ctx, listPicturesAsc,
+		arg.CursorPit,
+		arg.CursorID,
+		arg.Since,
+		arg.Until,
+		arg.Author,
+		arg.Extension,
+		arg.Status,
+		arg.Deleted,
+		arg.Limit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Picture
+	for rows.Next() {
+		var i Picture
+		if err := rows.Scan(
+			&i.ID,
+			&i.Author,
+			&i.Url,
+			&i.Description,
+			&i.Extension,
+			&i.NumLikes,
+			&i.NumDislikes,
+			&i.Pit,
+			&i.Size,
+			&i.Uploader,
+			&i.Name,
+			&i.ContentType,
+			&i.Sha256,
+			&i.Width,
+			&i.Height,
+			&i.BaseLikes,
+			&i.BaseDislikes,
+			&i.Status,
+			&i.ModerationReason,
+			&i.ModeratedBy,
+			&i.ModeratedAt,
+			&i.DeletedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listPicturesDesc = `-- name: ListPicturesDesc :many
 SELECT
-    id, author, url, description, extension, num_likes, num_dislikes, pit
+    id, author, url, description, extension, num_likes, num_dislikes, pit, size, uploader, name, content_type, sha256, width, height, base_likes, base_dislikes, status, moderation_reason, moderated_by, moderated_at, deleted_at
 FROM
     pictures
+WHERE
+    (pit, id) < (?, ?)
+    AND pit >= ?
+    AND pit < ?
+    AND author = COALESCE(?, author)
+    AND extension = COALESCE(?, extension)
+    AND status = COALESCE(?, status)
+    AND (deleted_at IS NOT NULL) = ?
+ORDER BY
+    pit DESC,
+    id DESC
+LIMIT
+    ?
 `
 
-func (q *Queries) GetAllPictures(ctx context.Context) ([]Picture, error) {
-	rows, err := q.db.QueryContext(ctx, getAllPictures)
+type ListPicturesDescParams struct {
+	CursorPit string
+	CursorID  int64
+	Since     string
+	Until     string
+	Author    sql.NullString
+	Extension sql.NullString
+	Status    sql.NullString
+	Deleted   bool
+	Limit     int64
+}
+
+func (q *Queries) ListPicturesDesc(ctx context.Context, arg ListPicturesDescParams) ([]Picture, error) {
+	rows, err := q.db.QueryContext(ctx, listPicturesDesc,
+		arg.CursorPit,
+		arg.CursorID,
+		arg.Since,
+		arg.Until,
+		arg.Author,
+		arg.Extension,
+		arg.Status,
+		arg.Deleted,
+		arg.Limit,
+	)
 	if err != nil {
 		return nil, err
 	}
@@ -78,6 +1542,20 @@ func (q *Queries) GetAllPictures(ctx context.Context) ([]Picture, error) {
 			&i.NumLikes,
 			&i.NumDislikes,
 			&i.Pit,
+			&i.Size,
+			&i.Uploader,
+			&i.Name,
+			&i.ContentType,
+			&i.Sha256,
+			&i.Width,
+			&i.Height,
+			&i.BaseLikes,
+			&i.BaseDislikes,
+			&i.Status,
+			&i.ModerationReason,
+			&i.ModeratedBy,
+			&i.ModeratedAt,
+			&i.DeletedAt,
 		); err != nil {
 			return nil, err
 		}
@@ -92,17 +1570,37 @@ func (q *Queries) GetAllPictures(ctx context.Context) ([]Picture, error) {
 	return items, nil
 }
 
-const getPicture = `-- name: GetPicture :one
-SELECT
-    id, author, url, description, extension, num_likes, num_dislikes, pit
-FROM
+const moderatePicture = `-- name: ModeratePicture :one
+UPDATE
     pictures
+SET
+    status = ?,
+    moderation_reason = ?,
+    moderated_by = ?,
+    moderated_at = ?
 WHERE
     id = ?
+    AND deleted_at IS NULL
+RETURNING
+    id, author, url, description, extension, num_likes, num_dislikes, pit, size, uploader, name, content_type, sha256, width, height, base_likes, base_dislikes, status, moderation_reason, moderated_by, moderated_at, deleted_at
 `
 
-func (q *Queries) GetPicture(ctx context.Context, id int64) (Picture, error) {
-	row := q.db.QueryRowContext(ctx, getPicture, id)
+type ModeratePictureParams struct {
+	Status           string
+	ModerationReason string
+	ModeratedBy      string
+	ModeratedAt      sql.NullTime
+	ID               int64
+}
+
+func (q *Queries) ModeratePicture(ctx context.Context, arg ModeratePictureParams) (Picture, error) {
+	row := q.db.QueryRowContext(ctx, moderatePicture,
+		arg.Status,
+		arg.ModerationReason,
+		arg.ModeratedBy,
+		arg.ModeratedAt,
+		arg.ID,
+	)
 	var i Picture
 	err := row.Scan(
 		&i.ID,
@@ -113,33 +1611,167 @@ func (q *Queries) GetPicture(ctx context.Context, id int64) (Picture, error) {
 		&i.NumLikes,
 		&i.NumDislikes,
 		&i.Pit,
+		&i.Size,
+		&i.Uploader,
+		&i.Name,
+		&i.ContentType,
+		&i.Sha256,
+		&i.Width,
+		&i.Height,
+		&i.BaseLikes,
+		&i.BaseDislikes,
+		&i.Status,
+		&i.ModerationReason,
+		&i.ModeratedBy,
+		&i.ModeratedAt,
+		&i.DeletedAt,
 	)
 	return i, err
 }
 
-const insertPicture = `-- name: InsertPicture :one
-INSERT INTO
-    pictures (url, author, extension, description)
-VALUES
-    (?, ?, ?, ?)
+const moderatePictureComment = `-- name: ModeratePictureComment :one
+UPDATE
+    picture_comments
+SET
+    status = ?,
+    moderation_reason = ?,
+    moderated_by = ?,
+    moderated_at = ?
+WHERE
+    id = ?
 RETURNING
-    id, author, url, description, extension, num_likes, num_dislikes, pit
+    id, picture_id, author, body, voter, ip_hash, status, moderation_reason, moderated_by, moderated_at, pit
 `
 
-type InsertPictureParams struct {
-	Url         string
-	Author      string
-	Extension   string
-	Description string
+type ModeratePictureCommentParams struct {
+	Status           string
+	ModerationReason string
+	ModeratedBy      string
+	ModeratedAt      sql.NullTime
+	ID               int64
 }
 
-func (q *Queries) InsertPicture(ctx context.Context, arg InsertPictureParams) (Picture, error) {
-	row := q.db.QueryRowContext(ctx, insertPicture,
-		arg.Url,
-		arg.Author,
-		arg.Extension,
-		arg.Description,
+func (q *Queries) ModeratePictureComment(ctx context.Context, arg ModeratePictureCommentParams) (PictureComment, error) {
+	row := q.db.QueryRowContext(ctx, moderatePictureComment,
+		arg.Status,
+		arg.ModerationReason,
+		arg.ModeratedBy,
+		arg.ModeratedAt,
+		arg.ID,
 	)
+	var i PictureComment
+	err := row.Scan(
+		&i.ID,
+		&i.PictureID,
+		&i.Author,
+		&i.Body,
+		&i.Voter,
+		&i.IpHash,
+		&i.Status,
+		&i.ModerationReason,
+		&i.ModeratedBy,
+		&i.ModeratedAt,
+		&i.Pit,
+	)
+	return i, err
+}
+
+const reconcilePictureVoteCounts = `-- name: ReconcilePictureVoteCounts :execrows
+UPDATE
+    pictures
+SET
+    num_likes = base_likes + (
+        SELECT
+            COUNT(*)
+        FROM
+            picture_votes
+        WHERE
+            picture_id = pictures.id
+            AND vote > 0
+    ),
+    num_dislikes = base_dislikes + (
+        SELECT
+            COUNT(*)
+        FROM
+            picture_votes
+        WHERE
+            picture_id = pictures.id
+            AND vote < 0
+    )
+WHERE
+    num_likes <> base_likes + (
+        SELECT
+            COUNT(*)
+        FROM
+            picture_votes
+        WHERE
+            picture_id = pictures.id
+            AND vote > 0
+    )
+    OR num_dislikes <> base_dislikes + (
+        SELECT
+            COUNT(*)
+        FROM
+            picture_votes
+        WHERE
+            picture_id = pictures.id
+            AND vote < 0
+    )
+`
+
+func (q *Queries) ReconcilePictureVoteCounts(ctx context.Context) (int64, error) {
+	result, err := q.db.ExecContext(ctx, reconcilePictureVoteCounts)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const refreshPictureVoteCounts = `-- name: RefreshPictureVoteCounts :exec
+UPDATE
+    pictures
+SET
+    num_likes = base_likes + (
+        SELECT
+            COUNT(*)
+        FROM
+            picture_votes
+        WHERE
+            picture_id = pictures.id
+            AND vote > 0
+    ),
+    num_dislikes = base_dislikes + (
+        SELECT
+            COUNT(*)
+        FROM
+            picture_votes
+        WHERE
+            picture_id = pictures.id
+            AND vote < 0
+    )
+WHERE
+    id = ?
+`
+
+func (q *Queries) RefreshPictureVoteCounts(ctx context.Context, id int64) error {
+	_, err := q.db.ExecContext(ctx, refreshPictureVoteCounts, id)
+	return err
+}
+
+const restorePicture = `-- name: RestorePicture :one
+UPDATE
+    pictures
+SET
+    deleted_at = NULL
+WHERE
+    id = ?
+    AND deleted_at IS NOT NULL
+RETURNING
+    id, author, url, description, extension, num_likes, num_dislikes, pit, size, uploader, name, content_type, sha256, width, height, base_likes, base_dislikes, status, moderation_reason, moderated_by, moderated_at, deleted_at
+`
+
+func (q *Queries) RestorePicture(ctx context.Context, id int64) (Picture, error) {
+	row := q.db.QueryRowContext(ctx, restorePicture, id)
 	var i Picture
 	err := row.Scan(
 		&i.ID,
@@ -150,10 +1782,42 @@ func (q *Queries) InsertPicture(ctx context.Context, arg InsertPictureParams) (P
 		&i.NumLikes,
 		&i.NumDislikes,
 		&i.Pit,
+		&i.Size,
+		&i.Uploader,
+		&i.Name,
+		&i.ContentType,
+		&i.Sha256,
+		&i.Width,
+		&i.Height,
+		&i.BaseLikes,
+		&i.BaseDislikes,
+		&i.Status,
+		&i.ModerationReason,
+		&i.ModeratedBy,
+		&i.ModeratedAt,
+		&i.DeletedAt,
 	)
 	return i, err
 }
 
+const trashPicture = `-- name: TrashPicture :execrows
+UPDATE
+    pictures
+SET
+    deleted_at = CURRENT_TIMESTAMP
+WHERE
+    id = ?
+    AND deleted_at IS NULL
+`
+
+func (q *Queries) TrashPicture(ctx context.Context, id int64) (int64, error) {
+	result, err := q.db.ExecContext(ctx, trashPicture, id)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
 const updateLikesDislikesOfPicture = `-- name: UpdateLikesDislikesOfPicture :one
 UPDATE
     pictures
@@ -162,8 +1826,9 @@ SET
     num_dislikes = ?
 WHERE
     id = ?
+    AND deleted_at IS NULL
 RETURNING
-    id, author, url, description, extension, num_likes, num_dislikes, pit
+    id, author, url, description, extension, num_likes, num_dislikes, pit, size, uploader, name, content_type, sha256, width, height, base_likes, base_dislikes, status, moderation_reason, moderated_by, moderated_at, deleted_at
 `
 
 type UpdateLikesDislikesOfPictureParams struct {
@@ -184,6 +1849,181 @@ func (q *Queries) UpdateLikesDislikesOfPicture(ctx context.Context, arg UpdateLi
 		&i.NumLikes,
 		&i.NumDislikes,
 		&i.Pit,
+		&i.Size,
+		&i.Uploader,
+		&i.Name,
+		&i.ContentType,
+		&i.Sha256,
+		&i.Width,
+		&i.Height,
+		&i.BaseLikes,
+		&i.BaseDislikes,
+		&i.Status,
+		&i.ModerationReason,
+		&i.ModeratedBy,
+		&i.ModeratedAt,
+		&i.DeletedAt,
 	)
 	return i, err
 }
+
+const updatePictureBlob = `-- name: UpdatePictureBlob :exec
+UPDATE
+    pictures
+SET
+    url = ?,
+    size = ?,
+    content_type = ?,
+    sha256 = ?
+WHERE
+    id = ?
+`
+
+type UpdatePictureBlobParams struct {
+	Url         string
+	Size        int64
+	ContentType string
+	Sha256      string
+	ID          int64
+}
+
+func (q *Queries) UpdatePictureBlob(ctx context.Context, arg UpdatePictureBlobParams) error {
+	_, err := q.db.ExecContext(ctx, updatePictureBlob,
+		arg.Url,
+		arg.Size,
+		arg.ContentType,
+		arg.Sha256,
+		arg.ID,
+	)
+	return err
+}
+
+const updatePictureDimensions = `-- name: UpdatePictureDimensions :exec
+UPDATE
+    pictures
+SET
+    width = ?,
+    height = ?
+WHERE
+    id = ?
+`
+
+type UpdatePictureDimensionsParams struct {
+	Width  int64
+	Height int64
+	ID     int64
+}
+
+func (q *Queries) UpdatePictureDimensions(ctx context.Context, arg UpdatePictureDimensionsParams) error {
+	_, err := q.db.ExecContext(ctx, updatePictureDimensions, arg.Width, arg.Height, arg.ID)
+	return err
+}
+
+const updatePictureMetadata = `-- name: UpdatePictureMetadata :exec
+UPDATE
+    pictures
+SET
+    content_type = ?,
+    sha256 = ?
+WHERE
+    id = ?
+`
+
+type UpdatePictureMetadataParams struct {
+	ContentType string
+	Sha256      string
+	ID          int64
+}
+
+func (q *Queries) UpdatePictureMetadata(ctx context.Context, arg UpdatePictureMetadataParams) error {
+	_, err := q.db.ExecContext(ctx, updatePictureMetadata, arg.ContentType, arg.Sha256, arg.ID)
+	return err
+}
+
+const updatePictureSize = `-- name: UpdatePictureSize :exec
+UPDATE
+    pictures
+SET
+    size = ?
+WHERE
+    id = ?
+`
+
+type UpdatePictureSizeParams struct {
+	Size int64
+	ID   int64
+}
+
+func (q *Queries) UpdatePictureSize(ctx context.Context, arg UpdatePictureSizeParams) error {
+	_, err := q.db.ExecContext(ctx, updatePictureSize, arg.Size, arg.ID)
+	return err
+}
+
+const updatePictureUrl = `-- name: UpdatePictureUrl :exec
+UPDATE
+    pictures
+SET
+    url = ?
+WHERE
+    id = ?
+`
+
+type UpdatePictureUrlParams struct {
+	Url string
+	ID  int64
+}
+
+func (q *Queries) UpdatePictureUrl(ctx context.Context, arg UpdatePictureUrlParams) error {
+	_, err := q.db.ExecContext(ctx, updatePictureUrl, arg.Url, arg.ID)
+	return err
+}
+
+const updatePictureVote = `-- name: UpdatePictureVote :exec
+UPDATE
+    picture_votes
+SET
+    voter = ?,
+    ip_hash = ?,
+    vote = ?,
+    pit = CURRENT_TIMESTAMP
+WHERE
+    id = ?
+`
+
+type UpdatePictureVoteParams struct {
+	Voter  string
+	IpHash string
+	Vote   int64
+	ID     int64
+}
+
+func (q *Queries) UpdatePictureVote(ctx context.Context, arg UpdatePictureVoteParams) error {
+	_, err := q.db.ExecContext(ctx, updatePictureVote,
+		arg.Voter,
+		arg.IpHash,
+		arg.Vote,
+		arg.ID,
+	)
+	return err
+}
+
+const updatePictureVoteBase = `-- name: UpdatePictureVoteBase :exec
+UPDATE
+    pictures
+SET
+    base_likes = ?,
+    base_dislikes = ?
+WHERE
+    id = ?
+`
+
+type UpdatePictureVoteBaseParams struct {
+	BaseLikes    int64
+	BaseDislikes int64
+	ID           int64
+}
+
+func (q *Queries) UpdatePictureVoteBase(ctx context.Context, arg UpdatePictureVoteBaseParams) error {
+	_, err := q.db.ExecContext(ctx, updatePictureVoteBase, arg.BaseLikes, arg.BaseDislikes, arg.ID)
+	return err
+}
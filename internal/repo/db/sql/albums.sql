@@ -0,0 +1,78 @@
+-- name: InsertAlbum :one
+INSERT INTO
+    albums (slug, title, description)
+VALUES
+    (?, ?, ?)
+RETURNING
+    *;
+
+-- name: GetAlbum :one
+SELECT
+    *
+FROM
+    albums
+WHERE
+    slug = ?;
+
+-- name: GetAllAlbums :many
+SELECT
+    *
+FROM
+    albums
+ORDER BY
+    title,
+    id;
+
+-- name: DeleteAlbum :execrows
+DELETE FROM
+    albums
+WHERE
+    slug = ?;
+
+-- name: AddAlbumPicture :exec
+INSERT INTO
+    album_pictures (album_id, picture_id)
+VALUES
+    (?, ?) ON CONFLICT DO NOTHING;
+
+-- name: RemoveAlbumPicture :execrows
+DELETE FROM
+    album_pictures
+WHERE
+    album_id = ?
+    AND picture_id = ?;
+
+-- name: GetPictureAlbums :many
+SELECT
+    albums.*
+FROM
+    albums
+    JOIN album_pictures ON album_pictures.album_id = albums.id
+WHERE
+    album_pictures.picture_id = ?
+ORDER BY
+    albums.title,
+    albums.id;
+
+-- name: AddPictureTag :exec
+INSERT INTO
+    picture_tags (picture_id, tag)
+VALUES
+    (?, ?) ON CONFLICT DO NOTHING;
+
+-- name: RemovePictureTag :execrows
+DELETE FROM
+    picture_tags
+WHERE
+    picture_id = ?
+    AND tag = ?;
+
+-- name: GetPictureTags :many
+SELECT
+    tag
+FROM
+    picture_tags
+WHERE
+    picture_id = ?
+ORDER BY
+    tag;
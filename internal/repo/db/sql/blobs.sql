@@ -0,0 +1,49 @@
+-- name: GetBlob :one
+SELECT
+    *
+FROM
+    blobs
+WHERE
+    sha256 = ?;
+
+-- name: GetAllBlobs :many
+SELECT
+    *
+FROM
+    blobs;
+
+-- name: InsertBlob :one
+INSERT INTO blobs (sha256, key, size, content_type, refs)
+VALUES (?, ?, ?, ?, 1)
+ON CONFLICT(sha256) DO UPDATE SET
+    refs = refs + 1
+RETURNING
+    *;
+
+-- name: AcquireBlob :one
+UPDATE
+    blobs
+SET
+    refs = refs + 1
+WHERE
+    sha256 = ?
+RETURNING
+    *;
+
+-- name: ReleaseBlob :one
+UPDATE
+    blobs
+SET
+    refs = refs - 1
+WHERE
+    sha256 = ?
+    AND key = ?
+RETURNING
+    *;
+
+-- name: DeleteBlob :exec
+DELETE FROM
+    blobs
+WHERE
+    sha256 = ?
+    AND refs <= 0;
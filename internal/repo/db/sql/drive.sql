@@ -1,12 +1,32 @@
 -- name: InsertFile :one
 INSERT INTO
-    files (uuid, url, notes, extension)
+    files (
+        uuid,
+        url,
+        name,
+        notes,
+        extension,
+        size,
+        uploader,
+        expires,
+        content_type,
+        sha256
+    )
 VALUES
-    (?, ?, ?, ?)
+    (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
 RETURNING
     *;
 
 -- name: GetFile :one
+SELECT
+    *
+FROM
+    files
+WHERE
+    uuid = ?
+    AND deleted_at IS NULL;
+
+-- name: GetFileIncludingTrashed :one
 SELECT
     *
 FROM
@@ -22,9 +42,16 @@ FROM
 
 -- name: InsertPermalink :one
 INSERT INTO
-    permalinks (uuid, file_uuid, duration_seconds, expires)
+    permalinks (
+        uuid,
+        file_uuid,
+        duration_seconds,
+        expires,
+        max_downloads,
+        password_hash
+    )
 VALUES
-    (?, ?, ?, ?)
+    (?, ?, ?, ?, ?, ?)
 RETURNING
     *;
 
@@ -40,4 +67,238 @@ WHERE
 SELECT
     *
 FROM
-    permalinks;
\ No newline at end of file
+    permalinks;
+
+-- name: UpdateFileSize :exec
+UPDATE
+    files
+SET
+    size = ?
+WHERE
+    uuid = ?;
+
+-- name: UpdateFileMetadata :exec
+UPDATE
+    files
+SET
+    content_type = ?,
+    sha256 = ?
+WHERE
+    uuid = ?;
+
+-- name: UpdateFileName :exec
+UPDATE
+    files
+SET
+    name = ?
+WHERE
+    uuid = ?;
+
+-- name: UpdateFileNotes :exec
+UPDATE
+    files
+SET
+    notes = ?
+WHERE
+    uuid = ?;
+
+-- name: UpdateFileExpires :exec
+UPDATE
+    files
+SET
+    expires = ?
+WHERE
+    uuid = ?;
+
+-- name: DeleteFile :one
+DELETE FROM
+    files
+WHERE
+    uuid = ?
+RETURNING
+    url,
+    size,
+    sha256;
+
+-- name: TrashFile :execrows
+UPDATE
+    files
+SET
+    deleted_at = CURRENT_TIMESTAMP
+WHERE
+    uuid = ?
+    AND deleted_at IS NULL;
+
+-- name: RestoreFile :one
+UPDATE
+    files
+SET
+    deleted_at = NULL
+WHERE
+    uuid = ?
+    AND deleted_at IS NOT NULL
+RETURNING
+    *;
+
+-- name: GetTrashedFilesBefore :many
+SELECT
+    uuid
+FROM
+    files
+WHERE
+    deleted_at < ?;
+
+-- name: GetExpiredFiles :many
+SELECT
+    *
+FROM
+    files
+WHERE
+    expires IS NOT NULL
+    AND expires <= ?;
+
+-- name: GetExpiredPermalinks :many
+SELECT
+    uuid
+FROM
+    permalinks
+WHERE
+    expires <= ?;
+
+-- name: DeleteExpiredPermalinks :many
+DELETE FROM
+    permalinks
+WHERE
+    expires <= ?
+RETURNING
+    uuid;
+
+-- name: RevokePermalink :one
+UPDATE
+    permalinks
+SET
+    revoked = CURRENT_TIMESTAMP
+WHERE
+    uuid = ?
+    AND revoked IS NULL
+RETURNING
+    *;
+
+-- name: IncrementPermalinkDownloads :execrows
+UPDATE
+    permalinks
+SET
+    downloads = downloads + 1
+WHERE
+    uuid = ?
+    AND (
+        max_downloads IS NULL
+        OR downloads < max_downloads
+    );
+
+-- name: InsertPermalinkAccess :exec
+INSERT INTO
+    permalink_accesses (permalink_uuid, ip, user_agent, outcome)
+VALUES
+    (?, ?, ?, ?);
+
+-- name: GetPermalinkAccesses :many
+SELECT
+    *
+FROM
+    permalink_accesses
+WHERE
+    permalink_uuid = ?
+ORDER BY
+    pit DESC,
+    id DESC;
+
+-- name: UpdateFileUrl :exec
+UPDATE
+    files
+SET
+    url = ?
+WHERE
+    uuid = ?;
+
+-- name: ListFilesDesc :many
+SELECT
+    *
+FROM
+    files
+WHERE
+    (pit, uuid) < (sqlc.arg(cursor_pit), sqlc.arg(cursor_uuid))
+    AND pit >= sqlc.arg(since)
+    AND pit < sqlc.arg(until)
+    AND extension = COALESCE(sqlc.narg(extension), extension)
+    AND (deleted_at IS NOT NULL) = sqlc.arg(deleted)
+ORDER BY
+    pit DESC,
+    uuid DESC
+LIMIT
+    ?;
+
+-- name: ListFilesAsc :many
+SELECT
+    *
+FROM
+    files
+WHERE
+    (pit, uuid) > (sqlc.arg(cursor_pit), sqlc.arg(cursor_uuid))
+    AND pit >= sqlc.arg(since)
+    AND pit < sqlc.arg(until)
+    AND extension = COALESCE(sqlc.narg(extension), extension)
+    AND (deleted_at IS NOT NULL) = sqlc.arg(deleted)
+ORDER BY
+    pit,
+    uuid
+LIMIT
+    ?;
+
+-- name: ListPermalinksDesc :many
+SELECT
+    *
+FROM
+    permalinks
+WHERE
+    (pit, uuid) < (sqlc.arg(cursor_pit), sqlc.arg(cursor_uuid))
+    AND pit >= sqlc.arg(since)
+    AND pit < sqlc.arg(until)
+    AND file_uuid = COALESCE(sqlc.narg(file_uuid), file_uuid)
+    AND file_uuid IN (
+        SELECT
+            uuid
+        FROM
+            files
+        WHERE
+            deleted_at IS NULL
+    )
+ORDER BY
+    pit DESC,
+    uuid DESC
+LIMIT
+    ?;
+
+-- name: ListPermalinksAsc :many
+SELECT
+    *
+FROM
+    permalinks
+WHERE
+    (pit, uuid) > (sqlc.arg(cursor_pit), sqlc.arg(cursor_uuid))
+    AND pit >= sqlc.arg(since)
+    AND pit < sqlc.arg(until)
+    AND file_uuid = COALESCE(sqlc.narg(file_uuid), file_uuid)
+    AND file_uuid IN (
+        SELECT
+            uuid
+        FROM
+            files
+        WHERE
+            deleted_at IS NULL
+    )
+ORDER BY
+    pit,
+    uuid
+LIMIT
+    ?;
@@ -1,8 +1,24 @@
 -- name: InsertPicture :one
 INSERT INTO
-    pictures (url, author, extension, description)
+    pictures (
+        url,
+        author,
+        extension,
+        description,
+        size,
+        uploader,
+        name,
+        content_type,
+        sha256,
+        width,
+        height,
+        status,
+        moderation_reason,
+        moderated_by,
+        moderated_at
+    )
 VALUES
-    (?, ?, ?, ?)
+    (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
 RETURNING
     *;
 
@@ -18,7 +34,8 @@ SELECT
 FROM
     pictures
 WHERE
-    id = ?;
+    id = ?
+    AND deleted_at IS NULL;
 
 -- name: DeletePicture :one
 DELETE FROM
@@ -26,23 +43,37 @@ DELETE FROM
 WHERE
     id = ?
 RETURNING
-    url;
+    url,
+    size,
+    sha256;
 
--- name: AddLikeToPicture :exec
+-- name: TrashPicture :execrows
 UPDATE
     pictures
 SET
-    num_likes = num_likes + 1
+    deleted_at = CURRENT_TIMESTAMP
 WHERE
-    id = ?;
+    id = ?
+    AND deleted_at IS NULL;
 
--- name: AddDislikeToPicture :exec
+-- name: RestorePicture :one
 UPDATE
     pictures
 SET
-    num_dislikes = num_dislikes + 1
+    deleted_at = NULL
 WHERE
-    id = ?;
+    id = ?
+    AND deleted_at IS NOT NULL
+RETURNING
+    *;
+
+-- name: GetTrashedPicturesBefore :many
+SELECT
+    id
+FROM
+    pictures
+WHERE
+    deleted_at < ?;
 
 -- name: UpdateLikesDislikesOfPicture :one
 UPDATE
@@ -52,5 +83,574 @@ SET
     num_dislikes = ?
 WHERE
     id = ?
+    AND deleted_at IS NULL
+RETURNING
+    *;
+
+-- name: UpdatePictureSize :exec
+UPDATE
+    pictures
+SET
+    size = ?
+WHERE
+    id = ?;
+
+-- name: UpdatePictureMetadata :exec
+UPDATE
+    pictures
+SET
+    content_type = ?,
+    sha256 = ?
+WHERE
+    id = ?;
+
+-- name: UpdatePictureUrl :exec
+UPDATE
+    pictures
+SET
+    url = ?
+WHERE
+    id = ?;
+
+-- name: UpdatePictureDimensions :exec
+UPDATE
+    pictures
+SET
+    width = ?,
+    height = ?
+WHERE
+    id = ?;
+
+-- name: UpdatePictureBlob :exec
+UPDATE
+    pictures
+SET
+    url = ?,
+    size = ?,
+    content_type = ?,
+    sha256 = ?
+WHERE
+    id = ?;
+
+-- name: InsertPictureVariant :one
+INSERT INTO
+    picture_variants (
+        picture_id,
+        width,
+        height,
+        content_type,
+        key,
+        size,
+        sha256
+    )
+VALUES
+    (?, ?, ?, ?, ?, ?, ?)
 RETURNING
-    *;
\ No newline at end of file
+    *;
+
+-- name: GetPictureVariants :many
+SELECT
+    *
+FROM
+    picture_variants
+WHERE
+    picture_id = ?
+ORDER BY
+    width,
+    content_type;
+
+-- name: GetAllPictureVariants :many
+SELECT
+    *
+FROM
+    picture_variants
+ORDER BY
+    picture_id,
+    width,
+    content_type;
+
+-- name: DeletePictureVariants :many
+DELETE FROM
+    picture_variants
+WHERE
+    picture_id = ?
+RETURNING
+    key,
+    size,
+    sha256;
+
+-- name: ListPicturesDesc :many
+SELECT
+    *
+FROM
+    pictures
+WHERE
+    (pit, id) < (sqlc.arg(cursor_pit), sqlc.arg(cursor_id))
+    AND pit >= sqlc.arg(since)
+    AND pit < sqlc.arg(until)
+    AND author = COALESCE(sqlc.narg(author), author)
+    AND extension = COALESCE(sqlc.narg(extension), extension)
+    AND status = COALESCE(sqlc.narg(status), status)
+    AND (deleted_at IS NOT NULL) = sqlc.arg(deleted)
+ORDER BY
+    pit DESC,
+    id DESC
+LIMIT
+    ?;
+
+-- name: ListPicturesAsc :many
+SELECT
+    *
+FROM
+    pictures
+WHERE
+    (pit, id) > (sqlc.arg(cursor_pit), sqlc.arg(cursor_id))
+    AND pit >= sqlc.arg(since)
+    AND pit < sqlc.arg(until)
+    AND author = COALESCE(sqlc.narg(author), author)
+    AND extension = COALESCE(sqlc.narg(extension), extension)
+    AND status = COALESCE(sqlc.narg(status), status)
+    AND (deleted_at IS NOT NULL) = sqlc.arg(deleted)
+ORDER BY
+    pit,
+    id
+LIMIT
+    ?;
+
+-- name: GetGalleryPicturesNewest :many
+SELECT
+    *
+FROM
+    pictures
+WHERE
+    status = 'approved'
+    AND deleted_at IS NULL
+    AND COALESCE(sqlc.narg(album_id), 0) IN (
+        SELECT
+            0
+        UNION ALL
+        SELECT
+            album_id
+        FROM
+            album_pictures
+        WHERE
+            album_pictures.picture_id = pictures.id
+    )
+    AND COALESCE(sqlc.narg(tag), '') IN (
+        SELECT
+            ''
+        UNION ALL
+        SELECT
+            tag
+        FROM
+            picture_tags
+        WHERE
+            picture_tags.picture_id = pictures.id
+    )
+ORDER BY
+    pit DESC,
+    id DESC
+LIMIT
+    ? OFFSET ?;
+
+-- name: GetGalleryPicturesOldest :many
+SELECT
+    *
+FROM
+    pictures
+WHERE
+    status = 'approved'
+    AND deleted_at IS NULL
+    AND COALESCE(sqlc.narg(album_id), 0) IN (
+        SELECT
+            0
+        UNION ALL
+        SELECT
+            album_id
+        FROM
+            album_pictures
+        WHERE
+            album_pictures.picture_id = pictures.id
+    )
+    AND COALESCE(sqlc.narg(tag), '') IN (
+        SELECT
+            ''
+        UNION ALL
+        SELECT
+            tag
+        FROM
+            picture_tags
+        WHERE
+            picture_tags.picture_id = pictures.id
+    )
+ORDER BY
+    pit,
+    id
+LIMIT
+    ? OFFSET ?;
+
+-- name: GetGalleryPicturesByLikes :many
+SELECT
+    *
+FROM
+    pictures
+WHERE
+    status = 'approved'
+    AND deleted_at IS NULL
+    AND COALESCE(sqlc.narg(album_id), 0) IN (
+        SELECT
+            0
+        UNION ALL
+        SELECT
+            album_id
+        FROM
+            album_pictures
+        WHERE
+            album_pictures.picture_id = pictures.id
+    )
+    AND COALESCE(sqlc.narg(tag), '') IN (
+        SELECT
+            ''
+        UNION ALL
+        SELECT
+            tag
+        FROM
+            picture_tags
+        WHERE
+            picture_tags.picture_id = pictures.id
+    )
+ORDER BY
+    num_likes DESC,
+    id DESC
+LIMIT
+    ? OFFSET ?;
+
+-- name: GetGalleryPicturesByDislikes :many
+SELECT
+    *
+FROM
+    pictures
+WHERE
+    status = 'approved'
+    AND deleted_at IS NULL
+    AND COALESCE(sqlc.narg(album_id), 0) IN (
+        SELECT
+            0
+        UNION ALL
+        SELECT
+            album_id
+        FROM
+            album_pictures
+        WHERE
+            album_pictures.picture_id = pictures.id
+    )
+    AND COALESCE(sqlc.narg(tag), '') IN (
+        SELECT
+            ''
+        UNION ALL
+        SELECT
+            tag
+        FROM
+            picture_tags
+        WHERE
+            picture_tags.picture_id = pictures.id
+    )
+ORDER BY
+    num_dislikes DESC,
+    id DESC
+LIMIT
+    ? OFFSET ?;
+
+-- name: GetGalleryPicturesShuffled :many
+SELECT
+    *
+FROM
+    pictures
+WHERE
+    status = 'approved'
+    AND deleted_at IS NULL
+    AND COALESCE(sqlc.narg(album_id), 0) IN (
+        SELECT
+            0
+        UNION ALL
+        SELECT
+            album_id
+        FROM
+            album_pictures
+        WHERE
+            album_pictures.picture_id = pictures.id
+    )
+    AND COALESCE(sqlc.narg(tag), '') IN (
+        SELECT
+            ''
+        UNION ALL
+        SELECT
+            tag
+        FROM
+            picture_tags
+        WHERE
+            picture_tags.picture_id = pictures.id
+    )
+ORDER BY
+    (id * sqlc.arg(multiplier) + sqlc.arg(increment)) % 2147483647,
+    id
+LIMIT
+    ? OFFSET ?;
+
+-- name: GetGalleryPicturesByScoreSince :many
+SELECT
+    *
+FROM
+    pictures
+WHERE
+    status = 'approved'
+    AND deleted_at IS NULL
+    AND COALESCE(sqlc.narg(album_id), 0) IN (
+        SELECT
+            0
+        UNION ALL
+        SELECT
+            album_id
+        FROM
+            album_pictures
+        WHERE
+            album_pictures.picture_id = pictures.id
+    )
+    AND COALESCE(sqlc.narg(tag), '') IN (
+        SELECT
+            ''
+        UNION ALL
+        SELECT
+            tag
+        FROM
+            picture_tags
+        WHERE
+            picture_tags.picture_id = pictures.id
+    )
+ORDER BY
+    (
+        SELECT
+            COALESCE(SUM(vote), 0)
+        FROM
+            picture_votes
+        WHERE
+            picture_votes.picture_id = pictures.id
+            AND picture_votes.pit >= sqlc.arg(since)
+    ) DESC,
+    pit DESC,
+    id DESC
+LIMIT
+    ? OFFSET ?;
+
+-- name: GetPictureVoteByVoter :one
+SELECT
+    *
+FROM
+    picture_votes
+WHERE
+    picture_id = ?
+    AND voter = ?;
+
+-- name: GetVoterVotes :many
+SELECT
+    picture_id,
+    vote
+FROM
+    picture_votes
+WHERE
+    voter = ?;
+
+-- name: InsertPictureVote :exec
+INSERT INTO
+    picture_votes (picture_id, voter, ip_hash, vote)
+VALUES
+    (?, ?, ?, ?);
+
+-- name: UpdatePictureVote :exec
+UPDATE
+    picture_votes
+SET
+    voter = ?,
+    ip_hash = ?,
+    vote = ?,
+    pit = CURRENT_TIMESTAMP
+WHERE
+    id = ?;
+
+-- name: DeletePictureVote :exec
+DELETE FROM
+    picture_votes
+WHERE
+    id = ?;
+
+-- name: GetPictureVoteCounts :one
+SELECT
+    COALESCE(SUM(vote > 0), 0) AS likes,
+    COALESCE(SUM(vote < 0), 0) AS dislikes
+FROM
+    picture_votes
+WHERE
+    picture_id = ?;
+
+-- name: UpdatePictureVoteBase :exec
+UPDATE
+    pictures
+SET
+    base_likes = ?,
+    base_dislikes = ?
+WHERE
+    id = ?;
+
+-- name: RefreshPictureVoteCounts :exec
+UPDATE
+    pictures
+SET
+    num_likes = base_likes + (
+        SELECT
+            COUNT(*)
+        FROM
+            picture_votes
+        WHERE
+            picture_id = pictures.id
+            AND vote > 0
+    ),
+    num_dislikes = base_dislikes + (
+        SELECT
+            COUNT(*)
+        FROM
+            picture_votes
+        WHERE
+            picture_id = pictures.id
+            AND vote < 0
+    )
+WHERE
+    id = ?;
+
+-- name: ReconcilePictureVoteCounts :execrows
+UPDATE
+    pictures
+SET
+    num_likes = base_likes + (
+        SELECT
+            COUNT(*)
+        FROM
+            picture_votes
+        WHERE
+            picture_id = pictures.id
+            AND vote > 0
+    ),
+    num_dislikes = base_dislikes + (
+        SELECT
+            COUNT(*)
+        FROM
+            picture_votes
+        WHERE
+            picture_id = pictures.id
+            AND vote < 0
+    )
+WHERE
+    num_likes <> base_likes + (
+        SELECT
+            COUNT(*)
+        FROM
+            picture_votes
+        WHERE
+            picture_id = pictures.id
+            AND vote > 0
+    )
+    OR num_dislikes <> base_dislikes + (
+        SELECT
+            COUNT(*)
+        FROM
+            picture_votes
+        WHERE
+            picture_id = pictures.id
+            AND vote < 0
+    );
+
+-- name: ModeratePicture :one
+UPDATE
+    pictures
+SET
+    status = ?,
+    moderation_reason = ?,
+    moderated_by = ?,
+    moderated_at = ?
+WHERE
+    id = ?
+    AND deleted_at IS NULL
+RETURNING
+    *;
+
+-- name: InsertPictureComment :one
+INSERT INTO
+    picture_comments (picture_id, author, body, voter, ip_hash)
+VALUES
+    (?, ?, ?, ?, ?)
+RETURNING
+    *;
+
+-- name: GetPictureComments :many
+SELECT
+    *
+FROM
+    picture_comments
+WHERE
+    picture_id = ?
+    AND status = 'visible'
+ORDER BY
+    pit,
+    id;
+
+-- name: ListPictureCommentsDesc :many
+SELECT
+    *
+FROM
+    picture_comments
+WHERE
+    (pit, id) < (sqlc.arg(cursor_pit), sqlc.arg(cursor_id))
+    AND pit >= sqlc.arg(since)
+    AND pit < sqlc.arg(until)
+    AND picture_id = COALESCE(sqlc.narg(picture_id), picture_id)
+    AND status = COALESCE(sqlc.narg(status), status)
+ORDER BY
+    pit DESC,
+    id DESC
+LIMIT
+    ?;
+
+-- name: ListPictureCommentsAsc :many
+SELECT
+    *
+FROM
+    picture_comments
+WHERE
+    (pit, id) > (sqlc.arg(cursor_pit), sqlc.arg(cursor_id))
+    AND pit >= sqlc.arg(since)
+    AND pit < sqlc.arg(until)
+    AND picture_id = COALESCE(sqlc.narg(picture_id), picture_id)
+    AND status = COALESCE(sqlc.narg(status), status)
+ORDER BY
+    pit,
+    id
+LIMIT
+    ?;
+
+-- name: ModeratePictureComment :one
+UPDATE
+    picture_comments
+SET
+    status = ?,
+    moderation_reason = ?,
+    moderated_by = ?,
+    moderated_at = ?
+WHERE
+    id = ?
+RETURNING
+    *;
+
+-- name: DeletePictureComment :execrows
+DELETE FROM
+    picture_comments
+WHERE
+    id = ?;
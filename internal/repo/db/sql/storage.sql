@@ -0,0 +1,75 @@
+-- name: GetStorageUsage :many
+SELECT
+    *
+FROM
+    storage_usage
+ORDER BY
+    area;
+
+-- name: AddStorageUsage :exec
+INSERT INTO storage_usage (area, bytes)
+VALUES (?, ?)
+ON CONFLICT(area) DO UPDATE SET
+    bytes = bytes + excluded.bytes,
+    updated_at = CURRENT_TIMESTAMP;
+
+-- name: SetStorageUsage :exec
+INSERT INTO storage_usage (area, bytes)
+VALUES (?, ?)
+ON CONFLICT(area) DO UPDATE SET
+    bytes = excluded.bytes,
+    updated_at = CURRENT_TIMESTAMP;
+
+-- name: GetUploaderUsage :one
+SELECT
+    CAST(COALESCE(SUM(size), 0) AS INTEGER) AS bytes
+FROM
+    (
+        SELECT uploader, size FROM files
+        UNION ALL
+        SELECT uploader, size FROM pictures
+        UNION ALL
+        SELECT
+            pictures.uploader,
+            picture_variants.size
+        FROM
+            picture_variants
+            JOIN pictures ON pictures.id = picture_variants.picture_id
+    )
+WHERE
+    uploader = ?;
+
+-- name: GetAllUploaderUsage :many
+SELECT
+    uploader,
+    CAST(SUM(size) AS INTEGER) AS bytes
+FROM
+    (
+        SELECT uploader, size FROM files
+        UNION ALL
+        SELECT uploader, size FROM pictures
+        UNION ALL
+        SELECT
+            pictures.uploader,
+            picture_variants.size
+        FROM
+            picture_variants
+            JOIN pictures ON pictures.id = picture_variants.picture_id
+    )
+GROUP BY
+    uploader
+ORDER BY
+    bytes DESC;
+
+-- name: IsBackfillDone :one
+SELECT
+    COUNT(*) AS count
+FROM
+    backfills
+WHERE
+    name = ?;
+
+-- name: MarkBackfillDone :exec
+INSERT INTO backfills (name)
+VALUES (?)
+ON CONFLICT(name) DO NOTHING;
@@ -0,0 +1,48 @@
+-- name: InsertApiToken :one
+INSERT INTO
+    api_tokens (name, token_hash, scopes, expires, max_upload_bytes)
+VALUES
+    (?, ?, ?, ?, ?)
+RETURNING
+    *;
+
+-- name: GetApiTokenByHash :one
+SELECT
+    *
+FROM
+    api_tokens
+WHERE
+    token_hash = ?;
+
+-- name: GetAllApiTokens :many
+SELECT
+    *
+FROM
+    api_tokens
+ORDER BY
+    id;
+
+-- name: TouchApiToken :exec
+UPDATE
+    api_tokens
+SET
+    last_used = CURRENT_TIMESTAMP
+WHERE
+    id = ?;
+
+-- name: RevokeApiToken :one
+UPDATE
+    api_tokens
+SET
+    revoked = CURRENT_TIMESTAMP
+WHERE
+    id = ?
+    AND revoked IS NULL
+RETURNING
+    *;
+
+-- name: CountApiTokens :one
+SELECT
+    COUNT(*) AS count
+FROM
+    api_tokens;
@@ -0,0 +1,80 @@
+-- name: InsertUpload :one
+INSERT INTO
+    uploads (
+        id,
+        name,
+        notes,
+        size,
+        uploader,
+        ttl_seconds,
+        updated_at
+    )
+VALUES
+    (?, ?, ?, ?, ?, ?, ?)
+RETURNING
+    *;
+
+-- name: GetUpload :one
+SELECT
+    *
+FROM
+    uploads
+WHERE
+    id = ?;
+
+-- name: AdvanceUpload :execrows
+UPDATE
+    uploads
+SET
+    received = sqlc.arg(new_received),
+    hash_state = sqlc.arg(hash_state),
+    content_type = sqlc.arg(content_type),
+    updated_at = sqlc.arg(updated_at)
+WHERE
+    id = sqlc.arg(id)
+    AND received = sqlc.arg(old_received);
+
+-- name: DeleteUpload :exec
+DELETE FROM
+    uploads
+WHERE
+    id = ?;
+
+-- name: GetStaleUploads :many
+SELECT
+    *
+FROM
+    uploads
+WHERE
+    updated_at < ?;
+
+-- name: InsertUploadPart :exec
+INSERT INTO
+    upload_parts (upload_id, offset_bytes, size, key)
+VALUES
+    (?, ?, ?, ?);
+
+-- name: GetUploadParts :many
+SELECT
+    *
+FROM
+    upload_parts
+WHERE
+    upload_id = ?
+ORDER BY
+    offset_bytes;
+
+-- name: GetOpenUploadBytes :one
+SELECT
+    CAST(COALESCE(SUM(size), 0) AS INTEGER) AS bytes
+FROM
+    uploads;
+
+-- name: GetUploaderOpenUploads :one
+SELECT
+    COUNT(*) AS count,
+    CAST(COALESCE(SUM(size), 0) AS INTEGER) AS bytes
+FROM
+    uploads
+WHERE
+    uploader = ?;
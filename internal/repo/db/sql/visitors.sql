@@ -5,7 +5,41 @@ VALUES
     (?, ?, ?, ?, ?, ?);
 
 -- name: GetAllVisitors :many
+SELECT
+    *
+FROM
+    visitors;
+
+-- name: ListVisitorsDesc :many
+SELECT
+    *
+FROM
+    visitors
+WHERE
+    (pit, id) < (sqlc.arg(cursor_pit), sqlc.arg(cursor_id))
+    AND pit >= sqlc.arg(since)
+    AND pit < sqlc.arg(until)
+    AND path = COALESCE(sqlc.narg(path), path)
+    AND country IS COALESCE(sqlc.narg(country), country)
+ORDER BY
+    pit DESC,
+    id DESC
+LIMIT
+    ?;
+
+-- name: ListVisitorsAsc :many
 SELECT
     *
 FROM
     visitors
+WHERE
+    (pit, id) > (sqlc.arg(cursor_pit), sqlc.arg(cursor_id))
+    AND pit >= sqlc.arg(since)
+    AND pit < sqlc.arg(until)
+    AND path = COALESCE(sqlc.narg(path), path)
+    AND country IS COALESCE(sqlc.narg(country), country)
+ORDER BY
+    pit,
+    id
+LIMIT
+    ?;
@@ -76,3 +76,139 @@ func (q *Queries) InsertVisitor(ctx context.Context, arg InsertVisitorParams) er
 	)
 	return err
 }
+
+const listVisitorsAsc = `-- name: ListVisitorsAsc :many
+SELECT
+    id, path, message, ip, city, region, country, pit
+FROM
+    visitors
+WHERE
+    (pit, id) > (?, ?)
+    AND pit >= ?
+    AND pit < ?
+    AND path = COALESCE(?, path)
+    AND country IS COALESCE(?, country)
+ORDER BY
+    pit,
+    id
+LIMIT
+    ?
+`
+
+type ListVisitorsAscParams struct {
+	CursorPit string
+	CursorID  int64
+	Since     string
+	Until     string
+	Path      sql.NullString
+	Country   sql.NullString
+	Limit     int64
+}
+
+func (q *Queries) ListVisitorsAsc(ctx context.Context, arg ListVisitorsAscParams) ([]Visitor, error) {
+	rows, err := q.db.QueryContext(ctx, listVisitorsAsc,
+		arg.CursorPit,
+		arg.CursorID,
+		arg.Since,
+		arg.Until,
+		arg.Path,
+		arg.Country,
+		arg.Limit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Visitor
+	for rows.Next() {
+		var i Visitor
+		if err := rows.Scan(
+			&i.ID,
+			&i.Path,
+			&i.Message,
+			&i.Ip,
+			&i.City,
+			&i.Region,
+			&i.Country,
+			&i.Pit,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listVisitorsDesc = `-- name: ListVisitorsDesc :many
+SELECT
+    id, path, message, ip, city, region, country, pit
+FROM
+    visitors
+WHERE
+    (pit, id) < (?, ?)
+    AND pit >= ?
+    AND pit < ?
+    AND path = COALESCE(?, path)
+    AND country IS COALESCE(?, country)
+ORDER BY
+    pit DESC,
+    id DESC
+LIMIT
+    ?
+`
+
+type ListVisitorsDescParams struct {
+	CursorPit string
+	CursorID  int64
+	Since     string
+	Until     string
+	Path      sql.NullString
+	Country   sql.NullString
+	Limit     int64
+}
+
+func (q *Queries) ListVisitorsDesc(ctx context.Context, arg ListVisitorsDescParams) ([]Visitor, error) {
+	rows, err := q.db.QueryContext(ctx, listVisitorsDesc,
+		arg.CursorPit,
+		arg.CursorID,
+		arg.Since,
+		arg.Until,
+		arg.Path,
+		arg.Country,
+		arg.Limit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Visitor
+	for rows.Next() {
+		var i Visitor
+		if err := rows.Scan(
+			&i.ID,
+			&i.Path,
+			&i.Message,
+			&i.Ip,
+			&i.City,
+			&i.Region,
+			&i.Country,
+			&i.Pit,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
@@ -2,33 +2,51 @@ package repo
 
 import (
 	"context"
-	"crypto/rand"
 	"database/sql"
-	"encoding/hex"
 	"errors"
 	"fmt"
 	"io"
 	"mime/multipart"
-	"os"
 	"path/filepath"
 	"time"
 
 	"github.com/btschwartz12/site/internal/repo/db"
+	"github.com/btschwartz12/site/internal/storage"
 	"github.com/google/uuid"
+	"golang.org/x/crypto/bcrypt"
 )
 
 const (
 	driveUploadDir    = "drive"
 	maxFileUploadMb   = 5
 	maxFileUploadSize = maxFileUploadMb << 20
+
+	maxPermalinkIdAttempts = 5
 )
 
 type File struct {
-	Uuid      uuid.UUID
-	Url       string
-	Notes     string
-	Extension string
-	Pit       time.Time
+	Uuid        uuid.UUID
+	Url         string
+	Name        string
+	Notes       string
+	Extension   string
+	Size        int64
+	ContentType string
+	Sha256      string
+	Uploader    string
+	Expires     *time.Time
+	Pit         time.Time
+	// DeletedAt is when the file went to the trash, if it has.
+	DeletedAt *time.Time
+}
+
+// FileUpdate holds the changes to apply to a file; nil fields are
+// left as they are.
+type FileUpdate struct {
+	Name  *string
+	Notes *string
+	// Expires sets a new expiry; a zero time clears it.
+	Expires *time.Time
 }
 
 type Permalink struct {
@@ -36,29 +54,78 @@ type Permalink struct {
 	File            *File
 	DurationSeconds int64
 	Expires         time.Time
+	MaxDownloads    *int64
+	Downloads       int64
+	HasPassword     bool
+	Revoked         *time.Time
 	Pit             time.Time
+
+	passwordHash string
+}
+
+// PermalinkOptions restricts who can use a permalink beyond its expiry.
+type PermalinkOptions struct {
+	// MaxDownloads limits how many times the file can be
+	// downloaded; zero means unlimited.
+	MaxDownloads int64
+	// Password, if set, must be given to download the file.
+	Password string
 }
 
 func (p *File) fromDb(row *db.File) {
 	p.Uuid = uuid.MustParse(row.Uuid)
 	p.Url = row.Url
+	p.Name = row.Name
 	p.Notes = row.Notes
 	p.Extension = row.Extension
+	p.Size = row.Size
+	p.ContentType = row.ContentType
+	p.Sha256 = row.Sha256
+	p.Uploader = row.Uploader
+	p.Expires = nullTimePtr(row.Expires)
 	p.Pit = row.Pit
+	p.DeletedAt = nullTimePtr(row.DeletedAt)
+}
+
+// Content describes how to serve the file, as a download under
+// its original name.
+func (p *File) Content() storage.Content {
+	return storage.Content{
+		Key:         p.Url,
+		Size:        p.Size,
+		ModTime:     p.Pit,
+		ContentType: p.ContentType,
+		Sha256:      p.Sha256,
+		Name:        p.Name,
+	}
 }
 
-func (r *Repo) permalinkFromDb(row *db.Permalink) (*Permalink, error) {
-	f, err := r.GetFile(context.Background(), row.FileUuid)
+// permalinkFromDb looks up the permalink's file even if it is in the
+// trash, so its permalinks can still be managed, and refuse downloads,
+// until it is purged.
+func (r *Repo) permalinkFromDb(ctx context.Context, row *db.Permalink) (*Permalink, error) {
+	q := db.New(r.db)
+	fileRow, err := q.GetFileIncludingTrashed(ctx, row.FileUuid)
 	if err != nil {
 		return nil, fmt.Errorf("error getting file: %w", err)
 	}
-	return &Permalink{
+	f := &File{}
+	f.fromDb(&fileRow)
+	p := &Permalink{
 		Uuid:            row.Uuid,
 		File:            f,
 		DurationSeconds: row.DurationSeconds,
 		Expires:         row.Expires,
+		Downloads:       row.Downloads,
+		HasPassword:     row.PasswordHash.Valid,
+		Revoked:         nullTimePtr(row.Revoked),
 		Pit:             row.Pit,
-	}, nil
+		passwordHash:    row.PasswordHash.String,
+	}
+	if row.MaxDownloads.Valid {
+		p.MaxDownloads = &row.MaxDownloads.Int64
+	}
+	return p, nil
 }
 
 func (r *Repo) InsertFile(
@@ -66,31 +133,101 @@ func (r *Repo) InsertFile(
 	file multipart.File,
 	header *multipart.FileHeader,
 	notes string,
+	uploader string,
+	ttl time.Duration,
 ) (*File, error) {
-	if r.storageFull() {
-		return nil, fmt.Errorf("storage full")
-	}
-
 	if header.Size > maxFileUploadSize {
-		return nil, fmt.Errorf("file too large (max %d MB)", maxPictureUploadMb)
+		return nil, errorf(ErrTooLarge, "file too large (max %d MB)", maxFileUploadMb)
+	}
+	if err := r.checkQuota(ctx, uploader, header.Size); err != nil {
+		return nil, err
+	}
+	meta, err := hashBlob(file, header.Filename)
+	if err != nil {
+		return nil, err
 	}
+
 	ext := filepath.Ext(header.Filename)
+	params := db.InsertFileParams{
+		Uuid:        uuid.New().String(),
+		Name:        header.Filename,
+		Notes:       notes,
+		Extension:   ext,
+		Size:        meta.Size,
+		Uploader:    uploader,
+		ContentType: meta.ContentType,
+		Sha256:      meta.Sha256,
+	}
+	if ttl > 0 {
+		params.Expires = expiresAt(time.Now().Add(ttl))
+	}
+	f, err := r.storeFile(ctx, meta, rewind(file), params)
+	if err != nil {
+		return nil, err
+	}
+	observeUpload(areaDrive, meta.Size)
+	return f, nil
+}
 
-	url, err := r.getFileUrl(ctx, uuid.New().String(), file, header)
+// InsertFileFromBlob adds a file with the content of an already stored
+// blob, without uploading it again.
+func (r *Repo) InsertFileFromBlob(
+	ctx context.Context,
+	sha256 string,
+	name string,
+	notes string,
+	uploader string,
+	ttl time.Duration,
+) (*File, error) {
+	if name == "" {
+		return nil, errorf(ErrInvalidInput, "invalid file: name is required")
+	}
+	b, err := r.GetBlob(ctx, sha256)
 	if err != nil {
-		return nil, fmt.Errorf("error getting file url: %w", err)
+		return nil, err
+	}
+	if err := r.checkQuota(ctx, uploader, b.Size); err != nil {
+		return nil, err
 	}
 
+	meta := &blobMeta{ContentType: b.ContentType, Sha256: b.Sha256, Size: b.Size}
 	params := db.InsertFileParams{
-		Uuid:      uuid.New().String(),
-		Url:       url,
-		Notes:     notes,
-		Extension: ext,
+		Uuid:        uuid.New().String(),
+		Name:        name,
+		Notes:       notes,
+		Extension:   filepath.Ext(name),
+		Size:        b.Size,
+		Uploader:    uploader,
+		ContentType: b.ContentType,
+		Sha256:      b.Sha256,
 	}
-	q := db.New(r.db)
-	row, err := q.InsertFile(ctx, params)
+	if ttl > 0 {
+		params.Expires = expiresAt(time.Now().Add(ttl))
+	}
+	// the blob is only reopened if it went away since the lookup
+	return r.storeFile(ctx, meta, func() (io.ReadCloser, error) {
+		return nil, fmt.Errorf("error getting blob: %w", sql.ErrNoRows)
+	}, params)
+}
+
+// storeFile stores the content of a new file and inserts its row.
+func (r *Repo) storeFile(ctx context.Context, meta *blobMeta, open func() (io.ReadCloser, error), params db.InsertFileParams) (*File, error) {
+	var row db.File
+	err := r.storeBlob(ctx, meta, open, func(q *db.Queries) error {
+		params.Url = meta.Key
+		var err error
+		row, err = q.InsertFile(ctx, params)
+		if err != nil {
+			return fmt.Errorf("error inserting file: %w", err)
+		}
+		err = q.AddStorageUsage(ctx, db.AddStorageUsageParams{Area: areaDrive, Bytes: row.Size})
+		if err != nil {
+			return fmt.Errorf("error updating storage usage: %w", err)
+		}
+		return nil
+	})
 	if err != nil {
-		return nil, fmt.Errorf("error inserting picture: %w", err)
+		return nil, err
 	}
 
 	f := &File{}
@@ -109,56 +246,252 @@ func (r *Repo) GetFile(ctx context.Context, uuid string) (*File, error) {
 	return f, nil
 }
 
-func (r *Repo) GetAllFiles(ctx context.Context) ([]File, error) {
+// FileFilter selects the files to list.
+type FileFilter struct {
+	ListOptions
+	// Extension matches files by extension, with or without the
+	// leading dot.
+	Extension string
+	// Deleted lists the files in the trash instead.
+	Deleted bool
+}
+
+// ListFiles returns a page of files matching f and the cursor for the
+// next page, which is empty on the last one.
+func (r *Repo) ListFiles(ctx context.Context, f FileFilter) ([]File, string, error) {
+	b, err := f.bounds()
+	if err != nil {
+		return nil, "", err
+	}
+	params := db.ListFilesDescParams{
+		CursorPit:  b.cursorPit,
+		CursorUuid: b.cursorKey,
+		Since:      b.since,
+		Until:      b.until,
+		Extension:  nullIfEmpty(normalizeExtension(f.Extension)),
+		Deleted:    f.Deleted,
+		Limit:      b.limit,
+	}
+
 	q := db.New(r.db)
-	rows, err := q.GetAllFiles(ctx)
+	var rows []db.File
+	if f.Ascending {
+		rows, err = q.ListFilesAsc(ctx, db.ListFilesAscParams(params))
+	} else {
+		rows, err = q.ListFilesDesc(ctx, params)
+	}
 	if err != nil {
-		return nil, fmt.Errorf("error getting files: %w", err)
+		return nil, "", fmt.Errorf("error listing files: %w", err)
 	}
+	rows, next := page(rows, b, func(f db.File) (time.Time, string) {
+		return f.Pit, f.Uuid
+	})
 	files := make([]File, len(rows))
 	for i, row := range rows {
 		files[i].fromDb(&row)
 	}
-	return files, nil
+	return files, next, nil
 }
 
-func (r *Repo) InsertPermalink(ctx context.Context, fileUuid string, durationSeconds int64) (*Permalink, error) {
-	_, err := r.GetFile(ctx, fileUuid)
+// UpdateFile applies upd to the file with the given uuid and returns
+// the updated file.
+func (r *Repo) UpdateFile(ctx context.Context, uuid string, upd FileUpdate) (*File, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
 	if err != nil {
-		if errors.Is(err, sql.ErrNoRows) {
-			return nil, fmt.Errorf("file not found")
-		} else {
-			return nil, fmt.Errorf("error getting file: %w", err)
+		return nil, fmt.Errorf("error beginning transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	q := db.New(tx)
+	if _, err := q.GetFile(ctx, uuid); err != nil {
+		return nil, fmt.Errorf("error getting file: %w", err)
+	}
+	if upd.Name != nil {
+		err = q.UpdateFileName(ctx, db.UpdateFileNameParams{Name: *upd.Name, Uuid: uuid})
+		if err != nil {
+			return nil, fmt.Errorf("error renaming file: %w", err)
 		}
 	}
+	if upd.Notes != nil {
+		err = q.UpdateFileNotes(ctx, db.UpdateFileNotesParams{Notes: *upd.Notes, Uuid: uuid})
+		if err != nil {
+			return nil, fmt.Errorf("error updating file notes: %w", err)
+		}
+	}
+	if upd.Expires != nil {
+		params := db.UpdateFileExpiresParams{Uuid: uuid}
+		if !upd.Expires.IsZero() {
+			params.Expires = expiresAt(*upd.Expires)
+		}
+		if err = q.UpdateFileExpires(ctx, params); err != nil {
+			return nil, fmt.Errorf("error updating file expiry: %w", err)
+		}
+	}
+	row, err := q.GetFile(ctx, uuid)
+	if err != nil {
+		return nil, fmt.Errorf("error getting file: %w", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("error committing transaction: %w", err)
+	}
 
-	uuid := ""
+	f := &File{}
+	f.fromDb(&row)
+	return f, nil
+}
+
+// DeleteFile moves a file to the trash, taking its permalinks out of
+// service with it, until it is restored or the janitor purges it.
+func (r *Repo) DeleteFile(ctx context.Context, uuid string) error {
+	q := db.New(r.db)
+	n, err := q.TrashFile(ctx, uuid)
+	if err != nil {
+		return fmt.Errorf("error deleting file: %w", err)
+	}
+	if n == 0 {
+		return fmt.Errorf("error deleting file: %w", sql.ErrNoRows)
+	}
+	r.logger.Infow("moved file to trash", "uuid", uuid)
+	return nil
+}
+
+// RestoreFile takes a file back out of the trash.
+func (r *Repo) RestoreFile(ctx context.Context, uuid string) (*File, error) {
+	q := db.New(r.db)
+	row, err := q.RestoreFile(ctx, uuid)
+	if err != nil {
+		return nil, fmt.Errorf("error restoring file: %w", err)
+	}
+	f := &File{}
+	f.fromDb(&row)
+	r.logger.Infow("restored file from trash", "uuid", uuid)
+	return f, nil
+}
+
+// PurgeFile removes a file and its permalinks for good, whether or
+// not it is in the trash, and its blob if no other file or picture
+// shares it.
+func (r *Repo) PurgeFile(ctx context.Context, uuid string) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("error beginning transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	// permalinks go with the row through ON DELETE CASCADE
+	q := db.New(tx)
+	row, err := q.DeleteFile(ctx, uuid)
+	if err != nil {
+		return fmt.Errorf("error deleting file: %w", err)
+	}
+	err = q.AddStorageUsage(ctx, db.AddStorageUsageParams{Area: areaDrive, Bytes: -row.Size})
+	if err != nil {
+		return fmt.Errorf("error updating storage usage: %w", err)
+	}
+	key, err := releaseBlob(ctx, q, row.Sha256, row.Url)
+	if err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("error committing transaction: %w", err)
+	}
+
+	return r.deleteReleasedBlob(ctx, key)
+}
+
+// DeleteExpiredFiles purges every file whose TTL has passed, skipping
+// the trash, returning how many were removed.
+func (r *Repo) DeleteExpiredFiles(ctx context.Context) (int, error) {
+	q := db.New(r.db)
+	rows, err := q.GetExpiredFiles(ctx, expiresAt(time.Now()))
+	if err != nil {
+		return 0, fmt.Errorf("error getting expired files: %w", err)
+	}
+	n := 0
+	for _, row := range rows {
+		if err := r.PurgeFile(ctx, row.Uuid); err != nil {
+			return n, err
+		}
+		r.logger.Infow("deleted expired file", "uuid", row.Uuid, "expires", row.Expires.Time)
+		n++
+	}
+	return n, nil
+}
+
+// RunFileReaper deletes expired files every interval until ctx is done.
+func (r *Repo) RunFileReaper(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
 	for {
-		bytes := make([]byte, 3)
-		_, err = rand.Read(bytes)
-		if err != nil {
-			return nil, fmt.Errorf("error generating uuid: %w", err)
+		if _, err := r.DeleteExpiredFiles(ctx); err != nil {
+			r.logger.Errorw("error deleting expired files", "error", err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
 		}
-		uuid = hex.EncodeToString(bytes)
-		_, err = r.GetPermalink(ctx, uuid)
+	}
+}
+
+// expiresAt normalizes t to UTC so stored expiries compare correctly
+// as text in sqlite.
+func expiresAt(t time.Time) sql.NullTime {
+	return sql.NullTime{Time: t.UTC(), Valid: true}
+}
+
+func (r *Repo) InsertPermalink(ctx context.Context, fileUuid string, durationSeconds int64, opts PermalinkOptions) (*Permalink, error) {
+	if durationSeconds <= 0 {
+		return nil, errorf(ErrInvalidInput, "invalid duration: must be positive")
+	}
+	if opts.MaxDownloads < 0 {
+		return nil, errorf(ErrInvalidInput, "invalid max downloads")
+	}
+
+	_, err := r.GetFile(ctx, fileUuid)
+	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
-			break
+			return nil, errorf(ErrNotFound, "file not found")
+		} else {
+			return nil, fmt.Errorf("error getting file: %w", err)
 		}
-		r.logger.Warnw("uuid collision", "uuid", uuid)
 	}
 
 	params := db.InsertPermalinkParams{
-		Uuid:            uuid,
 		FileUuid:        fileUuid,
 		DurationSeconds: durationSeconds,
-		Expires:         time.Now().Add(time.Duration(durationSeconds) * time.Second),
+		Expires:         time.Now().UTC().Add(time.Duration(durationSeconds) * time.Second),
+	}
+	if opts.MaxDownloads > 0 {
+		params.MaxDownloads = sql.NullInt64{Int64: opts.MaxDownloads, Valid: true}
 	}
+	if opts.Password != "" {
+		hash, err := bcrypt.GenerateFromPassword([]byte(opts.Password), bcrypt.DefaultCost)
+		if err != nil {
+			return nil, fmt.Errorf("error hashing password: %w", err)
+		}
+		params.PasswordHash = sql.NullString{String: string(hash), Valid: true}
+	}
+
+	// rely on the primary key to catch collisions rather than
+	// checking first, which would race with other inserts
 	q := db.New(r.db)
-	row, err := q.InsertPermalink(ctx, params)
-	if err != nil {
-		return nil, fmt.Errorf("error inserting permalink: %w", err)
+	var row db.Permalink
+	for attempt := 1; ; attempt++ {
+		params.Uuid, err = r.permalinkIds()
+		if err != nil {
+			return nil, err
+		}
+		row, err = q.InsertPermalink(ctx, params)
+		if err == nil {
+			break
+		}
+		if !isUniqueViolation(err) || attempt == maxPermalinkIdAttempts {
+			return nil, fmt.Errorf("error inserting permalink: %w", err)
+		}
+		r.logger.Warnw("permalink id collision", "uuid", params.Uuid)
 	}
-	return r.permalinkFromDb(&row)
+	return r.permalinkFromDb(ctx, &row)
 }
 
 func (r *Repo) GetPermalink(ctx context.Context, uuid string) (*Permalink, error) {
@@ -167,39 +500,51 @@ func (r *Repo) GetPermalink(ctx context.Context, uuid string) (*Permalink, error
 	if err != nil {
 		return nil, fmt.Errorf("error getting permalink: %w", err)
 	}
-	return r.permalinkFromDb(&row)
+	return r.permalinkFromDb(ctx, &row)
 }
 
-func (r *Repo) GetAllPermalinks(ctx context.Context) ([]Permalink, error) {
+// PermalinkFilter selects the permalinks to list.
+type PermalinkFilter struct {
+	ListOptions
+	FileUuid string
+}
+
+// ListPermalinks returns a page of permalinks matching f and the
+// cursor for the next page, which is empty on the last one.
+func (r *Repo) ListPermalinks(ctx context.Context, f PermalinkFilter) ([]Permalink, string, error) {
+	b, err := f.bounds()
+	if err != nil {
+		return nil, "", err
+	}
+	params := db.ListPermalinksDescParams{
+		CursorPit:  b.cursorPit,
+		CursorUuid: b.cursorKey,
+		Since:      b.since,
+		Until:      b.until,
+		FileUuid:   nullIfEmpty(f.FileUuid),
+		Limit:      b.limit,
+	}
+
 	q := db.New(r.db)
-	rows, err := q.GetAllPermalinks(ctx)
+	var rows []db.Permalink
+	if f.Ascending {
+		rows, err = q.ListPermalinksAsc(ctx, db.ListPermalinksAscParams(params))
+	} else {
+		rows, err = q.ListPermalinksDesc(ctx, params)
+	}
 	if err != nil {
-		return nil, fmt.Errorf("error getting permalinks: %w", err)
+		return nil, "", fmt.Errorf("error listing permalinks: %w", err)
 	}
+	rows, next := page(rows, b, func(p db.Permalink) (time.Time, string) {
+		return p.Pit, p.Uuid
+	})
 	permalinks := make([]Permalink, len(rows))
 	for i, row := range rows {
-		p, err := r.permalinkFromDb(&row)
+		p, err := r.permalinkFromDb(ctx, &row)
 		if err != nil {
-			return nil, fmt.Errorf("error getting permalink: %w", err)
+			return nil, "", fmt.Errorf("error getting permalink: %w", err)
 		}
 		permalinks[i] = *p
 	}
-	return permalinks, nil
-}
-
-// TODO use external file server
-func (r *Repo) getFileUrl(ctx context.Context, uuid string, file multipart.File,
-	header *multipart.FileHeader) (string, error) {
-	ext := filepath.Ext(header.Filename)
-	path := filepath.Join(r.varDir, driveUploadDir, uuid+ext)
-	newFile, err := os.Create(path)
-	if err != nil {
-		return "", fmt.Errorf("error creating file: %w", err)
-	}
-	defer newFile.Close()
-	_, err = io.Copy(newFile, file)
-	if err != nil {
-		return "", fmt.Errorf("error copying file content: %w", err)
-	}
-	return path, nil
+	return permalinks, next, nil
 }
@@ -0,0 +1,104 @@
+package repo
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/btschwartz12/site/internal/storage"
+)
+
+func TestPurgeFile_CascadesPermalinks(t *testing.T) {
+	ctx := context.Background()
+	r := newTestRepo(t)
+
+	file, header := newTestUpload(t, "a.txt", "hello")
+	f, err := r.InsertFile(ctx, file, header, "notes", "1.2.3.4", 0)
+	assert.NoError(t, err)
+	p, err := r.InsertPermalink(ctx, f.Uuid.String(), 60, PermalinkOptions{})
+	assert.NoError(t, err)
+	// a permalink that would already be expired is refused
+	_, err = r.InsertPermalink(ctx, f.Uuid.String(), -60, PermalinkOptions{})
+	assert.ErrorIs(t, err, ErrInvalidInput)
+
+	assert.NoError(t, r.PurgeFile(ctx, f.Uuid.String()))
+
+	_, err = r.GetPermalink(ctx, p.Uuid)
+	assert.ErrorIs(t, err, sql.ErrNoRows)
+	_, err = r.blobs.Stat(ctx, f.Url)
+	assert.ErrorIs(t, err, storage.ErrNotFound)
+	assert.Equal(t, int64(0), areaBytes(t, r, areaDrive))
+
+	err = r.PurgeFile(ctx, f.Uuid.String())
+	assert.ErrorIs(t, err, sql.ErrNoRows)
+}
+
+func TestUpdateFile(t *testing.T) {
+	ctx := context.Background()
+	r := newTestRepo(t)
+
+	file, header := newTestUpload(t, "a.txt", "hello")
+	f, err := r.InsertFile(ctx, file, header, "notes", "1.2.3.4", time.Hour)
+	assert.NoError(t, err)
+	assert.Equal(t, "a.txt", f.Name)
+	assert.NotNil(t, f.Expires)
+
+	name, notes := "b.txt", "new notes"
+	f, err = r.UpdateFile(ctx, f.Uuid.String(), FileUpdate{Name: &name, Notes: &notes, Expires: &time.Time{}})
+	assert.NoError(t, err)
+	assert.Equal(t, name, f.Name)
+	assert.Equal(t, notes, f.Notes)
+	assert.Nil(t, f.Expires)
+}
+
+func TestDeleteExpiredFiles(t *testing.T) {
+	ctx := context.Background()
+	r := newTestRepo(t)
+
+	file, header := newTestUpload(t, "a.txt", "hello")
+	keep, err := r.InsertFile(ctx, file, header, "notes", "1.2.3.4", time.Hour)
+	assert.NoError(t, err)
+	file, header = newTestUpload(t, "b.txt", "bye")
+	gone, err := r.InsertFile(ctx, file, header, "notes", "1.2.3.4", time.Hour)
+	assert.NoError(t, err)
+
+	past := time.Now().Add(-time.Second)
+	_, err = r.UpdateFile(ctx, gone.Uuid.String(), FileUpdate{Expires: &past})
+	assert.NoError(t, err)
+
+	n, err := r.DeleteExpiredFiles(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, n)
+
+	_, err = r.GetFile(ctx, keep.Uuid.String())
+	assert.NoError(t, err)
+	_, err = r.GetFile(ctx, gone.Uuid.String())
+	assert.ErrorIs(t, err, sql.ErrNoRows)
+}
+
+func TestInsertFile_Metadata(t *testing.T) {
+	ctx := context.Background()
+	r := newTestRepo(t)
+
+	file, header := newTestUpload(t, "page.html", "<html><body>hi</body></html>")
+	f, err := r.InsertFile(ctx, file, header, "notes", "1.2.3.4", 0)
+	assert.NoError(t, err)
+	assert.Equal(t, "page.html", f.Name)
+	assert.Equal(t, "text/html; charset=utf-8", f.ContentType)
+	sum := sha256.Sum256([]byte("<html><body>hi</body></html>"))
+	assert.Equal(t, hex.EncodeToString(sum[:]), f.Sha256)
+
+	// rows from before metadata was recorded get backfilled
+	_, err = r.db.Exec("UPDATE files SET sha256 = '', content_type = ''")
+	assert.NoError(t, err)
+	assert.NoError(t, r.backfillBlobMetadata(ctx))
+	got, err := r.GetFile(ctx, f.Uuid.String())
+	assert.NoError(t, err)
+	assert.Equal(t, f.Sha256, got.Sha256)
+	assert.Equal(t, f.ContentType, got.ContentType)
+}
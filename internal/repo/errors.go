@@ -0,0 +1,50 @@
+package repo
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/btschwartz12/site/internal/images"
+)
+
+// Errors returned by the repo, to be matched with errors.Is. They are
+// usually wrapped with a message saying what exactly went wrong.
+var (
+	// ErrNotFound is sql.ErrNoRows, so errors straight from a query
+	// match it too.
+	ErrNotFound         = sql.ErrNoRows
+	ErrInvalidID        = errors.New("invalid id")
+	ErrInvalidInput     = errors.New("invalid input")
+	ErrInvalidCursor    = errors.New("invalid cursor")
+	ErrInvalidExtension = errors.New("invalid extension")
+	ErrInvalidImage     = images.ErrInvalid
+	ErrTooLarge         = errors.New("file too large")
+	ErrStorageFull      = errors.New("storage full")
+	ErrAlreadyExists    = errors.New("already exists")
+	ErrOffsetMismatch   = errors.New("upload offset mismatch")
+	ErrUploadIncomplete = errors.New("upload incomplete")
+	ErrTooManyUploads   = errors.New("too many open uploads")
+	ErrPasswordRequired = errors.New("password required")
+	ErrInvalidPassword  = errors.New("invalid password")
+	// ErrUnavailable means a permalink exists but has been revoked,
+	// has expired or has run out of downloads.
+	ErrUnavailable  = errors.New("permalink unavailable")
+	ErrInvalidToken = errors.New("invalid token")
+)
+
+// repoError is an error with its own message that still matches one of
+// the sentinels above.
+type repoError struct {
+	kind error
+	msg  string
+}
+
+func (e *repoError) Error() string { return e.msg }
+func (e *repoError) Unwrap() error { return e.kind }
+
+// errorf formats an error of the given kind. Unlike wrapping with %w,
+// the message doesn't have to start with the sentinel's own text.
+func errorf(kind error, format string, args ...any) error {
+	return &repoError{kind: kind, msg: fmt.Sprintf(format, args...)}
+}
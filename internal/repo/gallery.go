@@ -0,0 +1,109 @@
+package repo
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"math/rand"
+	"time"
+
+	"github.com/btschwartz12/site/internal/repo/db"
+)
+
+const (
+	GalleryPageSize = 24
+
+	// shuffleModulus is the prime the shuffled order permutes ids
+	// modulo, small enough that the arithmetic never overflows.
+	shuffleModulus = 2147483647
+	topWindow      = 7 * 24 * time.Hour
+)
+
+// Orders the gallery can show pictures in.
+const (
+	OrderNewest   = "dsc"
+	OrderOldest   = "asc"
+	OrderLikes    = "likes"
+	OrderDislikes = "dislikes"
+	OrderRandom   = "random"
+	OrderTopWeek  = "week"
+)
+
+// GalleryOptions selects a page of the gallery.
+type GalleryOptions struct {
+	Order string
+	// Seed picks the order when Order is OrderRandom, so every page
+	// of the same shuffle is consistent.
+	Seed int64
+	// Page counts from zero.
+	Page int
+	// AlbumID and Tag, when set, narrow the gallery to the pictures
+	// in that album and with that tag.
+	AlbumID int64
+	Tag     string
+}
+
+// GetGalleryPictures returns a page of pictures in the given order,
+// and whether there are more after it.
+func (r *Repo) GetGalleryPictures(ctx context.Context, opts GalleryOptions) ([]Picture, bool, error) {
+	if opts.Page < 0 {
+		return nil, false, errorf(ErrInvalidInput, "invalid page")
+	}
+	// fetch one more than a page to tell whether there is another
+	limit := int64(GalleryPageSize + 1)
+	offset := int64(opts.Page) * GalleryPageSize
+	albumID := sql.NullInt64{Int64: opts.AlbumID, Valid: opts.AlbumID != 0}
+	tag := nullIfEmpty(opts.Tag)
+
+	q := db.New(r.db)
+	var rows []db.Picture
+	var err error
+	switch opts.Order {
+	case OrderNewest:
+		rows, err = q.GetGalleryPicturesNewest(ctx, db.GetGalleryPicturesNewestParams{AlbumID: albumID, Tag: tag, Limit: limit, Offset: offset})
+	case OrderOldest:
+		rows, err = q.GetGalleryPicturesOldest(ctx, db.GetGalleryPicturesOldestParams{AlbumID: albumID, Tag: tag, Limit: limit, Offset: offset})
+	case OrderLikes:
+		rows, err = q.GetGalleryPicturesByLikes(ctx, db.GetGalleryPicturesByLikesParams{AlbumID: albumID, Tag: tag, Limit: limit, Offset: offset})
+	case OrderDislikes:
+		rows, err = q.GetGalleryPicturesByDislikes(ctx, db.GetGalleryPicturesByDislikesParams{AlbumID: albumID, Tag: tag, Limit: limit, Offset: offset})
+	case OrderRandom:
+		// id*multiplier+increment mod a prime is a permutation of the
+		// ids, and a different one for each seed
+		rng := rand.New(rand.NewSource(opts.Seed))
+		rows, err = q.GetGalleryPicturesShuffled(ctx, db.GetGalleryPicturesShuffledParams{
+			AlbumID:    albumID,
+			Tag:        tag,
+			Multiplier: 1 + rng.Int63n(shuffleModulus-1),
+			Increment:  rng.Int63n(shuffleModulus),
+			Limit:      limit,
+			Offset:     offset,
+		})
+	case OrderTopWeek:
+		rows, err = q.GetGalleryPicturesByScoreSince(ctx, db.GetGalleryPicturesByScoreSinceParams{
+			AlbumID: albumID,
+			Tag:     tag,
+			Since:   time.Now().UTC().Add(-topWindow).Format(pitLayout),
+			Limit:   limit,
+			Offset:  offset,
+		})
+	default:
+		return nil, false, errorf(ErrInvalidInput, "invalid order: %s", opts.Order)
+	}
+	if err != nil {
+		return nil, false, fmt.Errorf("error getting gallery pictures: %w", err)
+	}
+
+	more := len(rows) > GalleryPageSize
+	if more {
+		rows = rows[:GalleryPageSize]
+	}
+	pictures := make([]Picture, len(rows))
+	for i, row := range rows {
+		pictures[i].fromDb(&row)
+		if pictures[i].Variants, err = pictureVariants(ctx, q, row.ID); err != nil {
+			return nil, false, err
+		}
+	}
+	return pictures, more, nil
+}
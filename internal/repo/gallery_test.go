@@ -0,0 +1,68 @@
+package repo
+
+import (
+	"context"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetGalleryPictures(t *testing.T) {
+	ctx := context.Background()
+	r := newTestRepo(t)
+	r.autoApprove = newAutoApproveRules("", "1.2.3.4")
+
+	var ids []int64
+	for i := 0; i < GalleryPageSize+6; i++ {
+		file, header := newTestUpload(t, "a.png", testPng(t, uint8(i)))
+		p, err := r.InsertPicture(ctx, file, header, "author", "desc", "1.2.3.4")
+		assert.NoError(t, err)
+		ids = append(ids, p.ID)
+	}
+
+	// pages of a shuffle cover every picture once, in the same order
+	// each time for the same seed
+	shuffle := func(seed int64) []int64 {
+		var got []int64
+		for page := 0; ; page++ {
+			pictures, more, err := r.GetGalleryPictures(ctx, GalleryOptions{Order: OrderRandom, Seed: seed, Page: page})
+			assert.NoError(t, err)
+			for _, p := range pictures {
+				got = append(got, p.ID)
+			}
+			if !more {
+				return got
+			}
+		}
+	}
+	a := shuffle(1)
+	assert.ElementsMatch(t, ids, a)
+	assert.Equal(t, a, shuffle(1))
+	assert.NotEqual(t, a, shuffle(2))
+
+	// old votes count towards likes but not this week's top
+	vote := func(id int64, voter string, vote int64) {
+		_, err := r.VotePicture(ctx, strconv.FormatInt(id, 10), Voter{ID: voter, IpHash: voter}, vote)
+		assert.NoError(t, err)
+	}
+	vote(ids[1], "a", 1)
+	vote(ids[1], "b", 1)
+	_, err := r.db.ExecContext(ctx, "UPDATE picture_votes SET pit = '2000-01-01 00:00:00'")
+	assert.NoError(t, err)
+	vote(ids[2], "a", 1)
+	vote(ids[3], "a", -1)
+
+	pictures, more, err := r.GetGalleryPictures(ctx, GalleryOptions{Order: OrderLikes})
+	assert.NoError(t, err)
+	assert.True(t, more)
+	assert.Len(t, pictures, GalleryPageSize)
+	assert.Equal(t, ids[1], pictures[0].ID)
+
+	pictures, _, err = r.GetGalleryPictures(ctx, GalleryOptions{Order: OrderTopWeek, Page: 1})
+	assert.NoError(t, err)
+	assert.Equal(t, ids[3], pictures[len(pictures)-1].ID)
+	pictures, _, err = r.GetGalleryPictures(ctx, GalleryOptions{Order: OrderTopWeek})
+	assert.NoError(t, err)
+	assert.Equal(t, ids[2], pictures[0].ID)
+}
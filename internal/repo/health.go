@@ -0,0 +1,64 @@
+package repo
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/btschwartz12/site/internal/repo/db"
+	"github.com/btschwartz12/site/internal/storage"
+)
+
+// Ping checks that the database can be queried.
+func (r *Repo) Ping(ctx context.Context) error {
+	if _, err := r.db.ExecContext(ctx, "SELECT 1"); err != nil {
+		return fmt.Errorf("error querying database: %w", err)
+	}
+	return nil
+}
+
+// CheckVarDir checks that files can still be written to the var dir,
+// where the database and blobs live.
+func (r *Repo) CheckVarDir(ctx context.Context) error {
+	f, err := os.CreateTemp(r.varDir, ".readyz-*")
+	if err != nil {
+		return fmt.Errorf("error creating file in var dir: %w", err)
+	}
+	defer os.Remove(f.Name())
+	if _, err := f.WriteString("ok"); err != nil {
+		f.Close()
+		return fmt.Errorf("error writing to var dir: %w", err)
+	}
+	if err := f.Close(); err != nil {
+		return fmt.Errorf("error writing to var dir: %w", err)
+	}
+	return nil
+}
+
+// CheckBlobs checks that the blob store can be reached.
+func (r *Repo) CheckBlobs(ctx context.Context) error {
+	_, err := r.blobs.Stat(ctx, ".readyz")
+	if err != nil && !errors.Is(err, storage.ErrNotFound) {
+		return fmt.Errorf("error reaching blob store: %w", err)
+	}
+	return nil
+}
+
+// CheckStorage fails once storage is full, when no more files or
+// pictures can be uploaded.
+func (r *Repo) CheckStorage(ctx context.Context) error {
+	q := db.New(r.db)
+	areas, err := q.GetStorageUsage(ctx)
+	if err != nil {
+		return fmt.Errorf("error getting storage usage: %w", err)
+	}
+	var total int64
+	for _, a := range areas {
+		total += a.Bytes
+	}
+	if total >= maxStorageSize {
+		return errorf(ErrStorageFull, "storage full (%d of %d MB used)", total>>20, maxStorageSize>>20)
+	}
+	return nil
+}
@@ -0,0 +1,23 @@
+package repo
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReadinessChecks(t *testing.T) {
+	ctx := context.Background()
+	r := newTestRepo(t)
+
+	assert.NoError(t, r.Ping(ctx))
+	assert.NoError(t, r.CheckVarDir(ctx))
+	assert.NoError(t, r.CheckBlobs(ctx))
+	assert.NoError(t, r.CheckStorage(ctx))
+
+	_, err := r.db.ExecContext(ctx, "UPDATE storage_usage SET bytes = ? WHERE area = ?", maxStorageSize, areaDrive)
+	assert.NoError(t, err)
+	assert.NoError(t, r.CheckBlobs(ctx))
+	assert.ErrorIs(t, r.CheckStorage(ctx), ErrStorageFull)
+}
@@ -0,0 +1,314 @@
+package repo
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/btschwartz12/site/internal/repo/db"
+	"github.com/btschwartz12/site/internal/storage"
+)
+
+// blobGracePeriod keeps the janitor away from blobs that were just
+// stored, since uploads write the blob before inserting the row.
+const blobGracePeriod = time.Hour
+
+type JanitorReport struct {
+	DryRun   bool
+	Started  time.Time
+	Finished time.Time
+	// ExpiredPermalinks are permalinks past their expiry.
+	ExpiredPermalinks []string
+	// OrphanedBlobs are stored blobs with no row pointing at them.
+	OrphanedBlobs []string
+	// MissingFileBlobs and MissingPictureBlobs are rows whose blob
+	// has vanished.
+	MissingFileBlobs    []string
+	MissingPictureBlobs []int64
+	// AbandonedUploads are resumable uploads that stopped receiving
+	// chunks.
+	AbandonedUploads []string
+	// TrashedFiles and TrashedPictures were in the trash for longer
+	// than it keeps them.
+	TrashedFiles    []string
+	TrashedPictures []int64
+}
+
+// RunJanitor cleans up every interval until ctx is done.
+func (r *Repo) RunJanitor(ctx context.Context, interval time.Duration, dryRun bool) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		if _, err := r.Janitor(ctx, dryRun); err != nil {
+			r.logger.Errorw("error running janitor", "error", err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// LastJanitorReport returns the report from the most recent janitor
+// run, or nil if it hasn't run yet.
+func (r *Repo) LastJanitorReport() *JanitorReport {
+	r.janitorMu.Lock()
+	defer r.janitorMu.Unlock()
+	return r.lastJanitorReport
+}
+
+// Janitor purges expired permalinks, abandoned uploads, the trash
+// past its retention, orphaned blobs and rows whose blobs are gone. With dryRun set it only reports what it would do.
+func (r *Repo) Janitor(ctx context.Context, dryRun bool) (*JanitorReport, error) {
+	report := &JanitorReport{
+		DryRun:              dryRun,
+		Started:             time.Now(),
+		ExpiredPermalinks:   []string{},
+		OrphanedBlobs:       []string{},
+		MissingFileBlobs:    []string{},
+		MissingPictureBlobs: []int64{},
+		AbandonedUploads:    []string{},
+		TrashedFiles:        []string{},
+		TrashedPictures:     []int64{},
+	}
+
+	if err := r.purgeExpiredPermalinks(ctx, report); err != nil {
+		return nil, err
+	}
+	if err := r.purgeAbandonedUploads(ctx, report); err != nil {
+		return nil, err
+	}
+	if err := r.purgeTrash(ctx, report); err != nil {
+		return nil, err
+	}
+	if err := r.purgeOrphanedBlobs(ctx, report); err != nil {
+		return nil, err
+	}
+	if err := r.purgeMissingBlobs(ctx, report); err != nil {
+		return nil, err
+	}
+	report.Finished = time.Now()
+
+	r.logger.Infow("janitor finished",
+		"dry_run", dryRun,
+		"expired_permalinks", len(report.ExpiredPermalinks),
+		"orphaned_blobs", len(report.OrphanedBlobs),
+		"missing_file_blobs", len(report.MissingFileBlobs),
+		"missing_picture_blobs", len(report.MissingPictureBlobs),
+		"abandoned_uploads", len(report.AbandonedUploads),
+		"trashed_files", len(report.TrashedFiles),
+		"trashed_pictures", len(report.TrashedPictures),
+		"duration", report.Finished.Sub(report.Started),
+	)
+
+	r.janitorMu.Lock()
+	r.lastJanitorReport = report
+	r.janitorMu.Unlock()
+	return report, nil
+}
+
+func (r *Repo) purgeExpiredPermalinks(ctx context.Context, report *JanitorReport) error {
+	q := db.New(r.db)
+	now := time.Now().UTC()
+	var uuids []string
+	var err error
+	if report.DryRun {
+		uuids, err = q.GetExpiredPermalinks(ctx, now)
+	} else {
+		uuids, err = q.DeleteExpiredPermalinks(ctx, now)
+	}
+	if err != nil {
+		return fmt.Errorf("error purging expired permalinks: %w", err)
+	}
+	report.ExpiredPermalinks = append(report.ExpiredPermalinks, uuids...)
+	return nil
+}
+
+func (r *Repo) purgeOrphanedBlobs(ctx context.Context, report *JanitorReport) error {
+	q := db.New(r.db)
+	known := map[string]bool{}
+	blobs, err := q.GetAllBlobs(ctx)
+	if err != nil {
+		return fmt.Errorf("error getting blobs: %w", err)
+	}
+	for _, b := range blobs {
+		known[b.Key] = true
+	}
+	files, err := q.GetAllFiles(ctx)
+	if err != nil {
+		return fmt.Errorf("error getting files: %w", err)
+	}
+	for _, f := range files {
+		known[f.Url] = true
+	}
+	pictures, err := q.GetAllPictures(ctx)
+	if err != nil {
+		return fmt.Errorf("error getting pictures: %w", err)
+	}
+	for _, p := range pictures {
+		known[p.Url] = true
+	}
+
+	// upload parts belong to whichever upload is named in their key
+	uploads := map[string]bool{}
+	isKnown := func(key string) (bool, error) {
+		id, ok := strings.CutPrefix(path.Dir(key), uploadPartDir+"/")
+		if !ok {
+			return known[key], nil
+		}
+		if _, seen := uploads[id]; !seen {
+			_, err := q.GetUpload(ctx, id)
+			if err != nil && !errors.Is(err, sql.ErrNoRows) {
+				return false, fmt.Errorf("error getting upload: %w", err)
+			}
+			uploads[id] = err == nil
+		}
+		return uploads[id], nil
+	}
+
+	cutoff := time.Now().Add(-blobGracePeriod)
+	for _, dir := range []string{blobDir, driveUploadDir, pictureUploadDir, uploadPartDir} {
+		blobs, err := r.blobs.List(ctx, dir+"/")
+		if err != nil {
+			return fmt.Errorf("error listing %s blobs: %w", dir, err)
+		}
+		for _, b := range blobs {
+			ok, err := isKnown(b.Key)
+			if err != nil {
+				return err
+			}
+			if ok || b.ModTime.After(cutoff) {
+				continue
+			}
+			report.OrphanedBlobs = append(report.OrphanedBlobs, b.Key)
+			if report.DryRun {
+				continue
+			}
+			if err := r.blobs.Delete(ctx, b.Key); err != nil {
+				return fmt.Errorf("error deleting orphaned blob: %w", err)
+			}
+			r.logger.Infow("deleted orphaned blob", "key", b.Key, "size", b.Size)
+		}
+	}
+	return nil
+}
+
+func (r *Repo) purgeAbandonedUploads(ctx context.Context, report *JanitorReport) error {
+	q := db.New(r.db)
+	uploads, err := q.GetStaleUploads(ctx, time.Now().UTC().Add(-uploadIdleTimeout))
+	if err != nil {
+		return fmt.Errorf("error getting stale uploads: %w", err)
+	}
+	for _, u := range uploads {
+		report.AbandonedUploads = append(report.AbandonedUploads, u.ID)
+		if report.DryRun {
+			continue
+		}
+		if err := r.deleteUpload(ctx, u.ID); err != nil {
+			return err
+		}
+		r.logger.Infow("deleted abandoned upload", "id", u.ID, "received", u.Received, "size", u.Size)
+	}
+	return nil
+}
+
+func (r *Repo) purgeTrash(ctx context.Context, report *JanitorReport) error {
+	q := db.New(r.db)
+	cutoff := time.Now().UTC().Add(-r.trashRetention).Format(pitLayout)
+
+	files, err := q.GetTrashedFilesBefore(ctx, cutoff)
+	if err != nil {
+		return fmt.Errorf("error getting trashed files: %w", err)
+	}
+	for _, uuid := range files {
+		report.TrashedFiles = append(report.TrashedFiles, uuid)
+		if report.DryRun {
+			continue
+		}
+		if err := r.PurgeFile(ctx, uuid); err != nil {
+			return err
+		}
+		r.logger.Infow("purged file from trash", "uuid", uuid)
+	}
+
+	pictures, err := q.GetTrashedPicturesBefore(ctx, cutoff)
+	if err != nil {
+		return fmt.Errorf("error getting trashed pictures: %w", err)
+	}
+	for _, id := range pictures {
+		report.TrashedPictures = append(report.TrashedPictures, id)
+		if report.DryRun {
+			continue
+		}
+		if err := r.PurgePicture(ctx, strconv.FormatInt(id, 10)); err != nil {
+			return err
+		}
+		r.logger.Infow("purged picture from trash", "id", id)
+	}
+	return nil
+}
+
+func (r *Repo) purgeMissingBlobs(ctx context.Context, report *JanitorReport) error {
+	q := db.New(r.db)
+	files, err := q.GetAllFiles(ctx)
+	if err != nil {
+		return fmt.Errorf("error getting files: %w", err)
+	}
+	for _, f := range files {
+		ok, err := r.blobExists(ctx, f.Url)
+		if err != nil {
+			return err
+		}
+		if ok {
+			continue
+		}
+		report.MissingFileBlobs = append(report.MissingFileBlobs, f.Uuid)
+		if report.DryRun {
+			continue
+		}
+		if err := r.PurgeFile(ctx, f.Uuid); err != nil {
+			return err
+		}
+		r.logger.Warnw("deleted file with missing blob", "uuid", f.Uuid, "url", f.Url)
+	}
+
+	pictures, err := q.GetAllPictures(ctx)
+	if err != nil {
+		return fmt.Errorf("error getting pictures: %w", err)
+	}
+	for _, p := range pictures {
+		ok, err := r.blobExists(ctx, p.Url)
+		if err != nil {
+			return err
+		}
+		if ok {
+			continue
+		}
+		report.MissingPictureBlobs = append(report.MissingPictureBlobs, p.ID)
+		if report.DryRun {
+			continue
+		}
+		if err := r.PurgePicture(ctx, strconv.FormatInt(p.ID, 10)); err != nil {
+			return err
+		}
+		r.logger.Warnw("deleted picture with missing blob", "id", p.ID, "url", p.Url)
+	}
+	return nil
+}
+
+func (r *Repo) blobExists(ctx context.Context, key string) (bool, error) {
+	_, err := r.blobs.Stat(ctx, key)
+	if err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			return false, nil
+		}
+		return false, fmt.Errorf("error getting blob info: %w", err)
+	}
+	return true, nil
+}
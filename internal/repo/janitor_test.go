@@ -0,0 +1,68 @@
+package repo
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestJanitor(t *testing.T) {
+	ctx := context.Background()
+	r := newTestRepo(t)
+
+	file, header := newTestUpload(t, "a.txt", "hello")
+	f, err := r.InsertFile(ctx, file, header, "notes", "1.2.3.4", 0)
+	assert.NoError(t, err)
+	expired, err := r.InsertPermalink(ctx, f.Uuid.String(), 60, PermalinkOptions{})
+	assert.NoError(t, err)
+	_, err = r.db.ExecContext(ctx, "UPDATE permalinks SET expires = ? WHERE uuid = ?", expiresAt(time.Now().Add(-time.Minute)), expired.Uuid)
+	assert.NoError(t, err)
+	live, err := r.InsertPermalink(ctx, f.Uuid.String(), 60, PermalinkOptions{})
+	assert.NoError(t, err)
+
+	file, header = newTestUpload(t, "b.png", testPng(t, 0))
+	p, err := r.InsertPicture(ctx, file, header, "author", "desc", "1.2.3.4")
+	assert.NoError(t, err)
+	assert.NoError(t, r.blobs.Delete(ctx, p.Url))
+
+	old := time.Now().Add(-2 * blobGracePeriod)
+	for _, key := range []string{"drive/orphan.txt", "pictures/fresh.png"} {
+		assert.NoError(t, r.blobs.Put(ctx, key, strings.NewReader("x"), 1))
+	}
+	assert.NoError(t, os.Chtimes(filepath.Join(r.varDir, "drive", "orphan.txt"), old, old))
+
+	report, err := r.Janitor(ctx, true)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{expired.Uuid}, report.ExpiredPermalinks)
+	assert.Equal(t, []string{"drive/orphan.txt"}, report.OrphanedBlobs)
+	assert.Empty(t, report.MissingFileBlobs)
+	assert.Equal(t, []int64{p.ID}, report.MissingPictureBlobs)
+	assert.Same(t, report, r.LastJanitorReport())
+
+	// dry run leaves everything in place
+	_, err = r.GetPermalink(ctx, expired.Uuid)
+	assert.NoError(t, err)
+	_, err = r.blobs.Stat(ctx, "drive/orphan.txt")
+	assert.NoError(t, err)
+
+	report, err = r.Janitor(ctx, false)
+	assert.NoError(t, err)
+	assert.Len(t, report.ExpiredPermalinks, 1)
+
+	_, err = r.GetPermalink(ctx, expired.Uuid)
+	assert.Error(t, err)
+	_, err = r.GetPermalink(ctx, live.Uuid)
+	assert.NoError(t, err)
+	_, err = r.blobs.Stat(ctx, "drive/orphan.txt")
+	assert.Error(t, err)
+	_, err = r.blobs.Stat(ctx, "pictures/fresh.png")
+	assert.NoError(t, err)
+	pictures, err := r.GetAllPictures(ctx)
+	assert.NoError(t, err)
+	assert.Empty(t, pictures)
+}
@@ -0,0 +1,123 @@
+package repo
+
+import (
+	"database/sql"
+	"encoding/base64"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	DefaultPageSize = 50
+	MaxPageSize     = 500
+
+	// pitLayout is how CURRENT_TIMESTAMP writes pit columns, so
+	// times formatted with it compare correctly against them as text.
+	pitLayout = "2006-01-02 15:04:05"
+	maxPit    = "9999-12-31 23:59:59"
+)
+
+// ListOptions selects a page of a list, which is ordered newest first
+// unless Ascending is set.
+type ListOptions struct {
+	// Limit is the most items to return, DefaultPageSize if zero.
+	Limit int
+	// Cursor continues from the end of a previous page.
+	Cursor    string
+	Ascending bool
+	// Since and Until, if set, limit the list to items created at or
+	// after Since and before Until.
+	Since time.Time
+	Until time.Time
+}
+
+// listBounds are ListOptions ready to bind to a list query.
+type listBounds struct {
+	limit     int64
+	cursorPit string
+	cursorKey string
+	since     string
+	until     string
+}
+
+func (o ListOptions) bounds() (listBounds, error) {
+	b := listBounds{
+		limit: DefaultPageSize,
+		since: "",
+		until: maxPit,
+	}
+	if o.Limit < 0 || o.Limit > MaxPageSize {
+		return b, errorf(ErrInvalidInput, "invalid limit: must be between 1 and %d", MaxPageSize)
+	}
+	if o.Limit > 0 {
+		b.limit = int64(o.Limit)
+	}
+	if !o.Since.IsZero() {
+		b.since = o.Since.UTC().Format(pitLayout)
+	}
+	if !o.Until.IsZero() {
+		b.until = o.Until.UTC().Format(pitLayout)
+	}
+
+	// without a cursor, start from before the first item either way
+	b.cursorPit = maxPit
+	if o.Ascending {
+		b.cursorPit = ""
+	}
+	if o.Cursor != "" {
+		raw, err := base64.RawURLEncoding.DecodeString(o.Cursor)
+		if err != nil {
+			return b, ErrInvalidCursor
+		}
+		pit, key, ok := strings.Cut(string(raw), "|")
+		if !ok {
+			return b, ErrInvalidCursor
+		}
+		if _, err := time.Parse(pitLayout, pit); err != nil {
+			return b, ErrInvalidCursor
+		}
+		b.cursorPit, b.cursorKey = pit, key
+	}
+	// fetch one more than asked for to tell whether there is a next page
+	b.limit++
+	return b, nil
+}
+
+// cursorID is the cursor key of a list keyed by an integer id.
+func (b listBounds) cursorID() (int64, error) {
+	if b.cursorKey == "" {
+		return 0, nil
+	}
+	id, err := strconv.ParseInt(b.cursorKey, 10, 64)
+	if err != nil {
+		return 0, ErrInvalidCursor
+	}
+	return id, nil
+}
+
+// page trims the extra item fetched by a list query and returns the
+// cursor for the page after it, or "" if this is the last page.
+func page[T any](items []T, b listBounds, key func(T) (time.Time, string)) ([]T, string) {
+	if int64(len(items)) < b.limit {
+		return items, ""
+	}
+	items = items[:b.limit-1]
+	pit, k := key(items[len(items)-1])
+	cursor := base64.RawURLEncoding.EncodeToString([]byte(pit.UTC().Format(pitLayout) + "|" + k))
+	return items, cursor
+}
+
+// nullIfEmpty turns an unset filter into NULL, which list queries
+// treat as matching everything.
+func nullIfEmpty(s string) sql.NullString {
+	return sql.NullString{String: s, Valid: s != ""}
+}
+
+// normalizeExtension adds the leading dot extensions are stored with.
+func normalizeExtension(ext string) string {
+	if ext == "" || strings.HasPrefix(ext, ".") {
+		return ext
+	}
+	return "." + ext
+}
@@ -0,0 +1,80 @@
+package repo
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestListFiles_Pages(t *testing.T) {
+	ctx := context.Background()
+	r := newTestRepo(t)
+
+	// files created in the same second are ordered by uuid
+	var want []string
+	for i := 0; i < 5; i++ {
+		file, header := newTestUpload(t, fmt.Sprintf("%d.txt", i), fmt.Sprintf("content %d", i))
+		f, err := r.InsertFile(ctx, file, header, "notes", "1.2.3.4", 0)
+		assert.NoError(t, err)
+		want = append(want, f.Uuid.String())
+	}
+	file, header := newTestUpload(t, "a.png", testPng(t, 0))
+	_, err := r.InsertFile(ctx, file, header, "notes", "1.2.3.4", 0)
+	assert.NoError(t, err)
+
+	walk := func(ascending bool) []string {
+		var got []string
+		opts := FileFilter{ListOptions: ListOptions{Limit: 2, Ascending: ascending}, Extension: "txt"}
+		for {
+			files, next, err := r.ListFiles(ctx, opts)
+			assert.NoError(t, err)
+			assert.LessOrEqual(t, len(files), 2)
+			for _, f := range files {
+				got = append(got, f.Uuid.String())
+			}
+			if next == "" {
+				return got
+			}
+			opts.Cursor = next
+		}
+	}
+	asc := walk(true)
+	assert.ElementsMatch(t, want, asc)
+	desc := walk(false)
+	for i := range desc {
+		assert.Equal(t, asc[len(asc)-1-i], desc[i])
+	}
+
+	_, _, err = r.ListFiles(ctx, FileFilter{ListOptions: ListOptions{Cursor: "bogus"}})
+	assert.ErrorContains(t, err, "invalid cursor")
+}
+
+func TestListVisitors_DateRange(t *testing.T) {
+	ctx := context.Background()
+	r := newTestRepo(t)
+
+	for _, pit := range []string{"2024-01-01 00:00:00", "2024-02-01 00:00:00", "2024-03-01 00:00:00"} {
+		_, err := r.db.ExecContext(ctx, "INSERT INTO visitors (path, message, country, pit) VALUES ('/', '', 'US', ?)", pit)
+		assert.NoError(t, err)
+	}
+
+	visitors, next, err := r.ListVisitors(ctx, VisitorFilter{
+		ListOptions: ListOptions{
+			Since: time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC),
+			Until: time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC),
+		},
+		Country: "US",
+	})
+	assert.NoError(t, err)
+	assert.Empty(t, next)
+	if assert.Len(t, visitors, 1) {
+		assert.Equal(t, int64(2), visitors[0].ID)
+	}
+
+	visitors, _, err = r.ListVisitors(ctx, VisitorFilter{Country: "CA"})
+	assert.NoError(t, err)
+	assert.Empty(t, visitors)
+}
@@ -0,0 +1,66 @@
+package repo
+
+import (
+	"context"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/btschwartz12/site/internal/metrics"
+	"github.com/btschwartz12/site/internal/repo/db"
+)
+
+const collectTimeout = 5 * time.Second
+
+var (
+	storageBytesDesc = prometheus.NewDesc(
+		"site_storage_bytes",
+		"Bytes charged to each area, counting shared blobs once per reference.",
+		[]string{"area"}, nil,
+	)
+	storageLimitDesc = prometheus.NewDesc(
+		"site_storage_limit_bytes",
+		"Bytes that can be stored across all areas.",
+		nil, nil,
+	)
+)
+
+// usageCollector reads storage usage from the database whenever
+// metrics are scraped, so it is never stale.
+type usageCollector struct {
+	r *Repo
+}
+
+// UsageCollector returns a collector for the bytes stored per area.
+func (r *Repo) UsageCollector() prometheus.Collector {
+	return &usageCollector{r: r}
+}
+
+func (c *usageCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- storageBytesDesc
+	ch <- storageLimitDesc
+}
+
+func (c *usageCollector) Collect(ch chan<- prometheus.Metric) {
+	ctx, cancel := context.WithTimeout(context.Background(), collectTimeout)
+	defer cancel()
+
+	if err := c.r.refreshDatabaseUsage(ctx); err != nil {
+		c.r.logger.Errorw("error refreshing database usage", "error", err)
+	}
+	areas, err := db.New(c.r.db).GetStorageUsage(ctx)
+	if err != nil {
+		c.r.logger.Errorw("error getting storage usage", "error", err)
+		ch <- prometheus.NewInvalidMetric(storageBytesDesc, err)
+		return
+	}
+	for _, a := range areas {
+		ch <- prometheus.MustNewConstMetric(storageBytesDesc, prometheus.GaugeValue, float64(a.Bytes), a.Area)
+	}
+	ch <- prometheus.MustNewConstMetric(storageLimitDesc, prometheus.GaugeValue, maxStorageSize)
+}
+
+// observeUpload records the size of a successful upload.
+func observeUpload(area string, size int64) {
+	metrics.UploadSize.WithLabelValues(area).Observe(float64(size))
+}
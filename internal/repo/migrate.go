@@ -0,0 +1,266 @@
+package repo
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"io/fs"
+	"regexp"
+	"sort"
+	"strconv"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/btschwartz12/site/internal/repo/db"
+)
+
+const (
+	migrationsDir = "migrations"
+
+	createMigrationsTable = `CREATE TABLE IF NOT EXISTS schema_migrations (
+	version INTEGER PRIMARY KEY,
+	name TEXT NOT NULL,
+	applied_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP NOT NULL
+)`
+)
+
+var (
+	migrationFileRe = regexp.MustCompile(`^(\d+)_(\w+)\.(up|down)\.sql$`)
+)
+
+type migration struct {
+	version int64
+	name    string
+	up      string
+	down    string
+}
+
+// MigrationStatus describes a single known migration and
+// whether it has been applied to the database.
+type MigrationStatus struct {
+	Version   int64
+	Name      string
+	Applied   bool
+	AppliedAt time.Time
+}
+
+// Migrator applies the numbered migrations embedded in the db
+// package, recording each applied version in schema_migrations.
+type Migrator struct {
+	logger     *zap.SugaredLogger
+	db         *sql.DB
+	migrations []migration
+}
+
+func newMigrator(logger *zap.SugaredLogger, conn *sql.DB, fsys fs.FS) (*Migrator, error) {
+	migrations, err := loadMigrations(fsys)
+	if err != nil {
+		return nil, fmt.Errorf("error loading migrations: %w", err)
+	}
+
+	if _, err := conn.Exec(createMigrationsTable); err != nil {
+		return nil, fmt.Errorf("error creating migrations table: %w", err)
+	}
+
+	return &Migrator{
+		logger:     logger,
+		db:         conn,
+		migrations: migrations,
+	}, nil
+}
+
+// NewMigrator opens the database in varDir without applying
+// any migrations, for use by the migrate subcommand.
+func NewMigrator(logger *zap.SugaredLogger, varDir string) (*Migrator, error) {
+	conn, err := openDb(varDir)
+	if err != nil {
+		return nil, err
+	}
+
+	m, err := newMigrator(logger, conn, db.Migrations)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return m, nil
+}
+
+func (m *Migrator) Close() error {
+	return m.db.Close()
+}
+
+// Status returns every known migration in version order.
+func (m *Migrator) Status(ctx context.Context) ([]MigrationStatus, error) {
+	applied, err := m.applied(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	statuses := make([]MigrationStatus, 0, len(m.migrations))
+	for _, mig := range m.migrations {
+		s := MigrationStatus{
+			Version: mig.version,
+			Name:    mig.name,
+		}
+		if at, ok := applied[mig.version]; ok {
+			s.Applied = true
+			s.AppliedAt = at
+		}
+		statuses = append(statuses, s)
+	}
+	return statuses, nil
+}
+
+// Up applies all pending migrations, each in its own transaction,
+// and returns the number applied.
+func (m *Migrator) Up(ctx context.Context) (int, error) {
+	applied, err := m.applied(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	n := 0
+	for _, mig := range m.migrations {
+		if _, ok := applied[mig.version]; ok {
+			continue
+		}
+		m.logger.Infow("applying migration", "version", mig.version, "name", mig.name)
+		err := m.inTx(ctx, mig.up, func(tx *sql.Tx) error {
+			_, err := tx.ExecContext(ctx,
+				"INSERT INTO schema_migrations (version, name) VALUES (?, ?)",
+				mig.version, mig.name,
+			)
+			return err
+		})
+		if err != nil {
+			return n, fmt.Errorf("error applying migration %d_%s: %w", mig.version, mig.name, err)
+		}
+		n++
+	}
+	return n, nil
+}
+
+// Down rolls back the most recently applied migrations, up to
+// the given number of steps, and returns the number rolled back.
+func (m *Migrator) Down(ctx context.Context, steps int) (int, error) {
+	applied, err := m.applied(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	n := 0
+	for i := len(m.migrations) - 1; i >= 0 && n < steps; i-- {
+		mig := m.migrations[i]
+		if _, ok := applied[mig.version]; !ok {
+			continue
+		}
+		if mig.down == "" {
+			return n, fmt.Errorf("migration %d_%s has no down migration", mig.version, mig.name)
+		}
+		m.logger.Infow("rolling back migration", "version", mig.version, "name", mig.name)
+		err := m.inTx(ctx, mig.down, func(tx *sql.Tx) error {
+			_, err := tx.ExecContext(ctx,
+				"DELETE FROM schema_migrations WHERE version = ?",
+				mig.version,
+			)
+			return err
+		})
+		if err != nil {
+			return n, fmt.Errorf("error rolling back migration %d_%s: %w", mig.version, mig.name, err)
+		}
+		n++
+	}
+	return n, nil
+}
+
+func (m *Migrator) inTx(ctx context.Context, stmts string, record func(tx *sql.Tx) error) error {
+	tx, err := m.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("error beginning transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, stmts); err != nil {
+		return fmt.Errorf("error executing migration: %w", err)
+	}
+	if err := record(tx); err != nil {
+		return fmt.Errorf("error recording migration: %w", err)
+	}
+	return tx.Commit()
+}
+
+func (m *Migrator) applied(ctx context.Context) (map[int64]time.Time, error) {
+	rows, err := m.db.QueryContext(ctx, "SELECT version, applied_at FROM schema_migrations")
+	if err != nil {
+		return nil, fmt.Errorf("error getting applied migrations: %w", err)
+	}
+	defer rows.Close()
+
+	applied := make(map[int64]time.Time)
+	for rows.Next() {
+		var version int64
+		var at time.Time
+		if err := rows.Scan(&version, &at); err != nil {
+			return nil, fmt.Errorf("error scanning applied migration: %w", err)
+		}
+		applied[version] = at
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error getting applied migrations: %w", err)
+	}
+	return applied, nil
+}
+
+// loadMigrations reads NNNN_name.up.sql / NNNN_name.down.sql
+// pairs from the migrations directory of fsys.
+func loadMigrations(fsys fs.FS) ([]migration, error) {
+	entries, err := fs.ReadDir(fsys, migrationsDir)
+	if err != nil {
+		return nil, fmt.Errorf("error reading migrations dir: %w", err)
+	}
+
+	byVersion := make(map[int64]*migration)
+	for _, e := range entries {
+		matches := migrationFileRe.FindStringSubmatch(e.Name())
+		if matches == nil {
+			return nil, fmt.Errorf("invalid migration filename: %s", e.Name())
+		}
+		version, err := strconv.ParseInt(matches[1], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid migration version: %s", e.Name())
+		}
+
+		data, err := fs.ReadFile(fsys, migrationsDir+"/"+e.Name())
+		if err != nil {
+			return nil, fmt.Errorf("error reading migration %s: %w", e.Name(), err)
+		}
+
+		mig, ok := byVersion[version]
+		if !ok {
+			mig = &migration{version: version, name: matches[2]}
+			byVersion[version] = mig
+		} else if mig.name != matches[2] {
+			return nil, fmt.Errorf("conflicting names for migration %d", version)
+		}
+
+		switch matches[3] {
+		case "up":
+			mig.up = string(data)
+		case "down":
+			mig.down = string(data)
+		}
+	}
+
+	migrations := make([]migration, 0, len(byVersion))
+	for _, mig := range byVersion {
+		if mig.up == "" {
+			return nil, fmt.Errorf("migration %d_%s has no up migration", mig.version, mig.name)
+		}
+		migrations = append(migrations, *mig)
+	}
+	sort.Slice(migrations, func(i, j int) bool {
+		return migrations[i].version < migrations[j].version
+	})
+	return migrations, nil
+}
@@ -0,0 +1,115 @@
+package repo
+
+import (
+	"context"
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+
+	"github.com/btschwartz12/site/internal/repo/db"
+)
+
+func TestMigrator_UpDown(t *testing.T) {
+	ctx := context.Background()
+	conn, err := openDb(t.TempDir())
+	assert.NoError(t, err)
+	defer conn.Close()
+
+	fsys := fstest.MapFS{
+		"migrations/0001_first.up.sql":    {Data: []byte("CREATE TABLE a (id INTEGER);")},
+		"migrations/0001_first.down.sql":  {Data: []byte("DROP TABLE a;")},
+		"migrations/0002_second.up.sql":   {Data: []byte("CREATE TABLE b (id INTEGER);")},
+		"migrations/0002_second.down.sql": {Data: []byte("DROP TABLE b;")},
+	}
+	m, err := newMigrator(zap.NewNop().Sugar(), conn, fsys)
+	assert.NoError(t, err)
+
+	n, err := m.Up(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, n)
+
+	// re-running is a no-op
+	n, err = m.Up(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, 0, n)
+
+	n, err = m.Down(ctx, 1)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, n)
+
+	statuses, err := m.Status(ctx)
+	assert.NoError(t, err)
+	assert.Len(t, statuses, 2)
+	assert.True(t, statuses[0].Applied)
+	assert.False(t, statuses[1].Applied)
+
+	_, err = conn.Exec("SELECT * FROM a")
+	assert.NoError(t, err)
+	_, err = conn.Exec("SELECT * FROM b")
+	assert.Error(t, err)
+}
+
+func TestMigrator_FailedStepRollsBack(t *testing.T) {
+	ctx := context.Background()
+	conn, err := openDb(t.TempDir())
+	assert.NoError(t, err)
+	defer conn.Close()
+
+	fsys := fstest.MapFS{
+		"migrations/0001_broken.up.sql": {Data: []byte("CREATE TABLE a (id INTEGER); NOT SQL;")},
+	}
+	m, err := newMigrator(zap.NewNop().Sugar(), conn, fsys)
+	assert.NoError(t, err)
+
+	_, err = m.Up(ctx)
+	assert.Error(t, err)
+
+	_, err = conn.Exec("SELECT * FROM a")
+	assert.Error(t, err)
+
+	statuses, err := m.Status(ctx)
+	assert.NoError(t, err)
+	assert.False(t, statuses[0].Applied)
+}
+
+func TestLoadMigrations_Embedded(t *testing.T) {
+	migrations, err := loadMigrations(db.Migrations)
+	assert.NoError(t, err)
+	assert.NotEmpty(t, migrations)
+	for i, mig := range migrations {
+		assert.Equal(t, int64(i+1), mig.version)
+		assert.NotEmpty(t, mig.down)
+	}
+}
+
+func TestLoadMigrations_InvalidName(t *testing.T) {
+	fsys := fstest.MapFS{
+		"migrations/first.sql": {Data: []byte("SELECT 1;")},
+	}
+	_, err := loadMigrations(fsys)
+	assert.Error(t, err)
+}
+
+func TestMigrator_EmbeddedRoundTrip(t *testing.T) {
+	ctx := context.Background()
+	conn, err := openDb(t.TempDir())
+	assert.NoError(t, err)
+	defer conn.Close()
+
+	m, err := newMigrator(zap.NewNop().Sugar(), conn, db.Migrations)
+	assert.NoError(t, err)
+
+	n, err := m.Up(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, len(m.migrations), n)
+
+	n, err = m.Down(ctx, len(m.migrations))
+	assert.NoError(t, err)
+	assert.Equal(t, len(m.migrations), n)
+
+	n, err = m.Up(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, len(m.migrations), n)
+}
@@ -0,0 +1,86 @@
+package repo
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/btschwartz12/site/internal/repo/db"
+)
+
+// Moderation states of a picture. Only approved pictures are shown
+// in the gallery.
+const (
+	PicturePending  = "pending"
+	PictureApproved = "approved"
+	PictureRejected = "rejected"
+
+	autoModerator = "auto"
+)
+
+// autoApproveRules decide which uploads skip the moderation queue.
+type autoApproveRules struct {
+	authors   map[string]bool
+	uploaders map[string]bool
+}
+
+func newAutoApproveRules(authors, uploaders string) autoApproveRules {
+	rules := autoApproveRules{authors: map[string]bool{}, uploaders: map[string]bool{}}
+	for _, a := range strings.Split(authors, ",") {
+		if a = strings.TrimSpace(a); a != "" {
+			rules.authors[strings.ToLower(a)] = true
+		}
+	}
+	for _, u := range strings.Split(uploaders, ",") {
+		if u = strings.TrimSpace(u); u != "" {
+			rules.uploaders[u] = true
+		}
+	}
+	return rules
+}
+
+// reason returns why a picture is approved without moderation, or ""
+// if it has to wait for a moderator.
+func (a autoApproveRules) reason(author, uploader string) string {
+	if a.uploaders[uploader] {
+		return "trusted uploader"
+	}
+	if a.authors[strings.ToLower(strings.TrimSpace(author))] {
+		return "trusted author"
+	}
+	return ""
+}
+
+// ModeratePicture approves or rejects a picture, recording who did
+// it and why.
+func (r *Repo) ModeratePicture(ctx context.Context, idStr string, status string, reason string, moderator string) (*Picture, error) {
+	if status != PictureApproved && status != PictureRejected {
+		return nil, errorf(ErrInvalidInput, "invalid status: %s", status)
+	}
+	id, err := strconv.ParseInt(idStr, 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidID, err)
+	}
+
+	q := db.New(r.db)
+	row, err := q.ModeratePicture(ctx, db.ModeratePictureParams{
+		Status:           status,
+		ModerationReason: reason,
+		ModeratedBy:      moderator,
+		ModeratedAt:      sql.NullTime{Time: time.Now().UTC(), Valid: true},
+		ID:               id,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error moderating picture: %w", err)
+	}
+	p := Picture{}
+	p.fromDb(&row)
+	if p.Variants, err = pictureVariants(ctx, q, id); err != nil {
+		return nil, err
+	}
+	r.logger.Infow("moderated picture", "id", id, "status", status, "moderator", moderator)
+	return &p, nil
+}
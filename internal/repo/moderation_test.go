@@ -0,0 +1,65 @@
+package repo
+
+import (
+	"context"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestModeratePicture(t *testing.T) {
+	ctx := context.Background()
+	r := newTestRepo(t)
+	r.autoApprove = newAutoApproveRules(" Ben ,", "api:ci")
+
+	upload := func(seed uint8, author, uploader string) *Picture {
+		file, header := newTestUpload(t, "a.png", testPng(t, seed))
+		p, err := r.InsertPicture(ctx, file, header, author, "desc", uploader)
+		assert.NoError(t, err)
+		return p
+	}
+	pending := upload(0, "someone", "1.2.3.4")
+	assert.Equal(t, PicturePending, pending.Status)
+	byAuthor := upload(1, "ben", "1.2.3.4")
+	assert.Equal(t, PictureApproved, byAuthor.Status)
+	assert.Equal(t, autoModerator, byAuthor.ModeratedBy)
+	byUploader := upload(2, "someone", "api:ci")
+	assert.Equal(t, PictureApproved, byUploader.Status)
+
+	gallery := func() []int64 {
+		pictures, _, err := r.GetGalleryPictures(ctx, GalleryOptions{Order: OrderOldest})
+		assert.NoError(t, err)
+		var ids []int64
+		for _, p := range pictures {
+			ids = append(ids, p.ID)
+		}
+		return ids
+	}
+	assert.Equal(t, []int64{byAuthor.ID, byUploader.ID}, gallery())
+
+	queue, _, err := r.ListPictures(ctx, PictureFilter{Status: PicturePending})
+	assert.NoError(t, err)
+	if assert.Len(t, queue, 1) {
+		assert.Equal(t, pending.ID, queue[0].ID)
+	}
+
+	id := strconv.FormatInt(pending.ID, 10)
+	_, err = r.VotePicture(ctx, id, Voter{ID: "a", IpHash: "a"}, 1)
+	assert.ErrorContains(t, err, "no rows")
+
+	p, err := r.ModeratePicture(ctx, id, PictureApproved, "looks fine", "api:admin")
+	assert.NoError(t, err)
+	assert.Equal(t, PictureApproved, p.Status)
+	assert.Equal(t, "looks fine", p.ModerationReason)
+	assert.Equal(t, "api:admin", p.ModeratedBy)
+	assert.NotNil(t, p.ModeratedAt)
+	assert.Equal(t, []int64{pending.ID, byAuthor.ID, byUploader.ID}, gallery())
+
+	_, err = r.ModeratePicture(ctx, id, PictureRejected, "changed my mind", "api:admin")
+	assert.NoError(t, err)
+	assert.Equal(t, []int64{byAuthor.ID, byUploader.ID}, gallery())
+
+	_, err = r.ModeratePicture(ctx, id, PicturePending, "", "api:admin")
+	assert.ErrorContains(t, err, "invalid status")
+}
@@ -0,0 +1,92 @@
+package repo
+
+import (
+	"crypto/rand"
+	_ "embed"
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
+	"strings"
+
+	"modernc.org/sqlite"
+	sqlite3 "modernc.org/sqlite/lib"
+)
+
+const (
+	base62Alphabet = "0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz"
+	hexAlphabet    = "0123456789abcdef"
+
+	minPermalinkIdBits = 24
+	maxPermalinkIdBits = 512
+)
+
+//go:embed permalink_words.txt
+var permalinkWordsTxt string
+
+// permalinkWords has 256 entries, so each word carries 8 bits.
+var permalinkWords = strings.Fields(permalinkWordsTxt)
+
+// permalinkIdGenerator returns a new random permalink id.
+type permalinkIdGenerator func() (string, error)
+
+func newPermalinkIdGenerator(scheme string, bits int) (permalinkIdGenerator, error) {
+	if bits < minPermalinkIdBits || bits > maxPermalinkIdBits {
+		return nil, fmt.Errorf("permalink id bits must be between %d and %d", minPermalinkIdBits, maxPermalinkIdBits)
+	}
+
+	switch scheme {
+	case "base62":
+		return alphabetIdGenerator(base62Alphabet, bits), nil
+	case "hex":
+		return alphabetIdGenerator(hexAlphabet, bits), nil
+	case "words":
+		n := int(math.Ceil(float64(bits) / math.Log2(float64(len(permalinkWords)))))
+		return func() (string, error) {
+			words := make([]string, n)
+			for i := range words {
+				j, err := randIndex(len(permalinkWords))
+				if err != nil {
+					return "", err
+				}
+				words[i] = permalinkWords[j]
+			}
+			return strings.Join(words, "-"), nil
+		}, nil
+	default:
+		return nil, fmt.Errorf("unknown permalink id scheme: %s", scheme)
+	}
+}
+
+// alphabetIdGenerator draws just enough characters from alphabet to
+// carry the requested number of bits.
+func alphabetIdGenerator(alphabet string, bits int) permalinkIdGenerator {
+	n := int(math.Ceil(float64(bits) / math.Log2(float64(len(alphabet)))))
+	return func() (string, error) {
+		b := make([]byte, n)
+		for i := range b {
+			j, err := randIndex(len(alphabet))
+			if err != nil {
+				return "", err
+			}
+			b[i] = alphabet[j]
+		}
+		return string(b), nil
+	}
+}
+
+func randIndex(n int) (int, error) {
+	i, err := rand.Int(rand.Reader, big.NewInt(int64(n)))
+	if err != nil {
+		return 0, fmt.Errorf("error generating permalink id: %w", err)
+	}
+	return int(i.Int64()), nil
+}
+
+func isUniqueViolation(err error) bool {
+	var serr *sqlite.Error
+	if !errors.As(err, &serr) {
+		return false
+	}
+	return serr.Code() == sqlite3.SQLITE_CONSTRAINT_PRIMARYKEY || serr.Code() == sqlite3.SQLITE_CONSTRAINT_UNIQUE
+}
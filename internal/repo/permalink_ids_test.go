@@ -0,0 +1,57 @@
+package repo
+
+import (
+	"context"
+	"regexp"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPermalinkIdGenerator(t *testing.T) {
+	tests := []struct {
+		scheme string
+		bits   int
+		re     string
+	}{
+		{"base62", 128, `^[0-9A-Za-z]{22}$`},
+		{"hex", 24, `^[0-9a-f]{6}$`},
+		{"words", 48, `^[a-z]+(-[a-z]+){5}$`},
+	}
+	for _, tt := range tests {
+		gen, err := newPermalinkIdGenerator(tt.scheme, tt.bits)
+		assert.NoError(t, err)
+		id, err := gen()
+		assert.NoError(t, err)
+		assert.Regexp(t, regexp.MustCompile(tt.re), id, tt.scheme)
+	}
+
+	_, err := newPermalinkIdGenerator("emoji", 128)
+	assert.Error(t, err)
+	_, err = newPermalinkIdGenerator("base62", 8)
+	assert.Error(t, err)
+	assert.Len(t, permalinkWords, 256)
+}
+
+func TestInsertPermalink_RetriesCollision(t *testing.T) {
+	ctx := context.Background()
+	r := newTestRepo(t)
+
+	file, header := newTestUpload(t, "a.txt", "hello")
+	f, err := r.InsertFile(ctx, file, header, "notes", "1.2.3.4", 0)
+	assert.NoError(t, err)
+
+	ids := []string{"taken", "taken", "fresh"}
+	r.permalinkIds = func() (string, error) {
+		id := ids[0]
+		ids = ids[1:]
+		return id, nil
+	}
+
+	p, err := r.InsertPermalink(ctx, f.Uuid.String(), 60, PermalinkOptions{})
+	assert.NoError(t, err)
+	assert.Equal(t, "taken", p.Uuid)
+	p, err = r.InsertPermalink(ctx, f.Uuid.String(), 60, PermalinkOptions{})
+	assert.NoError(t, err)
+	assert.Equal(t, "fresh", p.Uuid)
+}
@@ -0,0 +1,145 @@
+package repo
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+
+	"github.com/btschwartz12/site/internal/repo/db"
+	"github.com/btschwartz12/site/internal/storage"
+)
+
+const (
+	AccessServed           = "served"
+	AccessRevoked          = "revoked"
+	AccessExpired          = "expired"
+	AccessExhausted        = "exhausted"
+	AccessTrashed          = "trashed"
+	AccessPasswordRequired = "password_required"
+	AccessInvalidPassword  = "invalid_password"
+)
+
+type PermalinkAccess struct {
+	ID        int64
+	Ip        string
+	UserAgent string
+	Outcome   string
+	Pit       time.Time
+}
+
+// PermalinkRequest describes an attempt to download a permalink.
+type PermalinkRequest struct {
+	Ip        string
+	UserAgent string
+	Password  string
+	// SkipPassword lets callers that are already authenticated
+	// through other means past the password check.
+	SkipPassword bool
+	// Range and IfRange are the request's headers, used to tell
+	// downloads from follow-up range requests that continue a
+	// download already counted.
+	Range   string
+	IfRange string
+}
+
+// AccessPermalink checks that the permalink can be downloaded by req,
+// counts the download and records the attempt in the access log.
+func (r *Repo) AccessPermalink(ctx context.Context, uuid string, req PermalinkRequest) (*Permalink, error) {
+	p, err := r.GetPermalink(ctx, uuid)
+	if err != nil {
+		return nil, err
+	}
+
+	outcome, err := r.checkPermalink(ctx, p, req)
+	r.logPermalinkAccess(ctx, uuid, req, outcome)
+	if err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+func (r *Repo) checkPermalink(ctx context.Context, p *Permalink, req PermalinkRequest) (string, error) {
+	switch {
+	case p.Revoked != nil:
+		return AccessRevoked, errorf(ErrUnavailable, "permalink revoked")
+	case p.File.DeletedAt != nil:
+		return AccessTrashed, errorf(ErrUnavailable, "file is in the trash")
+	case p.Expires.Before(time.Now()):
+		return AccessExpired, errorf(ErrUnavailable, "permalink expired at %s", p.Expires)
+	case p.MaxDownloads != nil && p.Downloads >= *p.MaxDownloads:
+		return AccessExhausted, errorf(ErrUnavailable, "permalink download limit reached")
+	}
+
+	if p.HasPassword && !req.SkipPassword {
+		if req.Password == "" {
+			return AccessPasswordRequired, ErrPasswordRequired
+		}
+		err := bcrypt.CompareHashAndPassword([]byte(p.passwordHash), []byte(req.Password))
+		if err != nil {
+			return AccessInvalidPassword, ErrInvalidPassword
+		}
+	}
+
+	if storage.IsDownloadStart(req.Range, req.IfRange, p.File.Content()) {
+		q := db.New(r.db)
+		n, err := q.IncrementPermalinkDownloads(ctx, p.Uuid)
+		if err != nil {
+			return "", fmt.Errorf("error counting download: %w", err)
+		}
+		// another request may have used the last download
+		if n == 0 {
+			return AccessExhausted, errorf(ErrUnavailable, "permalink download limit reached")
+		}
+		p.Downloads++
+	}
+	return AccessServed, nil
+}
+
+func (r *Repo) logPermalinkAccess(ctx context.Context, uuid string, req PermalinkRequest, outcome string) {
+	if outcome == "" {
+		return
+	}
+	q := db.New(r.db)
+	err := q.InsertPermalinkAccess(ctx, db.InsertPermalinkAccessParams{
+		PermalinkUuid: uuid,
+		Ip:            req.Ip,
+		UserAgent:     req.UserAgent,
+		Outcome:       outcome,
+	})
+	if err != nil {
+		r.logger.Errorw("error logging permalink access", "error", err, "uuid", uuid)
+	}
+}
+
+func (r *Repo) RevokePermalink(ctx context.Context, uuid string) (*Permalink, error) {
+	q := db.New(r.db)
+	row, err := q.RevokePermalink(ctx, uuid)
+	if err != nil {
+		return nil, fmt.Errorf("error revoking permalink: %w", err)
+	}
+	return r.permalinkFromDb(ctx, &row)
+}
+
+func (r *Repo) GetPermalinkAccesses(ctx context.Context, uuid string) ([]PermalinkAccess, error) {
+	if _, err := r.GetPermalink(ctx, uuid); err != nil {
+		return nil, err
+	}
+	q := db.New(r.db)
+	rows, err := q.GetPermalinkAccesses(ctx, uuid)
+	if err != nil {
+		return nil, fmt.Errorf("error getting permalink accesses: %w", err)
+	}
+	accesses := make([]PermalinkAccess, len(rows))
+	for i, row := range rows {
+		accesses[i] = PermalinkAccess{
+			ID:        row.ID,
+			Ip:        row.Ip,
+			UserAgent: row.UserAgent,
+			Outcome:   row.Outcome,
+			Pit:       row.Pit,
+		}
+	}
+	return accesses, nil
+}
@@ -0,0 +1,64 @@
+package repo
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAccessPermalink_MaxDownloads(t *testing.T) {
+	ctx := context.Background()
+	r := newTestRepo(t)
+
+	file, header := newTestUpload(t, "a.txt", "hello")
+	f, err := r.InsertFile(ctx, file, header, "notes", "1.2.3.4", 0)
+	assert.NoError(t, err)
+	p, err := r.InsertPermalink(ctx, f.Uuid.String(), 60, PermalinkOptions{MaxDownloads: 2})
+	assert.NoError(t, err)
+
+	req := PermalinkRequest{Ip: "1.2.3.4", UserAgent: "curl"}
+	_, err = r.AccessPermalink(ctx, p.Uuid, req)
+	assert.NoError(t, err)
+	// resuming doesn't count, but a suffix range covering the whole
+	// file does
+	_, err = r.AccessPermalink(ctx, p.Uuid, PermalinkRequest{Range: "bytes=1-"})
+	assert.NoError(t, err)
+	_, err = r.AccessPermalink(ctx, p.Uuid, PermalinkRequest{Range: "bytes=-5"})
+	assert.NoError(t, err)
+	_, err = r.AccessPermalink(ctx, p.Uuid, req)
+	assert.ErrorContains(t, err, "download limit reached")
+
+	accesses, err := r.GetPermalinkAccesses(ctx, p.Uuid)
+	assert.NoError(t, err)
+	assert.Len(t, accesses, 4)
+	assert.Equal(t, AccessExhausted, accesses[0].Outcome)
+	assert.Equal(t, "curl", accesses[0].UserAgent)
+}
+
+func TestAccessPermalink_PasswordAndRevoke(t *testing.T) {
+	ctx := context.Background()
+	r := newTestRepo(t)
+
+	file, header := newTestUpload(t, "a.txt", "hello")
+	f, err := r.InsertFile(ctx, file, header, "notes", "1.2.3.4", 0)
+	assert.NoError(t, err)
+	p, err := r.InsertPermalink(ctx, f.Uuid.String(), 60, PermalinkOptions{Password: "hunter2"})
+	assert.NoError(t, err)
+	assert.True(t, p.HasPassword)
+
+	_, err = r.AccessPermalink(ctx, p.Uuid, PermalinkRequest{})
+	assert.ErrorIs(t, err, ErrPasswordRequired)
+	_, err = r.AccessPermalink(ctx, p.Uuid, PermalinkRequest{Password: "nope"})
+	assert.ErrorIs(t, err, ErrInvalidPassword)
+	_, err = r.AccessPermalink(ctx, p.Uuid, PermalinkRequest{Password: "hunter2"})
+	assert.NoError(t, err)
+	_, err = r.AccessPermalink(ctx, p.Uuid, PermalinkRequest{SkipPassword: true})
+	assert.NoError(t, err)
+
+	p, err = r.RevokePermalink(ctx, p.Uuid)
+	assert.NoError(t, err)
+	assert.NotNil(t, p.Revoked)
+	_, err = r.AccessPermalink(ctx, p.Uuid, PermalinkRequest{Password: "hunter2"})
+	assert.ErrorIs(t, err, ErrUnavailable)
+}
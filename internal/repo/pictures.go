@@ -1,18 +1,23 @@
 package repo
 
 import (
+	"bytes"
 	"context"
+	"database/sql"
 	"fmt"
 	"io"
 	"mime/multipart"
-	"os"
+	"path"
 	"path/filepath"
 	"regexp"
+	"sort"
 	"strconv"
+	"strings"
 	"time"
 
+	"github.com/btschwartz12/site/internal/images"
 	"github.com/btschwartz12/site/internal/repo/db"
-	"github.com/google/uuid"
+	"github.com/btschwartz12/site/internal/storage"
 )
 
 const (
@@ -21,8 +26,16 @@ const (
 	maxPictureUploadSize = maxPictureUploadMb << 20
 )
 
+const webpContentType = "image/webp"
+
 var (
 	allowedExtensionsRe = regexp.MustCompile(`\.(jpe?g|png|gif)$`)
+
+	variantExtensions = map[string]string{
+		"image/jpeg":    ".jpg",
+		"image/png":     ".png",
+		webpContentType: ".webp",
+	}
 )
 
 type Picture struct {
@@ -33,7 +46,43 @@ type Picture struct {
 	Extension   string
 	NumLikes    int64
 	NumDislikes int64
+	Name        string
+	Size        int64
+	ContentType string
+	Sha256      string
+	Width       int64
+	Height      int64
+	Variants    []PictureVariant
+	Uploader    string
 	Pit         time.Time
+
+	// Status is PicturePending, PictureApproved or PictureRejected.
+	Status           string
+	ModerationReason string
+	ModeratedBy      string
+	ModeratedAt      *time.Time
+
+	// DeletedAt is when the picture went to the trash, if it has.
+	DeletedAt *time.Time
+}
+
+// PictureVariant is a thumbnail or re-encoded copy of a picture.
+type PictureVariant struct {
+	Width       int64
+	Height      int64
+	ContentType string
+	Size        int64
+	Sha256      string
+	Key         string
+}
+
+func (v *PictureVariant) fromDb(row *db.PictureVariant) {
+	v.Width = row.Width
+	v.Height = row.Height
+	v.ContentType = row.ContentType
+	v.Size = row.Size
+	v.Sha256 = row.Sha256
+	v.Key = row.Key
 }
 
 func (p *Picture) fromDb(row *db.Picture) {
@@ -44,7 +93,78 @@ func (p *Picture) fromDb(row *db.Picture) {
 	p.Extension = row.Extension
 	p.NumLikes = row.NumLikes
 	p.NumDislikes = row.NumDislikes
+	p.Name = row.Name
+	p.Size = row.Size
+	p.ContentType = row.ContentType
+	p.Sha256 = row.Sha256
+	p.Width = row.Width
+	p.Height = row.Height
+	p.Uploader = row.Uploader
 	p.Pit = row.Pit
+	p.Status = row.Status
+	p.ModerationReason = row.ModerationReason
+	p.ModeratedBy = row.ModeratedBy
+	p.ModeratedAt = nullTimePtr(row.ModeratedAt)
+	p.DeletedAt = nullTimePtr(row.DeletedAt)
+}
+
+// Content describes how to serve the picture, inline so that it
+// can be shown in the page.
+func (p *Picture) Content() storage.Content {
+	return storage.Content{
+		Key:         p.Url,
+		Size:        p.Size,
+		ModTime:     p.Pit,
+		ContentType: p.ContentType,
+		Sha256:      p.Sha256,
+		Name:        p.Name,
+		Inline:      true,
+	}
+}
+
+// ContentFor picks the version of the picture to serve to a page
+// showing it width pixels wide, or at full size if width is zero:
+// the narrowest one at least that wide, as WebP if the client
+// accepts it and there is one.
+func (p *Picture) ContentFor(width int64, webp bool) storage.Content {
+	target := p.Width
+	if width > 0 {
+		for _, v := range p.Variants {
+			if v.Width >= width && v.Width < target {
+				target = v.Width
+			}
+		}
+	}
+
+	// variants are ordered by content type, which puts WebP last
+	var pick *PictureVariant
+	for i := range p.Variants {
+		v := &p.Variants[i]
+		if v.Width != target {
+			continue
+		}
+		if v.ContentType == webpContentType {
+			if webp {
+				pick = v
+				break
+			}
+			continue
+		}
+		pick = v
+	}
+	if pick == nil {
+		return p.Content()
+	}
+
+	return storage.Content{
+		Key:         pick.Key,
+		Size:        pick.Size,
+		ModTime:     p.Pit,
+		ContentType: pick.ContentType,
+		Sha256:      pick.Sha256,
+		Name:        strings.TrimSuffix(p.Name, path.Ext(p.Name)) + variantExtensions[pick.ContentType],
+		Inline:      true,
+	}
 }
 
 func (r *Repo) InsertPicture(
@@ -53,51 +173,152 @@ func (r *Repo) InsertPicture(
 	header *multipart.FileHeader,
 	author string,
 	description string,
+	uploader string,
 ) (*Picture, error) {
-	if r.storageFull() {
-		return nil, fmt.Errorf("storage full")
-	}
-
 	if header.Size > maxPictureUploadSize {
-		return nil, fmt.Errorf("file too large (max %d MB)", maxPictureUploadMb)
+		return nil, errorf(ErrTooLarge, "file too large (max %d MB)", maxPictureUploadMb)
+	}
+	if err := r.checkQuota(ctx, uploader, header.Size); err != nil {
+		return nil, err
 	}
 	r.logger.Infow("uploading picture", "size", header.Size, "max", maxPictureUploadSize)
 
 	ext := filepath.Ext(header.Filename)
 	if !allowedExtensionsRe.MatchString(ext) {
-		return nil, fmt.Errorf("invalid file extension")
+		return nil, errorf(ErrInvalidExtension, "invalid file extension")
 	}
 
-	newPath := filepath.Join(r.varDir, pictureUploadDir, uuid.New().String()+ext)
-
-	newFile, err := os.Create(newPath)
+	data, err := io.ReadAll(io.LimitReader(file, maxPictureUploadSize+1))
 	if err != nil {
-		return nil, fmt.Errorf("error creating file: %w", err)
+		return nil, fmt.Errorf("error reading file: %w", err)
 	}
-	defer newFile.Close()
-
-	_, err = io.Copy(newFile, file)
+	if len(data) > maxPictureUploadSize {
+		return nil, errorf(ErrTooLarge, "file too large (max %d MB)", maxPictureUploadMb)
+	}
+	img, err := images.Process(data)
+	if err != nil {
+		return nil, err
+	}
+	// the variants are charged to the uploader too, so check for room
+	// for everything before storing any of it
+	if err := r.checkQuota(ctx, uploader, int64(len(img.Data))+variantBytes(img.Variants)); err != nil {
+		return nil, err
+	}
+	meta, err := hashBlob(bytes.NewReader(img.Data), header.Filename)
 	if err != nil {
-		return nil, fmt.Errorf("error copying file content: %w", err)
+		return nil, err
 	}
+	meta.ContentType = img.ContentType
 
 	params := db.InsertPictureParams{
 		Author:      author,
-		Url:         newPath,
 		Description: description,
 		Extension:   ext,
+		Size:        meta.Size,
+		Uploader:    uploader,
+		Name:        header.Filename,
+		ContentType: meta.ContentType,
+		Sha256:      meta.Sha256,
+		Width:       int64(img.Width),
+		Height:      int64(img.Height),
+		Status:      PicturePending,
 	}
-	q := db.New(r.db)
-	row, err := q.InsertPicture(ctx, params)
+	if reason := r.autoApprove.reason(author, uploader); reason != "" {
+		params.Status = PictureApproved
+		params.ModerationReason = reason
+		params.ModeratedBy = autoModerator
+		params.ModeratedAt = sql.NullTime{Time: time.Now().UTC(), Valid: true}
+	}
+	var row db.Picture
+	err = r.storeBlob(ctx, meta, openBytes(img.Data), func(q *db.Queries) error {
+		params.Url = meta.Key
+		var err error
+		row, err = q.InsertPicture(ctx, params)
+		if err != nil {
+			return fmt.Errorf("error inserting picture: %w", err)
+		}
+		err = q.AddStorageUsage(ctx, db.AddStorageUsageParams{Area: areaPictures, Bytes: row.Size})
+		if err != nil {
+			return fmt.Errorf("error updating storage usage: %w", err)
+		}
+		return nil
+	})
 	if err != nil {
-		return nil, fmt.Errorf("error inserting picture: %w", err)
+		return nil, err
 	}
 
+	observeUpload(areaPictures, int64(len(data)))
+
 	p := Picture{}
 	p.fromDb(&row)
+	// the picture can be served without its variants, so failing to
+	// store them doesn't fail the upload
+	p.Variants, err = r.insertPictureVariants(ctx, uploader, row.ID, img.Variants)
+	if err != nil {
+		r.logger.Errorw("error storing picture variants", "error", err, "id", row.ID)
+	}
 	return &p, nil
 }
 
+// insertPictureVariants stores the variants of picture id, charging
+// them to its uploader.
+func (r *Repo) insertPictureVariants(ctx context.Context, uploader string, id int64, variants []images.Variant) ([]PictureVariant, error) {
+	if err := r.checkQuota(ctx, uploader, variantBytes(variants)); err != nil {
+		return nil, err
+	}
+	stored := make([]PictureVariant, 0, len(variants))
+	for _, v := range variants {
+		meta, err := hashBlob(bytes.NewReader(v.Data), "")
+		if err != nil {
+			return stored, err
+		}
+		meta.ContentType = v.ContentType
+
+		var row db.PictureVariant
+		err = r.storeBlob(ctx, meta, openBytes(v.Data), func(q *db.Queries) error {
+			var err error
+			row, err = q.InsertPictureVariant(ctx, db.InsertPictureVariantParams{
+				PictureID:   id,
+				Width:       int64(v.Width),
+				Height:      int64(v.Height),
+				ContentType: v.ContentType,
+				Key:         meta.Key,
+				Size:        meta.Size,
+				Sha256:      meta.Sha256,
+			})
+			if err != nil {
+				return fmt.Errorf("error inserting picture variant: %w", err)
+			}
+			err = q.AddStorageUsage(ctx, db.AddStorageUsageParams{Area: areaPictures, Bytes: row.Size})
+			if err != nil {
+				return fmt.Errorf("error updating storage usage: %w", err)
+			}
+			return nil
+		})
+		if err != nil {
+			return stored, err
+		}
+		pv := PictureVariant{}
+		pv.fromDb(&row)
+		stored = append(stored, pv)
+	}
+	sort.Slice(stored, func(i, j int) bool {
+		if stored[i].Width != stored[j].Width {
+			return stored[i].Width < stored[j].Width
+		}
+		return stored[i].ContentType < stored[j].ContentType
+	})
+	return stored, nil
+}
+
+func variantBytes(variants []images.Variant) int64 {
+	var n int64
+	for _, v := range variants {
+		n += int64(len(v.Data))
+	}
+	return n
+}
+
 func (r *Repo) GetAllPictures(ctx context.Context) ([]Picture, error) {
 	q := db.New(r.db)
 	rows, err := q.GetAllPictures(ctx)
@@ -105,10 +326,22 @@ func (r *Repo) GetAllPictures(ctx context.Context) ([]Picture, error) {
 		return nil, fmt.Errorf("error getting pictures: %w", err)
 	}
 
+	variantRows, err := q.GetAllPictureVariants(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("error getting picture variants: %w", err)
+	}
+	variants := map[int64][]PictureVariant{}
+	for _, row := range variantRows {
+		v := PictureVariant{}
+		v.fromDb(&row)
+		variants[row.PictureID] = append(variants[row.PictureID], v)
+	}
+
 	pictures := make([]Picture, 0, len(rows))
 	for _, row := range rows {
 		p := Picture{}
 		p.fromDb(&row)
+		p.Variants = variants[row.ID]
 		pictures = append(pictures, p)
 	}
 	return pictures, nil
@@ -127,65 +360,232 @@ func (r *Repo) GetPicture(ctx context.Context, basename string) (*Picture, error
 	}
 
 	if row.Extension != ext {
-		return nil, fmt.Errorf("invalid extension")
+		return nil, errorf(ErrNotFound, "picture %d is not a %s", id, ext)
 	}
 
 	p := Picture{}
 	p.fromDb(&row)
+	if p.Variants, err = pictureVariants(ctx, q, id); err != nil {
+		return nil, err
+	}
 	return &p, nil
 }
 
-func (r *Repo) DeletePicture(ctx context.Context, idStr string) error {
+// GetPictureByID is GetPicture for a bare id, without the extension.
+func (r *Repo) GetPictureByID(ctx context.Context, idStr string) (*Picture, error) {
 	id, err := strconv.ParseInt(idStr, 10, 64)
 	if err != nil {
-		return fmt.Errorf("error converting id to int64: %w", err)
+		return nil, fmt.Errorf("%w: %w", ErrInvalidID, err)
 	}
+
 	q := db.New(r.db)
-	url, err := q.DeletePicture(ctx, id)
+	row, err := q.GetPicture(ctx, id)
 	if err != nil {
-		return fmt.Errorf("error deleting picture: %w", err)
+		return nil, fmt.Errorf("error getting picture: %w", err)
 	}
 
-	err = os.Remove(url)
+	p := Picture{}
+	p.fromDb(&row)
+	if p.Variants, err = pictureVariants(ctx, q, id); err != nil {
+		return nil, err
+	}
+	return &p, nil
+}
+
+func pictureVariants(ctx context.Context, q *db.Queries, id int64) ([]PictureVariant, error) {
+	rows, err := q.GetPictureVariants(ctx, id)
 	if err != nil {
-		return fmt.Errorf("error removing file: %w", err)
+		return nil, fmt.Errorf("error getting picture variants: %w", err)
+	}
+	var variants []PictureVariant
+	for _, row := range rows {
+		v := PictureVariant{}
+		v.fromDb(&row)
+		variants = append(variants, v)
 	}
+	return variants, nil
+}
 
-	return nil
+// PictureFilter selects the pictures to list.
+type PictureFilter struct {
+	ListOptions
+	Author string
+	// Extension matches pictures by extension, with or without the
+	// leading dot.
+	Extension string
+	// Status matches pictures in one moderation state.
+	Status string
+	// Deleted lists the pictures in the trash instead.
+	Deleted bool
+}
+
+// ListPictures returns a page of pictures matching f and the cursor
+// for the next page, which is empty on the last one.
+func (r *Repo) ListPictures(ctx context.Context, f PictureFilter) ([]Picture, string, error) {
+	b, err := f.bounds()
+	if err != nil {
+		return nil, "", err
+	}
+	cursorID, err := b.cursorID()
+	if err != nil {
+		return nil, "", err
+	}
+	params := db.ListPicturesDescParams{
+		CursorPit: b.cursorPit,
+		CursorID:  cursorID,
+		Since:     b.since,
+		Until:     b.until,
+		Author:    nullIfEmpty(f.Author),
+		Extension: nullIfEmpty(normalizeExtension(f.Extension)),
+		Status:    nullIfEmpty(f.Status),
+		Deleted:   f.Deleted,
+		Limit:     b.limit,
+	}
+
+	q := db.New(r.db)
+	var rows []db.Picture
+	if f.Ascending {
+		rows, err = q.ListPicturesAsc(ctx, db.ListPicturesAscParams(params))
+	} else {
+		rows, err = q.ListPicturesDesc(ctx, params)
+	}
+	if err != nil {
+		return nil, "", fmt.Errorf("error listing pictures: %w", err)
+	}
+	rows, next := page(rows, b, func(p db.Picture) (time.Time, string) {
+		return p.Pit, strconv.FormatInt(p.ID, 10)
+	})
+	pictures := make([]Picture, len(rows))
+	for i, row := range rows {
+		pictures[i].fromDb(&row)
+		if pictures[i].Variants, err = pictureVariants(ctx, q, row.ID); err != nil {
+			return nil, "", err
+		}
+	}
+	return pictures, next, nil
 }
 
-func (r *Repo) LikePicture(ctx context.Context, idStr string) error {
+// DeletePicture moves a picture to the trash, out of the gallery and
+// the api, until it is restored or the janitor purges it.
+func (r *Repo) DeletePicture(ctx context.Context, idStr string) error {
 	id, err := strconv.ParseInt(idStr, 10, 64)
 	if err != nil {
-		return fmt.Errorf("error converting id to int64: %w", err)
+		return fmt.Errorf("%w: %w", ErrInvalidID, err)
 	}
 	q := db.New(r.db)
-	err = q.AddLikeToPicture(ctx, id)
+	n, err := q.TrashPicture(ctx, id)
 	if err != nil {
-		return fmt.Errorf("error liking picture: %w", err)
+		return fmt.Errorf("error deleting picture: %w", err)
 	}
+	if n == 0 {
+		return fmt.Errorf("error deleting picture: %w", sql.ErrNoRows)
+	}
+	r.logger.Infow("moved picture to trash", "id", id)
 	return nil
 }
 
-func (r *Repo) DislikePicture(ctx context.Context, idStr string) error {
+// RestorePicture takes a picture back out of the trash.
+func (r *Repo) RestorePicture(ctx context.Context, idStr string) (*Picture, error) {
 	id, err := strconv.ParseInt(idStr, 10, 64)
 	if err != nil {
-		return fmt.Errorf("error converting id to int64: %w", err)
+		return nil, fmt.Errorf("%w: %w", ErrInvalidID, err)
 	}
 	q := db.New(r.db)
-	err = q.AddDislikeToPicture(ctx, id)
+	row, err := q.RestorePicture(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("error restoring picture: %w", err)
+	}
+	p := Picture{}
+	p.fromDb(&row)
+	if p.Variants, err = pictureVariants(ctx, q, id); err != nil {
+		return nil, err
+	}
+	r.logger.Infow("restored picture from trash", "id", id)
+	return &p, nil
+}
+
+// PurgePicture removes a picture and its variants for good, whether
+// or not it is in the trash, along with their blobs if nothing else
+// shares them.
+func (r *Repo) PurgePicture(ctx context.Context, idStr string) error {
+	id, err := strconv.ParseInt(idStr, 10, 64)
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrInvalidID, err)
+	}
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("error beginning transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	q := db.New(tx)
+	variants, err := q.DeletePictureVariants(ctx, id)
+	if err != nil {
+		return fmt.Errorf("error deleting picture variants: %w", err)
+	}
+	row, err := q.DeletePicture(ctx, id)
+	if err != nil {
+		return fmt.Errorf("error deleting picture: %w", err)
+	}
+
+	size := row.Size
+	var released []string
+	key, err := releaseBlob(ctx, q, row.Sha256, row.Url)
 	if err != nil {
-		return fmt.Errorf("error disliking picture: %w", err)
+		return err
+	}
+	released = append(released, key)
+	for _, v := range variants {
+		size += v.Size
+		key, err := releaseBlob(ctx, q, v.Sha256, v.Key)
+		if err != nil {
+			return err
+		}
+		released = append(released, key)
+	}
+	err = q.AddStorageUsage(ctx, db.AddStorageUsageParams{Area: areaPictures, Bytes: -size})
+	if err != nil {
+		return fmt.Errorf("error updating storage usage: %w", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("error committing transaction: %w", err)
+	}
+
+	for _, key := range released {
+		if err := r.deleteReleasedBlob(ctx, key); err != nil {
+			return err
+		}
 	}
 	return nil
 }
 
+// UpdateLikesOfPicture sets the like and dislike counts of a picture,
+// keeping the votes behind them; later votes count up from there.
 func (r *Repo) UpdateLikesOfPicture(ctx context.Context, idStr string, likes int64, dislikes int64) (*Picture, error) {
 	id, err := strconv.ParseInt(idStr, 10, 64)
 	if err != nil {
-		return nil, fmt.Errorf("error converting id to int64: %w", err)
+		return nil, fmt.Errorf("%w: %w", ErrInvalidID, err)
+	}
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("error beginning transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	q := db.New(tx)
+	counts, err := q.GetPictureVoteCounts(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("error counting votes: %w", err)
+	}
+	err = q.UpdatePictureVoteBase(ctx, db.UpdatePictureVoteBaseParams{
+		BaseLikes:    likes - counts.Likes,
+		BaseDislikes: dislikes - counts.Dislikes,
+		ID:           id,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error updating vote base: %w", err)
 	}
-	q := db.New(r.db)
 	picture, err := q.UpdateLikesDislikesOfPicture(ctx, db.UpdateLikesDislikesOfPictureParams{
 		ID:          id,
 		NumLikes:    likes,
@@ -194,21 +594,108 @@ func (r *Repo) UpdateLikesOfPicture(ctx context.Context, idStr string, likes int
 	if err != nil {
 		return nil, fmt.Errorf("error updating likes/dislikes: %w", err)
 	}
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("error committing transaction: %w", err)
+	}
 	p := Picture{}
 	p.fromDb(&picture)
 	return &p, nil
 }
 
+// backfillPictureVariants runs pictures uploaded before the image
+// pipeline through it, stripping their metadata and rendering their
+// variants.
+func (r *Repo) backfillPictureVariants(ctx context.Context) error {
+	q := db.New(r.db)
+	pictures, err := q.GetAllPictures(ctx)
+	if err != nil {
+		return fmt.Errorf("error getting pictures: %w", err)
+	}
+	for _, p := range pictures {
+		if p.Width != 0 {
+			continue
+		}
+		if err := r.backfillPicture(ctx, &p); err != nil {
+			r.logger.Warnw("error processing picture", "error", err, "id", p.ID)
+		}
+	}
+	return nil
+}
+
+func (r *Repo) backfillPicture(ctx context.Context, p *db.Picture) error {
+	rc, err := r.blobs.Get(ctx, p.Url)
+	if err != nil {
+		return fmt.Errorf("error opening blob: %w", err)
+	}
+	data, err := io.ReadAll(rc)
+	rc.Close()
+	if err != nil {
+		return fmt.Errorf("error reading blob: %w", err)
+	}
+	img, err := images.Process(data)
+	if err != nil {
+		return err
+	}
+
+	q := db.New(r.db)
+	if !bytes.Equal(img.Data, data) {
+		meta, err := hashBlob(bytes.NewReader(img.Data), p.Name)
+		if err != nil {
+			return err
+		}
+		meta.ContentType = img.ContentType
+		var released string
+		err = r.storeBlob(ctx, meta, openBytes(img.Data), func(q *db.Queries) error {
+			err := q.UpdatePictureBlob(ctx, db.UpdatePictureBlobParams{
+				Url:         meta.Key,
+				Size:        meta.Size,
+				ContentType: meta.ContentType,
+				Sha256:      meta.Sha256,
+				ID:          p.ID,
+			})
+			if err != nil {
+				return fmt.Errorf("error updating picture: %w", err)
+			}
+			err = q.AddStorageUsage(ctx, db.AddStorageUsageParams{Area: areaPictures, Bytes: meta.Size - p.Size})
+			if err != nil {
+				return fmt.Errorf("error updating storage usage: %w", err)
+			}
+			released, err = releaseBlob(ctx, q, p.Sha256, p.Url)
+			return err
+		})
+		if err != nil {
+			return err
+		}
+		if err := r.deleteReleasedBlob(ctx, released); err != nil {
+			return err
+		}
+	}
+
+	err = q.UpdatePictureDimensions(ctx, db.UpdatePictureDimensionsParams{
+		Width:  int64(img.Width),
+		Height: int64(img.Height),
+		ID:     p.ID,
+	})
+	if err != nil {
+		return fmt.Errorf("error updating picture dimensions: %w", err)
+	}
+	if _, err := r.insertPictureVariants(ctx, p.Uploader, p.ID, img.Variants); err != nil {
+		return err
+	}
+	r.logger.Infow("processed picture", "id", p.ID, "variants", len(img.Variants))
+	return nil
+}
+
 func parsePictureBasename(basename string) (int64, string, error) {
 	re := regexp.MustCompile(`^(\d+)(\.\w+)$`)
 	matches := re.FindStringSubmatch(basename)
 	if len(matches) != 3 {
-		return 0, "", fmt.Errorf("invalid basename format")
+		return 0, "", errorf(ErrInvalidID, "invalid basename: %q", basename)
 	}
 
 	i, err := strconv.ParseInt(matches[1], 10, 64)
 	if err != nil {
-		return 0, "", fmt.Errorf("error converting id to int64: %w", err)
+		return 0, "", fmt.Errorf("%w: %w", ErrInvalidID, err)
 	}
 
 	return i, matches[2], nil
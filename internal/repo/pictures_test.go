@@ -0,0 +1,92 @@
+package repo
+
+import (
+	"bytes"
+	"context"
+	"image"
+	"image/jpeg"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/btschwartz12/site/internal/repo/db"
+	"github.com/btschwartz12/site/internal/storage"
+)
+
+func testJpeg(t *testing.T, w, h int) string {
+	var buf bytes.Buffer
+	assert.NoError(t, jpeg.Encode(&buf, image.NewGray(image.Rect(0, 0, w, h)), nil))
+	return buf.String()
+}
+
+func TestInsertPicture_Variants(t *testing.T) {
+	ctx := context.Background()
+	r := newTestRepo(t)
+
+	file, header := newTestUpload(t, "a.jpg", testJpeg(t, 700, 400))
+	p, err := r.InsertPicture(ctx, file, header, "author", "desc", "1.2.3.4")
+	assert.NoError(t, err)
+	assert.Equal(t, int64(700), p.Width)
+	assert.Equal(t, int64(400), p.Height)
+
+	// a flat image compresses well enough losslessly for every
+	// thumbnail to get a WebP too
+	p, err = r.GetPicture(ctx, strconv.FormatInt(p.ID, 10)+".jpg")
+	assert.NoError(t, err)
+	var variants []string
+	for _, v := range p.Variants {
+		variants = append(variants, strconv.FormatInt(v.Width, 10)+" "+v.ContentType)
+	}
+	assert.Equal(t, []string{"320 image/jpeg", "320 image/webp", "640 image/jpeg", "640 image/webp"}, variants)
+
+	c := p.ContentFor(300, false)
+	assert.Equal(t, p.Variants[0].Key, c.Key)
+	assert.Equal(t, "a.jpg", c.Name)
+	c = p.ContentFor(300, true)
+	assert.Equal(t, p.Variants[1].Key, c.Key)
+	assert.Equal(t, "a.webp", c.Name)
+	assert.Equal(t, p.Variants[2].Key, p.ContentFor(321, false).Key)
+	assert.Equal(t, p.Url, p.ContentFor(0, true).Key)
+	assert.Equal(t, p.Url, p.ContentFor(2000, false).Key)
+
+	assert.NoError(t, r.PurgePicture(ctx, strconv.FormatInt(p.ID, 10)))
+	for _, v := range p.Variants {
+		_, err := r.blobs.Stat(ctx, v.Key)
+		assert.ErrorIs(t, err, storage.ErrNotFound)
+	}
+	assert.Equal(t, int64(0), areaBytes(t, r, areaPictures))
+}
+
+func TestInsertPicture_RejectsNonImages(t *testing.T) {
+	ctx := context.Background()
+	r := newTestRepo(t)
+
+	file, header := newTestUpload(t, "a.png", "not really a png")
+	_, err := r.InsertPicture(ctx, file, header, "author", "desc", "1.2.3.4")
+	assert.ErrorIs(t, err, ErrInvalidImage)
+}
+
+func TestBackfillPictureVariants(t *testing.T) {
+	ctx := context.Background()
+	r := newTestRepo(t)
+
+	content := testJpeg(t, 400, 100)
+	assert.NoError(t, r.blobs.Put(ctx, "pictures/old.jpg", strings.NewReader(content), int64(len(content))))
+	q := db.New(r.db)
+	row, err := q.InsertPicture(ctx, db.InsertPictureParams{
+		Url:       "pictures/old.jpg",
+		Extension: ".jpg",
+		Size:      int64(len(content)),
+	})
+	assert.NoError(t, err)
+
+	assert.NoError(t, r.backfillPictureVariants(ctx))
+	p, err := r.GetPicture(ctx, strconv.FormatInt(row.ID, 10)+".jpg")
+	assert.NoError(t, err)
+	assert.Equal(t, int64(400), p.Width)
+	assert.Equal(t, int64(100), p.Height)
+	assert.Equal(t, int64(320), p.Variants[0].Width)
+	assert.Equal(t, "pictures/old.jpg", p.Url)
+}
@@ -1,15 +1,19 @@
 package repo
 
 import (
+	"context"
 	"database/sql"
 	"fmt"
 	"os"
 	"path/filepath"
+	"sync"
+	"time"
 
 	"go.uber.org/zap"
 	_ "modernc.org/sqlite"
 
 	"github.com/btschwartz12/site/internal/repo/db"
+	"github.com/btschwartz12/site/internal/storage"
 )
 
 const (
@@ -21,6 +25,23 @@ type Repo struct {
 	logger *zap.SugaredLogger
 	db     *sql.DB
 	varDir string
+	blobs  storage.BlobStore
+
+	permalinkIds  permalinkIdGenerator
+	maxUploadSize int64
+	// maxAnonymousUploadSize is the smaller limit for
+	// unauthenticated uploads
+	maxAnonymousUploadSize int64
+	maxOpenUploads         int64
+	autoApprove            autoApproveRules
+	trashRetention         time.Duration
+
+	janitorMu         sync.Mutex
+	lastJanitorReport *JanitorReport
+
+	// background tracks the goroutines started with Go, so Close can
+	// wait for them
+	background sync.WaitGroup
 }
 
 func NewRepo(logger *zap.SugaredLogger, varDir string) (*Repo, error) {
@@ -33,29 +54,132 @@ func NewRepo(logger *zap.SugaredLogger, varDir string) (*Repo, error) {
 	}
 	r.varDir = varDir
 
-	conn, err := sql.Open("sqlite", filepath.Join(varDir, dbName))
+	conf, err := newConfig()
 	if err != nil {
-		return nil, fmt.Errorf("error opening database connection: %w", err)
+		return nil, err
 	}
+	r.permalinkIds, err = newPermalinkIdGenerator(conf.PermalinkIdScheme, conf.PermalinkIdBits)
+	if err != nil {
+		return nil, fmt.Errorf("error creating permalink id generator: %w", err)
+	}
+
+	r.maxUploadSize = conf.MaxUploadMb << 20
+	r.maxAnonymousUploadSize = conf.AnonymousMaxUploadMb << 20
+	r.maxOpenUploads = conf.MaxOpenUploads
+	r.autoApprove = newAutoApproveRules(conf.PicsAutoApproveAuthors, conf.PicsAutoApproveUploaders)
+	r.trashRetention = conf.TrashRetention
 
-	if _, err := conn.Exec(string(db.Schema)); err != nil {
-		return nil, fmt.Errorf("error executing schema: %w", err)
+	blobs, err := storage.New(varDir)
+	if err != nil {
+		return nil, fmt.Errorf("error creating blob store: %w", err)
+	}
+	r.blobs = blobs
+
+	conn, err := openDb(varDir)
+	if err != nil {
+		return nil, err
+	}
+
+	m, err := newMigrator(logger, conn, db.Migrations)
+	if err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("error creating migrator: %w", err)
+	}
+	if _, err := m.Up(context.Background()); err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("error applying migrations: %w", err)
 	}
 
 	r.db = conn
+
+	r.runBackfills(context.Background(), []backfill{
+		{name: "blob_metadata", run: r.backfillBlobMetadata},
+		{name: "adopt_blobs", run: r.adoptBlobs},
+		{name: "picture_variants", run: r.backfillPictureVariants},
+	})
+	// after the backfills, so it counts what they stored
+	if err := r.reconcileStorageUsage(context.Background()); err != nil {
+		r.logger.Errorw("error reconciling storage usage", "error", err)
+	}
+	if err := r.reconcilePictureVotes(context.Background()); err != nil {
+		r.logger.Errorw("error reconciling picture votes", "error", err)
+	}
+
 	return r, nil
 }
 
-func (r *Repo) storageFull() bool {
-	var stat os.FileInfo
-	var err error
-	if stat, err = os.Stat(r.varDir); err != nil {
-		r.logger.Errorw("error getting var dir info", "error", err)
-		return true
+// backfill brings rows stored by older versions up to date. They read
+// or rewrite blobs, so each runs only until it first succeeds.
+type backfill struct {
+	name string
+	run  func(ctx context.Context) error
+}
+
+func (r *Repo) runBackfills(ctx context.Context, backfills []backfill) {
+	q := db.New(r.db)
+	for _, b := range backfills {
+		done, err := q.IsBackfillDone(ctx, b.name)
+		if err != nil {
+			r.logger.Errorw("error checking backfill", "error", err, "backfill", b.name)
+			continue
+		}
+		if done > 0 {
+			continue
+		}
+		if err := b.run(ctx); err != nil {
+			r.logger.Errorw("error running backfill", "error", err, "backfill", b.name)
+			continue
+		}
+		if err := q.MarkBackfillDone(ctx, b.name); err != nil {
+			r.logger.Errorw("error recording backfill", "error", err, "backfill", b.name)
+			continue
+		}
+		r.logger.Infow("completed backfill", "backfill", b.name)
 	}
-	if stat.Size() > maxStorageSize {
-		r.logger.Errorw("storage full", "size", stat.Size())
-		return true
+}
+
+// Blobs returns the store holding uploaded drive files and pictures.
+func (r *Repo) Blobs() storage.BlobStore {
+	return r.blobs
+}
+
+// Go runs f in the background. Close waits for it to return before
+// closing the database.
+func (r *Repo) Go(f func()) {
+	r.background.Add(1)
+	go func() {
+		defer r.background.Done()
+		f()
+	}()
+}
+
+// Close waits for the work started with Go to finish, or for ctx to
+// be done, and then closes the database.
+func (r *Repo) Close(ctx context.Context) error {
+	done := make(chan struct{})
+	go func() {
+		r.background.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-ctx.Done():
+		r.logger.Warnw("gave up waiting for background work", "error", ctx.Err())
+	}
+
+	if err := r.db.Close(); err != nil {
+		return fmt.Errorf("error closing database: %w", err)
+	}
+	return nil
+}
+
+func openDb(varDir string) (*sql.DB, error) {
+	// foreign_keys is per connection, so set it in the dsn for
+	// every connection in the pool
+	dsn := filepath.Join(varDir, dbName) + "?_pragma=foreign_keys(1)"
+	conn, err := sql.Open("sqlite", dsn)
+	if err != nil {
+		return nil, fmt.Errorf("error opening database connection: %w", err)
 	}
-	return false
+	return conn, nil
 }
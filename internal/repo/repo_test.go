@@ -0,0 +1,25 @@
+package repo
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+)
+
+func TestClose_WaitsForBackgroundWork(t *testing.T) {
+	ctx := context.Background()
+	r, err := NewRepo(zap.NewNop().Sugar(), t.TempDir())
+	assert.NoError(t, err)
+
+	done := false
+	r.Go(func() {
+		time.Sleep(50 * time.Millisecond)
+		done = true
+	})
+	assert.NoError(t, r.Close(ctx))
+	assert.True(t, done)
+	assert.Error(t, r.db.PingContext(ctx))
+}
@@ -0,0 +1,59 @@
+package repo
+
+import (
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+)
+
+const secretSize = 32
+
+var validSecretName = regexp.MustCompile(`^[a-z0-9_]+$`)
+
+// Secret returns the secret stored under name in the var dir,
+// generating and saving a random one the first time it is asked for,
+// so it survives restarts.
+func (r *Repo) Secret(name string) ([]byte, error) {
+	if !validSecretName.MatchString(name) {
+		return nil, errorf(ErrInvalidInput, "invalid secret name: %q", name)
+	}
+	path := filepath.Join(r.varDir, name+".secret")
+
+	secret, err := os.ReadFile(path)
+	if err == nil {
+		if len(secret) < secretSize {
+			return nil, fmt.Errorf("secret %s is too short", path)
+		}
+		return secret, nil
+	}
+	if !errors.Is(err, fs.ErrNotExist) {
+		return nil, fmt.Errorf("error reading secret: %w", err)
+	}
+
+	secret = make([]byte, secretSize)
+	if _, err := rand.Read(secret); err != nil {
+		return nil, fmt.Errorf("error generating secret: %w", err)
+	}
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+	if errors.Is(err, fs.ErrExist) {
+		// created since we looked, so use that one
+		return r.Secret(name)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error creating secret: %w", err)
+	}
+	if _, err := f.Write(secret); err != nil {
+		f.Close()
+		os.Remove(path)
+		return nil, fmt.Errorf("error writing secret: %w", err)
+	}
+	if err := f.Close(); err != nil {
+		os.Remove(path)
+		return nil, fmt.Errorf("error writing secret: %w", err)
+	}
+	return secret, nil
+}
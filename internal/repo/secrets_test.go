@@ -0,0 +1,33 @@
+package repo
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSecret(t *testing.T) {
+	r := newTestRepo(t)
+
+	a, err := r.Secret("vote")
+	assert.NoError(t, err)
+	assert.Len(t, a, secretSize)
+
+	// the same secret comes back, as it would after a restart
+	again, err := r.Secret("vote")
+	assert.NoError(t, err)
+	assert.Equal(t, a, again)
+
+	info, err := os.Stat(filepath.Join(r.varDir, "vote.secret"))
+	assert.NoError(t, err)
+	assert.Equal(t, os.FileMode(0600), info.Mode().Perm())
+
+	b, err := r.Secret("other")
+	assert.NoError(t, err)
+	assert.NotEqual(t, a, b)
+
+	_, err = r.Secret("../vote")
+	assert.ErrorIs(t, err, ErrInvalidInput)
+}
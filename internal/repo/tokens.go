@@ -0,0 +1,176 @@
+package repo
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/btschwartz12/site/internal/repo/db"
+)
+
+const (
+	apiTokenPrefix = "site_"
+	apiTokenBytes  = 32
+
+	// AdminScope grants every other scope.
+	AdminScope = "admin"
+)
+
+type ApiToken struct {
+	ID     int64
+	Name   string
+	Scopes []string
+	// MaxUploadBytes limits resumable uploads made with the token;
+	// nil means the default limit applies.
+	MaxUploadBytes *int64
+	Expires        *time.Time
+	LastUsed       *time.Time
+	Revoked        *time.Time
+	Pit            time.Time
+}
+
+func (t *ApiToken) fromDb(row *db.ApiToken) {
+	t.ID = row.ID
+	t.Name = row.Name
+	t.Scopes = strings.Fields(row.Scopes)
+	if row.MaxUploadBytes.Valid {
+		t.MaxUploadBytes = &row.MaxUploadBytes.Int64
+	}
+	t.Expires = nullTimePtr(row.Expires)
+	t.LastUsed = nullTimePtr(row.LastUsed)
+	t.Revoked = nullTimePtr(row.Revoked)
+	t.Pit = row.Pit
+}
+
+// HasScope reports whether the token grants scope, either
+// directly or through the admin scope.
+func (t *ApiToken) HasScope(scope string) bool {
+	for _, s := range t.Scopes {
+		if s == scope || s == AdminScope {
+			return true
+		}
+	}
+	return false
+}
+
+// InsertApiToken mints a new token, returning it along with the
+// plaintext secret, which is not stored and cannot be recovered.
+func (r *Repo) InsertApiToken(ctx context.Context, name string, scopes []string, expires *time.Time, maxUploadBytes int64) (*ApiToken, string, error) {
+	b := make([]byte, apiTokenBytes)
+	if _, err := rand.Read(b); err != nil {
+		return nil, "", fmt.Errorf("error generating token: %w", err)
+	}
+	secret := apiTokenPrefix + base64.RawURLEncoding.EncodeToString(b)
+
+	params := db.InsertApiTokenParams{
+		Name:      name,
+		TokenHash: hashApiToken(secret),
+		Scopes:    strings.Join(scopes, " "),
+	}
+	if expires != nil {
+		params.Expires = sql.NullTime{Time: *expires, Valid: true}
+	}
+	if maxUploadBytes > 0 {
+		params.MaxUploadBytes = sql.NullInt64{Int64: maxUploadBytes, Valid: true}
+	}
+	q := db.New(r.db)
+	row, err := q.InsertApiToken(ctx, params)
+	if err != nil {
+		if isUniqueViolation(err) {
+			return nil, "", errorf(ErrAlreadyExists, "token already exists: %s", name)
+		}
+		return nil, "", fmt.Errorf("error inserting token: %w", err)
+	}
+
+	t := &ApiToken{}
+	t.fromDb(&row)
+	return t, secret, nil
+}
+
+// AuthenticateApiToken looks up the token matching secret and
+// records its use, failing if it is unknown, revoked or expired.
+func (r *Repo) AuthenticateApiToken(ctx context.Context, secret string) (*ApiToken, error) {
+	hash := hashApiToken(secret)
+
+	q := db.New(r.db)
+	row, err := q.GetApiTokenByHash(ctx, hash)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrInvalidToken
+		}
+		return nil, fmt.Errorf("error getting token: %w", err)
+	}
+	if row.Revoked.Valid {
+		return nil, errorf(ErrInvalidToken, "token revoked")
+	}
+	if row.Expires.Valid && row.Expires.Time.Before(time.Now()) {
+		return nil, errorf(ErrInvalidToken, "token expired")
+	}
+
+	if err := q.TouchApiToken(ctx, row.ID); err != nil {
+		r.logger.Errorw("error updating token last used", "error", err, "id", row.ID)
+	}
+
+	t := &ApiToken{}
+	t.fromDb(&row)
+	return t, nil
+}
+
+// HasApiTokens reports whether any token has ever been minted,
+// including ones since revoked or expired.
+func (r *Repo) HasApiTokens(ctx context.Context) (bool, error) {
+	q := db.New(r.db)
+	n, err := q.CountApiTokens(ctx)
+	if err != nil {
+		return false, fmt.Errorf("error counting tokens: %w", err)
+	}
+	return n > 0, nil
+}
+
+func (r *Repo) GetAllApiTokens(ctx context.Context) ([]ApiToken, error) {
+	q := db.New(r.db)
+	rows, err := q.GetAllApiTokens(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("error getting tokens: %w", err)
+	}
+	tokens := make([]ApiToken, len(rows))
+	for i, row := range rows {
+		tokens[i].fromDb(&row)
+	}
+	return tokens, nil
+}
+
+func (r *Repo) RevokeApiToken(ctx context.Context, idStr string) (*ApiToken, error) {
+	id, err := strconv.ParseInt(idStr, 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidID, err)
+	}
+	q := db.New(r.db)
+	row, err := q.RevokeApiToken(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("error revoking token: %w", err)
+	}
+	t := &ApiToken{}
+	t.fromDb(&row)
+	return t, nil
+}
+
+func hashApiToken(secret string) string {
+	h := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(h[:])
+}
+
+func nullTimePtr(t sql.NullTime) *time.Time {
+	if !t.Valid {
+		return nil
+	}
+	return &t.Time
+}
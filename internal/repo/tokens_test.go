@@ -0,0 +1,57 @@
+package repo
+
+import (
+	"context"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestApiToken_Authenticate(t *testing.T) {
+	ctx := context.Background()
+	r := newTestRepo(t)
+
+	minted, err := r.HasApiTokens(ctx)
+	assert.NoError(t, err)
+	assert.False(t, minted)
+
+	token, secret, err := r.InsertApiToken(ctx, "ci", []string{"pics:read", "drive:write"}, nil, 0)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"pics:read", "drive:write"}, token.Scopes)
+	minted, err = r.HasApiTokens(ctx)
+	assert.NoError(t, err)
+	assert.True(t, minted)
+
+	// quotas go by name, so two tokens can't share one
+	_, _, err = r.InsertApiToken(ctx, "ci", []string{"pics:read"}, nil, 0)
+	assert.ErrorIs(t, err, ErrAlreadyExists)
+
+	got, err := r.AuthenticateApiToken(ctx, secret)
+	assert.NoError(t, err)
+	assert.Equal(t, token.ID, got.ID)
+	assert.True(t, got.HasScope("pics:read"))
+	assert.False(t, got.HasScope("visitors:read"))
+
+	_, err = r.AuthenticateApiToken(ctx, secret+"x")
+	assert.Error(t, err)
+}
+
+func TestApiToken_RevokedAndExpired(t *testing.T) {
+	ctx := context.Background()
+	r := newTestRepo(t)
+
+	token, secret, err := r.InsertApiToken(ctx, "revoked", []string{AdminScope}, nil, 0)
+	assert.NoError(t, err)
+	_, err = r.RevokeApiToken(ctx, strconv.FormatInt(token.ID, 10))
+	assert.NoError(t, err)
+	_, err = r.AuthenticateApiToken(ctx, secret)
+	assert.ErrorContains(t, err, "token revoked")
+
+	expires := time.Now().Add(-time.Minute)
+	_, secret, err = r.InsertApiToken(ctx, "expired", []string{AdminScope}, &expires, 0)
+	assert.NoError(t, err)
+	_, err = r.AuthenticateApiToken(ctx, secret)
+	assert.ErrorContains(t, err, "token expired")
+}
@@ -0,0 +1,99 @@
+package repo
+
+import (
+	"context"
+	"database/sql"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTrash(t *testing.T) {
+	ctx := context.Background()
+	r := newTestRepo(t)
+	r.autoApprove = newAutoApproveRules("", "1.2.3.4")
+
+	file, header := newTestUpload(t, "a.png", testPng(t, 0))
+	p, err := r.InsertPicture(ctx, file, header, "author", "desc", "1.2.3.4")
+	assert.NoError(t, err)
+	id := strconv.FormatInt(p.ID, 10)
+	file, header = newTestUpload(t, "a.txt", "hello")
+	f, err := r.InsertFile(ctx, file, header, "notes", "1.2.3.4", 0)
+	assert.NoError(t, err)
+	link, err := r.InsertPermalink(ctx, f.Uuid.String(), 60, PermalinkOptions{})
+	assert.NoError(t, err)
+
+	_, err = r.RestorePicture(ctx, id)
+	assert.ErrorIs(t, err, sql.ErrNoRows)
+
+	// trashed rows drop out of everything but the trash
+	assert.NoError(t, r.DeletePicture(ctx, id))
+	assert.ErrorIs(t, r.DeletePicture(ctx, id), sql.ErrNoRows)
+	_, err = r.GetPicture(ctx, id+".png")
+	assert.ErrorIs(t, err, sql.ErrNoRows)
+	pictures, _, err := r.GetGalleryPictures(ctx, GalleryOptions{Order: OrderNewest})
+	assert.NoError(t, err)
+	assert.Empty(t, pictures)
+	trash, _, err := r.ListPictures(ctx, PictureFilter{Deleted: true})
+	assert.NoError(t, err)
+	if assert.Len(t, trash, 1) {
+		assert.NotNil(t, trash[0].DeletedAt)
+	}
+
+	assert.NoError(t, r.DeleteFile(ctx, f.Uuid.String()))
+	links, _, err := r.ListPermalinks(ctx, PermalinkFilter{})
+	assert.NoError(t, err)
+	assert.Empty(t, links)
+
+	// a trashed file's permalinks can still be managed, but are gone
+	// for anyone downloading them
+	got, err := r.GetPermalink(ctx, link.Uuid)
+	assert.NoError(t, err)
+	assert.NotNil(t, got.File.DeletedAt)
+	_, err = r.AccessPermalink(ctx, link.Uuid, PermalinkRequest{Ip: "1.2.3.4"})
+	assert.ErrorIs(t, err, ErrUnavailable)
+	accesses, err := r.GetPermalinkAccesses(ctx, link.Uuid)
+	assert.NoError(t, err)
+	if assert.Len(t, accesses, 1) {
+		assert.Equal(t, AccessTrashed, accesses[0].Outcome)
+	}
+	revoked, err := r.RevokePermalink(ctx, link.Uuid)
+	assert.NoError(t, err)
+	assert.NotNil(t, revoked.Revoked)
+
+	// and come back whole
+	restored, err := r.RestorePicture(ctx, id)
+	assert.NoError(t, err)
+	assert.Nil(t, restored.DeletedAt)
+	assert.Len(t, restored.Variants, len(p.Variants))
+	_, err = r.RestoreFile(ctx, f.Uuid.String())
+	assert.NoError(t, err)
+	_, err = r.GetPermalink(ctx, link.Uuid)
+	assert.NoError(t, err)
+
+	// the janitor purges what has been in the trash too long
+	assert.NoError(t, r.DeletePicture(ctx, id))
+	assert.NoError(t, r.DeleteFile(ctx, f.Uuid.String()))
+	report, err := r.Janitor(ctx, false)
+	assert.NoError(t, err)
+	assert.Empty(t, report.TrashedPictures)
+	_, err = r.db.ExecContext(ctx, "UPDATE pictures SET deleted_at = '2000-01-01 00:00:00'")
+	assert.NoError(t, err)
+	_, err = r.db.ExecContext(ctx, "UPDATE files SET deleted_at = '2000-01-01 00:00:00'")
+	assert.NoError(t, err)
+
+	report, err = r.Janitor(ctx, true)
+	assert.NoError(t, err)
+	assert.Equal(t, []int64{p.ID}, report.TrashedPictures)
+	_, err = r.RestorePicture(ctx, id)
+	assert.NoError(t, err)
+
+	report, err = r.Janitor(ctx, false)
+	assert.NoError(t, err)
+	assert.Empty(t, report.TrashedPictures)
+	assert.Equal(t, []string{f.Uuid.String()}, report.TrashedFiles)
+	_, err = r.RestoreFile(ctx, f.Uuid.String())
+	assert.ErrorIs(t, err, sql.ErrNoRows)
+	assert.Equal(t, int64(0), areaBytes(t, r, areaDrive))
+}
@@ -0,0 +1,382 @@
+package repo
+
+import (
+	"bufio"
+	"context"
+	"crypto/sha256"
+	"encoding"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"path"
+	"path/filepath"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/btschwartz12/site/internal/repo/db"
+	"github.com/btschwartz12/site/internal/storage"
+)
+
+const (
+	uploadPartDir = "uploads"
+	// uploadIdleTimeout is how long an upload can go without a new
+	// chunk before the janitor treats it as abandoned.
+	uploadIdleTimeout = 24 * time.Hour
+)
+
+var uploadIds = alphabetIdGenerator(base62Alphabet, 128)
+
+// Upload is a drive file being uploaded in chunks. Each chunk is
+// stored as its own blob and they are joined when it completes.
+type Upload struct {
+	ID        string
+	Name      string
+	Notes     string
+	Size      int64
+	Received  int64
+	Uploader  string
+	UpdatedAt time.Time
+	Pit       time.Time
+}
+
+func (u *Upload) fromDb(row *db.Upload) {
+	u.ID = row.ID
+	u.Name = row.Name
+	u.Notes = row.Notes
+	u.Size = row.Size
+	u.Received = row.Received
+	u.Uploader = row.Uploader
+	u.UpdatedAt = row.UpdatedAt
+	u.Pit = row.Pit
+}
+
+type UploadOptions struct {
+	Name     string
+	Notes    string
+	Size     int64
+	Uploader string
+	// TTL, if set, is applied to the file once the upload completes.
+	TTL time.Duration
+	// Authenticated callers are held to the larger default limit
+	// for api tokens, everyone else to the anonymous one.
+	Authenticated bool
+	// MaxSize overrides the default limit for authenticated callers
+	// if non-zero.
+	MaxSize int64
+}
+
+// MaxAnonymousUploadSize is the largest upload that callers who aren't
+// authenticated may make.
+func (r *Repo) MaxAnonymousUploadSize() int64 {
+	return r.maxAnonymousUploadSize
+}
+
+// CreateUpload starts a resumable upload of opts.Size bytes.
+func (r *Repo) CreateUpload(ctx context.Context, opts UploadOptions) (*Upload, error) {
+	maxSize := r.maxAnonymousUploadSize
+	if opts.Authenticated {
+		maxSize = r.maxUploadSize
+		if opts.MaxSize != 0 {
+			maxSize = opts.MaxSize
+		}
+	}
+	if opts.Name == "" {
+		return nil, errorf(ErrInvalidInput, "invalid upload: name is required")
+	}
+	if opts.Size <= 0 {
+		return nil, errorf(ErrInvalidInput, "invalid upload: size must be positive")
+	}
+	if opts.Size > maxSize {
+		return nil, errorf(ErrTooLarge, "file too large (max %d MB)", maxSize>>20)
+	}
+	if err := r.checkOpenUploads(ctx, opts.Uploader); err != nil {
+		return nil, err
+	}
+	if err := r.checkQuota(ctx, opts.Uploader, opts.Size); err != nil {
+		return nil, err
+	}
+
+	id, err := uploadIds()
+	if err != nil {
+		return nil, err
+	}
+	q := db.New(r.db)
+	row, err := q.InsertUpload(ctx, db.InsertUploadParams{
+		ID:         id,
+		Name:       opts.Name,
+		Notes:      opts.Notes,
+		Size:       opts.Size,
+		Uploader:   opts.Uploader,
+		TtlSeconds: int64(opts.TTL.Seconds()),
+		UpdatedAt:  time.Now().UTC(),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error inserting upload: %w", err)
+	}
+
+	u := &Upload{}
+	u.fromDb(&row)
+	return u, nil
+}
+
+func (r *Repo) GetUpload(ctx context.Context, id string) (*Upload, error) {
+	q := db.New(r.db)
+	row, err := q.GetUpload(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("error getting upload: %w", err)
+	}
+	u := &Upload{}
+	u.fromDb(&row)
+	return u, nil
+}
+
+// AppendUpload stores the length bytes of body as the chunk starting
+// at offset, which must be where the upload left off.
+func (r *Repo) AppendUpload(ctx context.Context, id string, offset int64, body io.Reader, length int64) (*Upload, error) {
+	q := db.New(r.db)
+	row, err := q.GetUpload(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("error getting upload: %w", err)
+	}
+	if offset != row.Received {
+		return nil, errorf(ErrOffsetMismatch, "upload offset mismatch: expected %d", row.Received)
+	}
+	if length <= 0 {
+		return nil, errorf(ErrInvalidInput, "invalid chunk: length must be positive")
+	}
+	if offset+length > row.Size {
+		return nil, errorf(ErrInvalidInput, "invalid chunk: exceeds upload size of %d bytes", row.Size)
+	}
+
+	h, err := restoreHash(row.HashState)
+	if err != nil {
+		return nil, err
+	}
+	br := bufio.NewReaderSize(io.LimitReader(body, length), sniffLen)
+	contentType := row.ContentType
+	if offset == 0 {
+		head, err := br.Peek(sniffLen)
+		if err != nil && !errors.Is(err, io.EOF) && !errors.Is(err, bufio.ErrBufferFull) {
+			return nil, fmt.Errorf("error reading chunk: %w", err)
+		}
+		contentType = sniffContentType(head, row.Name)
+	}
+
+	// parts get a unique suffix so a racing append at the same
+	// offset can't overwrite this one before losing the update below
+	suffix, err := uploadIds()
+	if err != nil {
+		return nil, err
+	}
+	key := path.Join(uploadPartDir, id, fmt.Sprintf("%012d-%s", offset, suffix))
+	if err := r.blobs.Put(ctx, key, io.TeeReader(br, h), length); err != nil {
+		return nil, fmt.Errorf("error storing chunk: %w", err)
+	}
+
+	state, err := h.(encoding.BinaryMarshaler).MarshalBinary()
+	if err != nil {
+		return nil, fmt.Errorf("error saving hash state: %w", err)
+	}
+	err = r.advanceUpload(ctx, db.AdvanceUploadParams{
+		NewReceived: offset + length,
+		HashState:   state,
+		ContentType: contentType,
+		UpdatedAt:   time.Now().UTC(),
+		ID:          id,
+		OldReceived: offset,
+	}, key, length)
+	if err != nil {
+		if err := r.blobs.Delete(ctx, key); err != nil {
+			r.logger.Errorw("error removing orphaned chunk", "error", err, "key", key)
+		}
+		return nil, err
+	}
+
+	return r.GetUpload(ctx, id)
+}
+
+func (r *Repo) advanceUpload(ctx context.Context, params db.AdvanceUploadParams, key string, length int64) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("error beginning transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	q := db.New(tx)
+	n, err := q.AdvanceUpload(ctx, params)
+	if err != nil {
+		return fmt.Errorf("error updating upload: %w", err)
+	}
+	if n == 0 {
+		return errorf(ErrOffsetMismatch, "upload offset mismatch: chunk at %d was already received", params.OldReceived)
+	}
+	err = q.InsertUploadPart(ctx, db.InsertUploadPartParams{
+		UploadID:    params.ID,
+		OffsetBytes: params.OldReceived,
+		Size:        length,
+		Key:         key,
+	})
+	if err != nil {
+		return fmt.Errorf("error inserting upload part: %w", err)
+	}
+	return tx.Commit()
+}
+
+// CompleteUpload joins the chunks of a fully received upload into a
+// drive file.
+func (r *Repo) CompleteUpload(ctx context.Context, id string) (*File, error) {
+	q := db.New(r.db)
+	row, err := q.GetUpload(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("error getting upload: %w", err)
+	}
+	if row.Received != row.Size {
+		return nil, errorf(ErrUploadIncomplete, "upload incomplete: received %d of %d bytes", row.Received, row.Size)
+	}
+	// the upload's size is already reserved, so this only fails if
+	// usage went over some other way since it was created
+	if err := r.checkQuota(ctx, row.Uploader, 0); err != nil {
+		return nil, err
+	}
+	parts, err := q.GetUploadParts(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("error getting upload parts: %w", err)
+	}
+	h, err := restoreHash(row.HashState)
+	if err != nil {
+		return nil, err
+	}
+
+	meta := &blobMeta{
+		ContentType: row.ContentType,
+		Sha256:      hex.EncodeToString(h.Sum(nil)),
+		Size:        row.Size,
+	}
+	params := db.InsertFileParams{
+		Uuid:        uuid.New().String(),
+		Name:        row.Name,
+		Notes:       row.Notes,
+		Extension:   filepath.Ext(row.Name),
+		Size:        row.Size,
+		Uploader:    row.Uploader,
+		ContentType: meta.ContentType,
+		Sha256:      meta.Sha256,
+	}
+	if row.TtlSeconds > 0 {
+		params.Expires = expiresAt(time.Now().Add(time.Duration(row.TtlSeconds) * time.Second))
+	}
+	// the parts are only joined if the content isn't already stored
+	f, err := r.storeFile(ctx, meta, func() (io.ReadCloser, error) {
+		return &partsReader{ctx: ctx, store: r.blobs, parts: parts}, nil
+	}, params)
+	if err != nil {
+		return nil, err
+	}
+	observeUpload(areaDrive, row.Size)
+
+	if err := r.deleteUpload(ctx, id); err != nil {
+		r.logger.Errorw("error cleaning up completed upload", "error", err, "id", id)
+	}
+	return f, nil
+}
+
+// checkOpenUploads returns an error if uploader already has as many
+// uploads open as it's allowed.
+func (r *Repo) checkOpenUploads(ctx context.Context, uploader string) error {
+	q := db.New(r.db)
+	open, err := q.GetUploaderOpenUploads(ctx, uploader)
+	if err != nil {
+		return fmt.Errorf("error getting uploader open uploads: %w", err)
+	}
+	if open.Count >= r.maxOpenUploads {
+		r.logger.Warnw("too many open uploads", "uploader", uploader, "open", open.Count)
+		return errorf(ErrTooManyUploads, "too many open uploads (max %d): finish or abort one first", r.maxOpenUploads)
+	}
+	return nil
+}
+
+// AbortUpload discards an upload and everything received for it.
+func (r *Repo) AbortUpload(ctx context.Context, id string) error {
+	if _, err := r.GetUpload(ctx, id); err != nil {
+		return err
+	}
+	return r.deleteUpload(ctx, id)
+}
+
+func (r *Repo) deleteUpload(ctx context.Context, id string) error {
+	q := db.New(r.db)
+	if err := q.DeleteUpload(ctx, id); err != nil {
+		return fmt.Errorf("error deleting upload: %w", err)
+	}
+
+	// list rather than use upload_parts so chunks from failed
+	// appends go too
+	blobs, err := r.blobs.List(ctx, path.Join(uploadPartDir, id)+"/")
+	if err != nil {
+		return fmt.Errorf("error listing upload parts: %w", err)
+	}
+	for _, b := range blobs {
+		if err := r.blobs.Delete(ctx, b.Key); err != nil && !errors.Is(err, storage.ErrNotFound) {
+			return fmt.Errorf("error deleting upload part: %w", err)
+		}
+	}
+	return nil
+}
+
+func restoreHash(state []byte) (hash.Hash, error) {
+	h := sha256.New()
+	if len(state) == 0 {
+		return h, nil
+	}
+	if err := h.(encoding.BinaryUnmarshaler).UnmarshalBinary(state); err != nil {
+		return nil, fmt.Errorf("error restoring hash state: %w", err)
+	}
+	return h, nil
+}
+
+// partsReader reads upload parts back to back, opening each one
+// only when the previous one is used up.
+type partsReader struct {
+	ctx   context.Context
+	store storage.BlobStore
+	parts []db.UploadPart
+	cur   io.ReadCloser
+}
+
+func (p *partsReader) Read(b []byte) (int, error) {
+	for {
+		if p.cur == nil {
+			if len(p.parts) == 0 {
+				return 0, io.EOF
+			}
+			rc, err := p.store.Get(p.ctx, p.parts[0].Key)
+			if err != nil {
+				return 0, fmt.Errorf("error opening upload part: %w", err)
+			}
+			p.cur = rc
+			p.parts = p.parts[1:]
+		}
+		n, err := p.cur.Read(b)
+		if errors.Is(err, io.EOF) {
+			p.cur.Close()
+			p.cur = nil
+			if n > 0 {
+				return n, nil
+			}
+			continue
+		}
+		return n, err
+	}
+}
+
+func (p *partsReader) Close() error {
+	if p.cur == nil {
+		return nil
+	}
+	err := p.cur.Close()
+	p.cur = nil
+	return err
+}
@@ -0,0 +1,155 @@
+package repo
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestUpload_ChunkedRoundTrip(t *testing.T) {
+	ctx := context.Background()
+	r := newTestRepo(t)
+
+	content := strings.Repeat("0123456789", 1000)
+	u, err := r.CreateUpload(ctx, UploadOptions{Name: "big.txt", Notes: "notes", Size: int64(len(content)), Uploader: "1.2.3.4"})
+	assert.NoError(t, err)
+
+	for off := 0; off < len(content); off += 3000 {
+		end := min(off+3000, len(content))
+		u, err = r.AppendUpload(ctx, u.ID, int64(off), strings.NewReader(content[off:end]), int64(end-off))
+		assert.NoError(t, err)
+		assert.Equal(t, int64(end), u.Received)
+	}
+
+	f, err := r.CompleteUpload(ctx, u.ID)
+	assert.NoError(t, err)
+	sum := sha256.Sum256([]byte(content))
+	assert.Equal(t, hex.EncodeToString(sum[:]), f.Sha256)
+	assert.Equal(t, "big.txt", f.Name)
+	assert.Equal(t, int64(len(content)), f.Size)
+
+	rc, err := r.blobs.Open(ctx, f.Url, 0, f.Size)
+	assert.NoError(t, err)
+	data, _ := io.ReadAll(rc)
+	rc.Close()
+	assert.Equal(t, content, string(data))
+
+	_, err = r.GetUpload(ctx, u.ID)
+	assert.ErrorIs(t, err, sql.ErrNoRows)
+	blobs, err := r.blobs.List(ctx, uploadPartDir+"/")
+	assert.NoError(t, err)
+	assert.Empty(t, blobs)
+}
+
+func TestUpload_OffsetMismatch(t *testing.T) {
+	ctx := context.Background()
+	r := newTestRepo(t)
+
+	u, err := r.CreateUpload(ctx, UploadOptions{Name: "a.txt", Size: 10, Uploader: "1.2.3.4"})
+	assert.NoError(t, err)
+	_, err = r.AppendUpload(ctx, u.ID, 0, strings.NewReader("01234"), 5)
+	assert.NoError(t, err)
+
+	_, err = r.AppendUpload(ctx, u.ID, 0, strings.NewReader("01234"), 5)
+	assert.ErrorIs(t, err, ErrOffsetMismatch)
+	_, err = r.CompleteUpload(ctx, u.ID)
+	assert.ErrorIs(t, err, ErrUploadIncomplete)
+}
+
+func TestUpload_AbortRemovesParts(t *testing.T) {
+	ctx := context.Background()
+	r := newTestRepo(t)
+
+	u, err := r.CreateUpload(ctx, UploadOptions{Name: "a.txt", Size: 10, Uploader: "1.2.3.4"})
+	assert.NoError(t, err)
+	_, err = r.AppendUpload(ctx, u.ID, 0, strings.NewReader("01234"), 5)
+	assert.NoError(t, err)
+
+	assert.NoError(t, r.AbortUpload(ctx, u.ID))
+	blobs, err := r.blobs.List(ctx, uploadPartDir+"/")
+	assert.NoError(t, err)
+	assert.Empty(t, blobs)
+	_, err = r.GetUpload(ctx, u.ID)
+	assert.ErrorIs(t, err, sql.ErrNoRows)
+}
+
+func TestCreateUpload_Limits(t *testing.T) {
+	ctx := context.Background()
+	r := newTestRepo(t)
+	size := r.maxAnonymousUploadSize + 1
+
+	// only authenticated callers get past the anonymous limit
+	_, err := r.CreateUpload(ctx, UploadOptions{Name: "a.txt", Size: size, Uploader: "1.2.3.4"})
+	assert.ErrorIs(t, err, ErrTooLarge)
+	_, err = r.CreateUpload(ctx, UploadOptions{Name: "a.txt", Size: size, Uploader: "1.2.3.4", MaxSize: size})
+	assert.ErrorIs(t, err, ErrTooLarge)
+	_, err = r.CreateUpload(ctx, UploadOptions{Name: "a.txt", Size: size, Uploader: "api:a", Authenticated: true})
+	assert.NoError(t, err)
+	_, err = r.CreateUpload(ctx, UploadOptions{Name: "a.txt", Size: size, Uploader: "api:a", Authenticated: true, MaxSize: size - 1})
+	assert.ErrorIs(t, err, ErrTooLarge)
+}
+
+func TestCreateUpload_OpenLimit(t *testing.T) {
+	ctx := context.Background()
+	r := newTestRepo(t)
+
+	var first *Upload
+	for i := int64(0); i < r.maxOpenUploads; i++ {
+		u, err := r.CreateUpload(ctx, UploadOptions{Name: "a.txt", Size: 10, Uploader: "1.2.3.4"})
+		assert.NoError(t, err)
+		if first == nil {
+			first = u
+		}
+	}
+	_, err := r.CreateUpload(ctx, UploadOptions{Name: "a.txt", Size: 10, Uploader: "1.2.3.4"})
+	assert.ErrorIs(t, err, ErrTooManyUploads)
+	_, err = r.CreateUpload(ctx, UploadOptions{Name: "a.txt", Size: 10, Uploader: "5.6.7.8"})
+	assert.NoError(t, err)
+
+	assert.NoError(t, r.AbortUpload(ctx, first.ID))
+	_, err = r.CreateUpload(ctx, UploadOptions{Name: "a.txt", Size: 10, Uploader: "1.2.3.4"})
+	assert.NoError(t, err)
+}
+
+func TestCreateUpload_ReservesQuota(t *testing.T) {
+	ctx := context.Background()
+	r := newTestRepo(t)
+
+	_, err := r.db.Exec("INSERT INTO files (uuid, url, notes, extension, size, uploader) VALUES ('x', 'drive/x', '', '', ?, 'greedy')",
+		maxUploaderStorageSize-10)
+	assert.NoError(t, err)
+
+	// the first upload's declared size counts before any of it arrives
+	u, err := r.CreateUpload(ctx, UploadOptions{Name: "a.txt", Size: 6, Uploader: "greedy"})
+	assert.NoError(t, err)
+	_, err = r.CreateUpload(ctx, UploadOptions{Name: "b.txt", Size: 6, Uploader: "greedy"})
+	assert.ErrorIs(t, err, ErrStorageFull)
+
+	// and completing it doesn't charge it twice
+	_, err = r.AppendUpload(ctx, u.ID, 0, strings.NewReader("012345"), 6)
+	assert.NoError(t, err)
+	_, err = r.CompleteUpload(ctx, u.ID)
+	assert.NoError(t, err)
+}
+
+func TestCreateUpload_ReservesGlobalQuota(t *testing.T) {
+	ctx := context.Background()
+	r := newTestRepo(t)
+
+	assert.NoError(t, r.refreshDatabaseUsage(ctx))
+	used := areaBytes(t, r, areaDatabase)
+	// leave room for one upload, and for the database to grow a little
+	_, err := r.db.Exec("UPDATE storage_usage SET bytes = ? WHERE area = ?", maxStorageSize-used-(1<<20), areaDrive)
+	assert.NoError(t, err)
+
+	_, err = r.CreateUpload(ctx, UploadOptions{Name: "a.txt", Size: 600 << 10, Uploader: "1.2.3.4"})
+	assert.NoError(t, err)
+	_, err = r.CreateUpload(ctx, UploadOptions{Name: "b.txt", Size: 600 << 10, Uploader: "5.6.7.8"})
+	assert.ErrorIs(t, err, ErrStorageFull)
+}
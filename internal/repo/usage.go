@@ -0,0 +1,221 @@
+package repo
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/btschwartz12/site/internal/repo/db"
+	"github.com/btschwartz12/site/internal/storage"
+)
+
+const (
+	areaDrive    = driveUploadDir
+	areaPictures = pictureUploadDir
+	areaDatabase = "database"
+
+	maxUploaderStorageSize = 1000 << 20 // 1 GB
+)
+
+type AreaUsage struct {
+	Area      string
+	Bytes     int64
+	UpdatedAt time.Time
+}
+
+type UploaderUsage struct {
+	Uploader string
+	Bytes    int64
+}
+
+// StorageUsage is the bytes charged to each area and uploader. Every
+// file, picture and picture variant is charged its full size, even when
+// its blob is shared, so what an upload costs doesn't depend on what
+// anyone else stored: deduplication saves disk space, not quota.
+type StorageUsage struct {
+	Areas              []AreaUsage
+	Uploaders          []UploaderUsage
+	TotalBytes         int64
+	LimitBytes         int64
+	UploaderLimitBytes int64
+}
+
+func (r *Repo) GetStorageUsage(ctx context.Context) (*StorageUsage, error) {
+	if err := r.refreshDatabaseUsage(ctx); err != nil {
+		return nil, err
+	}
+
+	q := db.New(r.db)
+	areas, err := q.GetStorageUsage(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("error getting storage usage: %w", err)
+	}
+	uploaders, err := q.GetAllUploaderUsage(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("error getting uploader usage: %w", err)
+	}
+
+	u := &StorageUsage{
+		Areas:              make([]AreaUsage, 0, len(areas)),
+		Uploaders:          make([]UploaderUsage, 0, len(uploaders)),
+		LimitBytes:         maxStorageSize,
+		UploaderLimitBytes: maxUploaderStorageSize,
+	}
+	for _, a := range areas {
+		u.Areas = append(u.Areas, AreaUsage{
+			Area:      a.Area,
+			Bytes:     a.Bytes,
+			UpdatedAt: a.UpdatedAt,
+		})
+		u.TotalBytes += a.Bytes
+	}
+	for _, up := range uploaders {
+		u.Uploaders = append(u.Uploaders, UploaderUsage{
+			Uploader: up.Uploader,
+			Bytes:    up.Bytes,
+		})
+	}
+	return u, nil
+}
+
+// checkQuota returns an error if charging uploader size more bytes
+// would exceed either the global or the per-uploader cap. Open uploads
+// count at their declared size, so parallel uploads can't each pass
+// the check and together fill the disk.
+func (r *Repo) checkQuota(ctx context.Context, uploader string, size int64) error {
+	if err := r.refreshDatabaseUsage(ctx); err != nil {
+		return err
+	}
+
+	q := db.New(r.db)
+	areas, err := q.GetStorageUsage(ctx)
+	if err != nil {
+		return fmt.Errorf("error getting storage usage: %w", err)
+	}
+	total, err := q.GetOpenUploadBytes(ctx)
+	if err != nil {
+		return fmt.Errorf("error getting open upload usage: %w", err)
+	}
+	for _, a := range areas {
+		total += a.Bytes
+	}
+	if total+size > maxStorageSize {
+		r.logger.Errorw("storage full", "size", total, "requested", size)
+		return errorf(ErrStorageFull, "storage full")
+	}
+
+	used, err := q.GetUploaderUsage(ctx, uploader)
+	if err != nil {
+		return fmt.Errorf("error getting uploader usage: %w", err)
+	}
+	open, err := q.GetUploaderOpenUploads(ctx, uploader)
+	if err != nil {
+		return fmt.Errorf("error getting uploader open uploads: %w", err)
+	}
+	used += open.Bytes
+	if used+size > maxUploaderStorageSize {
+		r.logger.Warnw("uploader quota exceeded", "uploader", uploader, "size", used, "requested", size)
+		return errorf(ErrStorageFull, "storage full: uploader quota exceeded (max %d MB)", maxUploaderStorageSize>>20)
+	}
+	return nil
+}
+
+func (r *Repo) refreshDatabaseUsage(ctx context.Context) error {
+	var pageCount, pageSize int64
+	if err := r.db.QueryRowContext(ctx, "PRAGMA page_count").Scan(&pageCount); err != nil {
+		return fmt.Errorf("error getting database page count: %w", err)
+	}
+	if err := r.db.QueryRowContext(ctx, "PRAGMA page_size").Scan(&pageSize); err != nil {
+		return fmt.Errorf("error getting database page size: %w", err)
+	}
+
+	q := db.New(r.db)
+	err := q.SetStorageUsage(ctx, db.SetStorageUsageParams{
+		Area:  areaDatabase,
+		Bytes: pageCount * pageSize,
+	})
+	if err != nil {
+		return fmt.Errorf("error updating database usage: %w", err)
+	}
+	return nil
+}
+
+// reconcileStorageUsage recomputes the per-area byte counters from
+// the blob store, charging every row that references a blob, and
+// backfills the size of any row that disagrees.
+func (r *Repo) reconcileStorageUsage(ctx context.Context) error {
+	q := db.New(r.db)
+
+	files, err := q.GetAllFiles(ctx)
+	if err != nil {
+		return fmt.Errorf("error getting files: %w", err)
+	}
+	var driveBytes int64
+	for _, f := range files {
+		size, err := r.blobSize(ctx, f.Url)
+		if err != nil {
+			return err
+		}
+		if size != f.Size {
+			err = q.UpdateFileSize(ctx, db.UpdateFileSizeParams{Size: size, Uuid: f.Uuid})
+			if err != nil {
+				return fmt.Errorf("error updating file size: %w", err)
+			}
+		}
+		driveBytes += size
+	}
+
+	pictures, err := q.GetAllPictures(ctx)
+	if err != nil {
+		return fmt.Errorf("error getting pictures: %w", err)
+	}
+	var pictureBytes int64
+	for _, p := range pictures {
+		size, err := r.blobSize(ctx, p.Url)
+		if err != nil {
+			return err
+		}
+		if size != p.Size {
+			err = q.UpdatePictureSize(ctx, db.UpdatePictureSizeParams{Size: size, ID: p.ID})
+			if err != nil {
+				return fmt.Errorf("error updating picture size: %w", err)
+			}
+		}
+		pictureBytes += size
+	}
+	variants, err := q.GetAllPictureVariants(ctx)
+	if err != nil {
+		return fmt.Errorf("error getting picture variants: %w", err)
+	}
+	for _, v := range variants {
+		size, err := r.blobSize(ctx, v.Key)
+		if err != nil {
+			return err
+		}
+		pictureBytes += size
+	}
+
+	for area, bytes := range map[string]int64{areaDrive: driveBytes, areaPictures: pictureBytes} {
+		err := q.SetStorageUsage(ctx, db.SetStorageUsageParams{Area: area, Bytes: bytes})
+		if err != nil {
+			return fmt.Errorf("error updating %s usage: %w", area, err)
+		}
+	}
+	r.logger.Infow("reconciled storage usage", "drive", driveBytes, "pictures", pictureBytes)
+
+	return r.refreshDatabaseUsage(ctx)
+}
+
+// blobSize returns the stored size of a blob, or 0 if it is missing.
+func (r *Repo) blobSize(ctx context.Context, key string) (int64, error) {
+	info, err := r.blobs.Stat(ctx, key)
+	if err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			r.logger.Warnw("blob missing during reconciliation", "key", key)
+			return 0, nil
+		}
+		return 0, fmt.Errorf("error getting blob info: %w", err)
+	}
+	return info.Size, nil
+}
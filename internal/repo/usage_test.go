@@ -0,0 +1,183 @@
+package repo
+
+import (
+	"bytes"
+	"context"
+	"image"
+	"image/png"
+	"mime/multipart"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+)
+
+func newTestRepo(t *testing.T) *Repo {
+	r, err := NewRepo(zap.NewNop().Sugar(), t.TempDir())
+	assert.NoError(t, err)
+	t.Cleanup(func() { r.db.Close() })
+	return r
+}
+
+func newTestUpload(t *testing.T, name string, content string) (multipart.File, *multipart.FileHeader) {
+	p := filepath.Join(t.TempDir(), name)
+	assert.NoError(t, os.WriteFile(p, []byte(content), 0644))
+	f, err := os.Open(p)
+	assert.NoError(t, err)
+	t.Cleanup(func() { f.Close() })
+	return f, &multipart.FileHeader{Filename: name, Size: int64(len(content))}
+}
+
+// testPng returns a small PNG, different for each seed.
+func testPng(t *testing.T, seed uint8) string {
+	m := image.NewNRGBA(image.Rect(0, 0, 8, 8))
+	for i := range m.Pix {
+		m.Pix[i] = seed + uint8(i)
+	}
+	var buf bytes.Buffer
+	assert.NoError(t, png.Encode(&buf, m))
+	return buf.String()
+}
+
+func pictureBytes(p *Picture) int64 {
+	n := p.Size
+	for _, v := range p.Variants {
+		n += v.Size
+	}
+	return n
+}
+
+func areaBytes(t *testing.T, r *Repo, area string) int64 {
+	u, err := r.GetStorageUsage(context.Background())
+	assert.NoError(t, err)
+	for _, a := range u.Areas {
+		if a.Area == area {
+			return a.Bytes
+		}
+	}
+	return 0
+}
+
+func TestStorageUsage_InsertDelete(t *testing.T) {
+	ctx := context.Background()
+	r := newTestRepo(t)
+
+	file, header := newTestUpload(t, "a.png", testPng(t, 0))
+	p, err := r.InsertPicture(ctx, file, header, "author", "desc", "1.2.3.4")
+	assert.NoError(t, err)
+	assert.Equal(t, header.Size, p.Size)
+	assert.Equal(t, pictureBytes(p), areaBytes(t, r, areaPictures))
+
+	file, header = newTestUpload(t, "notes.txt", "some notes")
+	_, err = r.InsertFile(ctx, file, header, "notes", "1.2.3.4", 0)
+	assert.NoError(t, err)
+	assert.Equal(t, header.Size, areaBytes(t, r, areaDrive))
+
+	u, err := r.GetStorageUsage(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, []UploaderUsage{{Uploader: "1.2.3.4", Bytes: pictureBytes(p) + header.Size}}, u.Uploaders)
+	assert.Greater(t, areaBytes(t, r, areaDatabase), int64(0))
+
+	assert.NoError(t, r.PurgePicture(ctx, strconv.FormatInt(p.ID, 10)))
+	assert.Equal(t, int64(0), areaBytes(t, r, areaPictures))
+}
+
+func TestStorageUsage_Reconcile(t *testing.T) {
+	ctx := context.Background()
+	r := newTestRepo(t)
+
+	file, header := newTestUpload(t, "a.png", testPng(t, 0))
+	p, err := r.InsertPicture(ctx, file, header, "author", "desc", "1.2.3.4")
+	assert.NoError(t, err)
+
+	// simulate a drifted counter
+	_, err = r.db.Exec("UPDATE storage_usage SET bytes = 0; UPDATE pictures SET size = 0")
+	assert.NoError(t, err)
+
+	assert.NoError(t, r.reconcileStorageUsage(ctx))
+	assert.Equal(t, pictureBytes(p), areaBytes(t, r, areaPictures))
+	pictures, err := r.GetAllPictures(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, header.Size, pictures[0].Size)
+}
+
+func TestStorageUsage_UploaderQuota(t *testing.T) {
+	ctx := context.Background()
+	r := newTestRepo(t)
+
+	_, err := r.db.Exec("INSERT INTO files (uuid, url, notes, extension, size, uploader) VALUES ('x', 'drive/x', '', '', ?, 'greedy')",
+		maxUploaderStorageSize-5)
+	assert.NoError(t, err)
+
+	file, header := newTestUpload(t, "a.png", testPng(t, 0))
+	_, err = r.InsertPicture(ctx, file, header, "author", "desc", "greedy")
+	assert.Error(t, err)
+	assert.True(t, strings.Contains(err.Error(), "storage full"))
+
+	_, err = r.InsertPicture(ctx, file, header, "author", "desc", "someone else")
+	assert.NoError(t, err)
+}
+
+func TestStorageUsage_ChargesEveryReference(t *testing.T) {
+	ctx := context.Background()
+	r := newTestRepo(t)
+
+	file, header := newTestUpload(t, "a.jpg", testJpeg(t, 700, 400))
+	p, err := r.InsertPicture(ctx, file, header, "author", "desc", "alice")
+	assert.NoError(t, err)
+	assert.NotEmpty(t, p.Variants)
+	file, header = newTestUpload(t, "a.jpg", testJpeg(t, 700, 400))
+	_, err = r.InsertPicture(ctx, file, header, "author", "desc", "bob")
+	assert.NoError(t, err)
+
+	// the blobs are shared, but each picture and its variants are
+	// charged in full
+	assert.Equal(t, 2*pictureBytes(p), areaBytes(t, r, areaPictures))
+	u, err := r.GetStorageUsage(ctx)
+	assert.NoError(t, err)
+	assert.ElementsMatch(t, []UploaderUsage{
+		{Uploader: "alice", Bytes: pictureBytes(p)},
+		{Uploader: "bob", Bytes: pictureBytes(p)},
+	}, u.Uploaders)
+
+	assert.NoError(t, r.reconcileStorageUsage(ctx))
+	assert.Equal(t, 2*pictureBytes(p), areaBytes(t, r, areaPictures))
+
+	// a picture that fits but whose variants don't is turned away
+	// whole
+	_, err = r.db.Exec("INSERT INTO files (uuid, url, notes, extension, size, uploader) VALUES ('x', 'drive/x', '', '', ?, 'carol')",
+		maxUploaderStorageSize-p.Size)
+	assert.NoError(t, err)
+	file, header = newTestUpload(t, "a.jpg", testJpeg(t, 700, 400))
+	_, err = r.InsertPicture(ctx, file, header, "author", "desc", "carol")
+	assert.ErrorIs(t, err, ErrStorageFull)
+}
+
+func TestBackfills_RunOnce(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	r, err := NewRepo(zap.NewNop().Sugar(), dir)
+	assert.NoError(t, err)
+	var done int
+	assert.NoError(t, r.db.QueryRow("SELECT COUNT(*) FROM backfills").Scan(&done))
+	assert.Equal(t, 3, done)
+
+	// the backfills aren't run again, but storage usage is reconciled
+	// on every start
+	_, err = r.db.Exec("DELETE FROM backfills WHERE name = 'picture_variants'")
+	assert.NoError(t, err)
+	_, err = r.db.Exec("UPDATE storage_usage SET bytes = 123 WHERE area = ?", areaDrive)
+	assert.NoError(t, err)
+	assert.NoError(t, r.Close(ctx))
+
+	r, err = NewRepo(zap.NewNop().Sugar(), dir)
+	assert.NoError(t, err)
+	t.Cleanup(func() { r.db.Close() })
+	assert.NoError(t, r.db.QueryRow("SELECT COUNT(*) FROM backfills").Scan(&done))
+	assert.Equal(t, 3, done)
+	assert.Equal(t, int64(0), areaBytes(t, r, areaDrive))
+}
@@ -5,6 +5,8 @@ import (
 	"database/sql"
 	"fmt"
 	"net/http"
+	"strconv"
+	"time"
 
 	"github.com/btschwartz12/site/internal/ipdata"
 	"github.com/btschwartz12/site/internal/repo/db"
@@ -54,12 +56,55 @@ func (r *Repo) RecordVisitor(ctx context.Context, req *http.Request, message str
 	return nil
 }
 
-func (r *Repo) GetAllVisitors(ctx context.Context) ([]Visitor, error) {
+// RecordVisitorAsync records a visitor in the background, for
+// handlers that shouldn't wait on it.
+func (r *Repo) RecordVisitorAsync(req *http.Request, message string, slackBlocks []slack.Block) {
+	r.Go(func() {
+		r.RecordVisitor(context.Background(), req, message, slackBlocks)
+	})
+}
+
+// VisitorFilter selects the visitors to list.
+type VisitorFilter struct {
+	ListOptions
+	Path    string
+	Country string
+}
+
+// ListVisitors returns a page of visitors matching f and the cursor
+// for the next page, which is empty on the last one.
+func (r *Repo) ListVisitors(ctx context.Context, f VisitorFilter) ([]Visitor, string, error) {
+	b, err := f.bounds()
+	if err != nil {
+		return nil, "", err
+	}
+	cursorID, err := b.cursorID()
+	if err != nil {
+		return nil, "", err
+	}
+	params := db.ListVisitorsDescParams{
+		CursorPit: b.cursorPit,
+		CursorID:  cursorID,
+		Since:     b.since,
+		Until:     b.until,
+		Path:      nullIfEmpty(f.Path),
+		Country:   nullIfEmpty(f.Country),
+		Limit:     b.limit,
+	}
+
 	q := db.New(r.db)
-	rows, err := q.GetAllVisitors(ctx)
+	var rows []db.Visitor
+	if f.Ascending {
+		rows, err = q.ListVisitorsAsc(ctx, db.ListVisitorsAscParams(params))
+	} else {
+		rows, err = q.ListVisitorsDesc(ctx, params)
+	}
 	if err != nil {
-		return nil, fmt.Errorf("error getting all visitors: %w", err)
+		return nil, "", fmt.Errorf("error listing visitors: %w", err)
 	}
+	rows, next := page(rows, b, func(v db.Visitor) (time.Time, string) {
+		return v.Pit, strconv.FormatInt(v.ID, 10)
+	})
 	visitors := make([]Visitor, 0, len(rows))
 	for _, v := range rows {
 		visitors = append(visitors, Visitor{
@@ -73,5 +118,5 @@ func (r *Repo) GetAllVisitors(ctx context.Context) ([]Visitor, error) {
 			Pit:     v.Pit.String(),
 		})
 	}
-	return visitors, nil
+	return visitors, next, nil
 }
@@ -0,0 +1,114 @@
+package repo
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strconv"
+
+	"github.com/btschwartz12/site/internal/repo/db"
+)
+
+// Voter identifies who is voting on pictures by a cookie they keep.
+// The hash of their IP is recorded with their votes, but never decides
+// whose a vote is, since many people can share one address.
+type Voter struct {
+	ID     string
+	IpHash string
+}
+
+// VotePicture records v's like (1) or dislike (-1) of a picture,
+// replacing any earlier vote of theirs, or retracts their vote if it
+// was already the same. It returns the vote v now has on the picture,
+// zero if none.
+func (r *Repo) VotePicture(ctx context.Context, idStr string, v Voter, vote int64) (int64, error) {
+	if vote != 1 && vote != -1 {
+		return 0, errorf(ErrInvalidInput, "invalid vote: %d", vote)
+	}
+	if v.ID == "" || v.IpHash == "" {
+		return 0, errorf(ErrInvalidInput, "invalid voter")
+	}
+	id, err := strconv.ParseInt(idStr, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("%w: %w", ErrInvalidID, err)
+	}
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, fmt.Errorf("error beginning transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	q := db.New(tx)
+	picture, err := q.GetPicture(ctx, id)
+	if err != nil {
+		return 0, fmt.Errorf("error getting picture: %w", err)
+	}
+	if picture.Status != PictureApproved {
+		// pictures outside the gallery can't be voted on
+		return 0, fmt.Errorf("error getting picture: %w", sql.ErrNoRows)
+	}
+
+	existing, err := q.GetPictureVoteByVoter(ctx, db.GetPictureVoteByVoterParams{PictureID: id, Voter: v.ID})
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		err = q.InsertPictureVote(ctx, db.InsertPictureVoteParams{
+			PictureID: id,
+			Voter:     v.ID,
+			IpHash:    v.IpHash,
+			Vote:      vote,
+		})
+	case err != nil:
+		return 0, fmt.Errorf("error getting vote: %w", err)
+	case existing.Vote == vote:
+		err = q.DeletePictureVote(ctx, existing.ID)
+		vote = 0
+	default:
+		err = q.UpdatePictureVote(ctx, db.UpdatePictureVoteParams{
+			Voter:  v.ID,
+			IpHash: v.IpHash,
+			Vote:   vote,
+			ID:     existing.ID,
+		})
+	}
+	if err != nil {
+		return 0, fmt.Errorf("error recording vote: %w", err)
+	}
+
+	if err := q.RefreshPictureVoteCounts(ctx, id); err != nil {
+		return 0, fmt.Errorf("error counting votes: %w", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("error committing transaction: %w", err)
+	}
+	return vote, nil
+}
+
+// GetVoterVotes returns v's votes by picture id.
+func (r *Repo) GetVoterVotes(ctx context.Context, v Voter) (map[int64]int64, error) {
+	q := db.New(r.db)
+	rows, err := q.GetVoterVotes(ctx, v.ID)
+	if err != nil {
+		return nil, fmt.Errorf("error getting votes: %w", err)
+	}
+	votes := make(map[int64]int64, len(rows))
+	for _, row := range rows {
+		votes[row.PictureID] = row.Vote
+	}
+	return votes, nil
+}
+
+// reconcilePictureVotes recounts likes and dislikes from the votes
+// behind them, in case they have drifted.
+func (r *Repo) reconcilePictureVotes(ctx context.Context) error {
+	q := db.New(r.db)
+	n, err := q.ReconcilePictureVoteCounts(ctx)
+	if err != nil {
+		return fmt.Errorf("error reconciling picture votes: %w", err)
+	}
+	if n > 0 {
+		r.logger.Warnw("reconciled picture vote counts", "pictures", n)
+	}
+	return nil
+}
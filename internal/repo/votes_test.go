@@ -0,0 +1,79 @@
+package repo
+
+import (
+	"context"
+	"database/sql"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestVotePicture(t *testing.T) {
+	ctx := context.Background()
+	r := newTestRepo(t)
+	r.autoApprove = newAutoApproveRules("", "1.2.3.4")
+
+	file, header := newTestUpload(t, "a.png", testPng(t, 0))
+	p, err := r.InsertPicture(ctx, file, header, "author", "desc", "1.2.3.4")
+	assert.NoError(t, err)
+	id := strconv.FormatInt(p.ID, 10)
+
+	counts := func() (int64, int64) {
+		p, err := r.GetPicture(ctx, id+".png")
+		assert.NoError(t, err)
+		return p.NumLikes, p.NumDislikes
+	}
+
+	// voting twice takes the vote back rather than counting it again
+	alice := Voter{ID: "alice", IpHash: "ip1"}
+	v, err := r.VotePicture(ctx, id, alice, 1)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), v)
+	v, err = r.VotePicture(ctx, id, alice, 1)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(0), v)
+	likes, dislikes := counts()
+	assert.Equal(t, int64(0), likes)
+	assert.Equal(t, int64(0), dislikes)
+
+	// changing a vote moves it
+	_, err = r.VotePicture(ctx, id, alice, 1)
+	assert.NoError(t, err)
+	_, err = r.VotePicture(ctx, id, alice, -1)
+	assert.NoError(t, err)
+	likes, dislikes = counts()
+	assert.Equal(t, int64(0), likes)
+	assert.Equal(t, int64(1), dislikes)
+
+	// someone else behind the same IP votes separately, and can't see
+	// or change alice's vote
+	v, err = r.VotePicture(ctx, id, Voter{ID: "carol", IpHash: "ip1"}, -1)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(-1), v)
+	likes, dislikes = counts()
+	assert.Equal(t, int64(0), likes)
+	assert.Equal(t, int64(2), dislikes)
+	votes, err := r.GetVoterVotes(ctx, Voter{ID: "dave", IpHash: "ip1"})
+	assert.NoError(t, err)
+	assert.Empty(t, votes)
+
+	// counts set through the api are kept as votes come in
+	_, err = r.UpdateLikesOfPicture(ctx, id, 10, 5)
+	assert.NoError(t, err)
+	_, err = r.VotePicture(ctx, id, Voter{ID: "bob", IpHash: "ip2"}, 1)
+	assert.NoError(t, err)
+	votes, err = r.GetVoterVotes(ctx, Voter{ID: "bob"})
+	assert.NoError(t, err)
+	assert.Equal(t, map[int64]int64{p.ID: 1}, votes)
+
+	_, err = r.db.ExecContext(ctx, "UPDATE pictures SET num_likes = 0")
+	assert.NoError(t, err)
+	assert.NoError(t, r.reconcilePictureVotes(ctx))
+	likes, dislikes = counts()
+	assert.Equal(t, int64(11), likes)
+	assert.Equal(t, int64(5), dislikes)
+
+	_, err = r.VotePicture(ctx, "999999", alice, 1)
+	assert.ErrorIs(t, err, sql.ErrNoRows)
+}
@@ -6,6 +6,8 @@ import (
 	"time"
 
 	"go.uber.org/zap"
+
+	"github.com/btschwartz12/site/internal/metrics"
 )
 
 var (
@@ -29,11 +31,14 @@ func SendAlert(logger *zap.SugaredLogger, title string, blocks []Block) {
 		select {
 		case err := <-errChan:
 			if err != nil {
+				metrics.SlackWebhooks.WithLabelValues("failure").Inc()
 				logger.Errorw("failed to send message to Slack", "error", err, "blocks", blocks)
 			} else {
+				metrics.SlackWebhooks.WithLabelValues("success").Inc()
 				logger.Infow("sent message to slack")
 			}
 		case <-ctx.Done():
+			metrics.SlackWebhooks.WithLabelValues("failure").Inc()
 			logger.Errorw("timed out sending message to slack", "error", ctx.Err())
 		}
 	}()
@@ -0,0 +1,50 @@
+package storage
+
+import (
+	"fmt"
+
+	env "github.com/Netflix/go-env"
+)
+
+type config struct {
+	Backend           string `env:"BLOB_BACKEND,default=local"`
+	S3Endpoint        string `env:"S3_ENDPOINT"`
+	S3Region          string `env:"S3_REGION,default=us-east-1"`
+	S3Bucket          string `env:"S3_BUCKET"`
+	S3AccessKeyID     string `env:"S3_ACCESS_KEY_ID"`
+	S3SecretAccessKey string `env:"S3_SECRET_ACCESS_KEY"`
+	S3PathStyle       bool   `env:"S3_PATH_STYLE,default=true"`
+}
+
+func newConfig() (*config, error) {
+	conf := config{}
+	if _, err := env.UnmarshalFromEnviron(&conf); err != nil {
+		return nil, fmt.Errorf("failed to load config: %w", err)
+	}
+	return &conf, nil
+}
+
+// New returns the BlobStore selected by the BLOB_BACKEND env var,
+// rooted at localDir when the local backend is used.
+func New(localDir string) (BlobStore, error) {
+	conf, err := newConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	switch conf.Backend {
+	case "local":
+		return NewLocal(localDir)
+	case "s3":
+		return NewS3(S3Options{
+			Endpoint:        conf.S3Endpoint,
+			Region:          conf.S3Region,
+			Bucket:          conf.S3Bucket,
+			AccessKeyID:     conf.S3AccessKeyID,
+			SecretAccessKey: conf.S3SecretAccessKey,
+			PathStyle:       conf.S3PathStyle,
+		})
+	default:
+		return nil, fmt.Errorf("unknown blob backend: %s", conf.Backend)
+	}
+}
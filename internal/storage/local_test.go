@@ -0,0 +1,77 @@
+package storage
+
+import (
+	"context"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLocal_RoundTrip(t *testing.T) {
+	ctx := context.Background()
+	l, err := NewLocal(t.TempDir())
+	assert.NoError(t, err)
+
+	content := "hello from the drive"
+	assert.NoError(t, l.Put(ctx, "drive/a.txt", strings.NewReader(content), int64(len(content))))
+
+	info, err := l.Stat(ctx, "drive/a.txt")
+	assert.NoError(t, err)
+	assert.Equal(t, int64(len(content)), info.Size)
+
+	rc, err := l.Open(ctx, "drive/a.txt", 6, 4)
+	assert.NoError(t, err)
+	data, _ := io.ReadAll(rc)
+	rc.Close()
+	assert.Equal(t, "from", string(data))
+
+	assert.NoError(t, l.Delete(ctx, "drive/a.txt"))
+	_, err = l.Get(ctx, "drive/a.txt")
+	assert.ErrorIs(t, err, ErrNotFound)
+}
+
+func TestLocal_InvalidKey(t *testing.T) {
+	ctx := context.Background()
+	l, err := NewLocal(t.TempDir())
+	assert.NoError(t, err)
+
+	for _, key := range []string{"", "../site.db", "/etc/passwd", "drive/../../x"} {
+		err := l.Put(ctx, key, strings.NewReader("x"), 1)
+		assert.ErrorIs(t, err, ErrInvalidKey, key)
+	}
+}
+
+func TestLocal_ShortWrite(t *testing.T) {
+	ctx := context.Background()
+	l, err := NewLocal(t.TempDir())
+	assert.NoError(t, err)
+
+	err = l.Put(ctx, "drive/a.txt", strings.NewReader("abc"), 10)
+	assert.Error(t, err)
+	_, err = l.Stat(ctx, "drive/a.txt")
+	assert.ErrorIs(t, err, ErrNotFound)
+}
+
+func TestLocal_List(t *testing.T) {
+	ctx := context.Background()
+	l, err := NewLocal(t.TempDir())
+	assert.NoError(t, err)
+
+	for _, key := range []string{"drive/a.txt", "drive/sub/b.txt", "pictures/c.png", "site.db"} {
+		assert.NoError(t, l.Put(ctx, key, strings.NewReader("x"), 1))
+	}
+
+	blobs, err := l.List(ctx, "drive/")
+	assert.NoError(t, err)
+	keys := []string{}
+	for _, b := range blobs {
+		keys = append(keys, b.Key)
+	}
+	assert.ElementsMatch(t, []string{"drive/a.txt", "drive/sub/b.txt"}, keys)
+
+	blobs, err = l.List(ctx, "missing/")
+	assert.NoError(t, err)
+	assert.Empty(t, blobs)
+}
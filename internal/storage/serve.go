@@ -0,0 +1,206 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"io"
+	"mime"
+	"net/http"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// blobReadSeeker adapts a BlobStore blob to an io.ReadSeeker,
+// opening a ranged reader lazily from the current offset so that
+// seeking (as http.ServeContent does) costs nothing until a read.
+type blobReadSeeker struct {
+	ctx    context.Context
+	store  BlobStore
+	key    string
+	size   int64
+	offset int64
+	rc     io.ReadCloser
+}
+
+func newBlobReadSeeker(ctx context.Context, store BlobStore, key string, size int64) *blobReadSeeker {
+	return &blobReadSeeker{
+		ctx:   ctx,
+		store: store,
+		key:   key,
+		size:  size,
+	}
+}
+
+func (b *blobReadSeeker) Read(p []byte) (int, error) {
+	if b.offset >= b.size {
+		return 0, io.EOF
+	}
+	if b.rc == nil {
+		rc, err := b.store.Open(b.ctx, b.key, b.offset, -1)
+		if err != nil {
+			return 0, err
+		}
+		b.rc = rc
+	}
+	n, err := b.rc.Read(p)
+	b.offset += int64(n)
+	return n, err
+}
+
+func (b *blobReadSeeker) Seek(offset int64, whence int) (int64, error) {
+	var abs int64
+	switch whence {
+	case io.SeekStart:
+		abs = offset
+	case io.SeekCurrent:
+		abs = b.offset + offset
+	case io.SeekEnd:
+		abs = b.size + offset
+	default:
+		return 0, errors.New("invalid whence")
+	}
+	if abs < 0 {
+		return 0, errors.New("negative position")
+	}
+	if abs != b.offset {
+		b.Close()
+		b.offset = abs
+	}
+	return abs, nil
+}
+
+func (b *blobReadSeeker) Close() error {
+	if b.rc == nil {
+		return nil
+	}
+	err := b.rc.Close()
+	b.rc = nil
+	return err
+}
+
+// Content describes a blob to serve using metadata recorded when
+// it was stored, so serving it doesn't need to stat the store.
+type Content struct {
+	Key         string
+	Size        int64
+	ModTime     time.Time
+	ContentType string
+	// Sha256 is the hex digest of the blob, used as a strong ETag.
+	Sha256 string
+	// Name is the filename offered to the client; it is omitted
+	// from the Content-Disposition header if empty.
+	Name string
+	// Inline asks the browser to display the blob rather than
+	// download it.
+	Inline bool
+}
+
+// ServeContent writes the blob described by c to w with its
+// Content-Type, ETag and Content-Disposition set, handling Range and
+// conditional requests via http.ServeContent.
+func ServeContent(w http.ResponseWriter, r *http.Request, store BlobStore, c Content) {
+	if c.ContentType != "" {
+		w.Header().Set("Content-Type", c.ContentType)
+	}
+	if c.Sha256 != "" {
+		w.Header().Set("ETag", `"`+c.Sha256+`"`)
+	}
+	disposition := "attachment"
+	if c.Inline {
+		disposition = "inline"
+	}
+	params := map[string]string{}
+	if c.Name != "" {
+		params["filename"] = c.Name
+	}
+	if v := mime.FormatMediaType(disposition, params); v != "" {
+		w.Header().Set("Content-Disposition", v)
+	}
+
+	rs := newBlobReadSeeker(r.Context(), store, c.Key, c.Size)
+	defer rs.Close()
+
+	http.ServeContent(w, r, path.Base(c.Key), c.ModTime, rs)
+}
+
+// IsDownloadStart reports whether serving c for a request with the
+// given Range and If-Range headers sends its first byte, so that range
+// requests resuming a download aren't counted as separate downloads.
+// The headers are read the way http.ServeContent reads them, so
+// anything that gets the start of the file counts, including suffix
+// ranges, multiple ranges and ranges it ignores.
+func IsDownloadStart(rangeHeader, ifRange string, c Content) bool {
+	if rangeHeader == "" || !ifRangeMatches(ifRange, c) {
+		return true
+	}
+
+	const prefix = "bytes="
+	if !strings.HasPrefix(rangeHeader, prefix) {
+		return true
+	}
+	var sum int64
+	for _, ra := range strings.Split(rangeHeader[len(prefix):], ",") {
+		ra = strings.TrimSpace(ra)
+		if ra == "" {
+			continue
+		}
+		start, end, ok := strings.Cut(ra, "-")
+		if !ok {
+			return true
+		}
+		start, end = strings.TrimSpace(start), strings.TrimSpace(end)
+		if start == "" {
+			// a suffix range, the last n bytes
+			n, err := strconv.ParseInt(end, 10, 64)
+			if err != nil || n < 0 {
+				return true
+			}
+			if n >= c.Size {
+				return true
+			}
+			sum += n
+			continue
+		}
+		i, err := strconv.ParseInt(start, 10, 64)
+		if err != nil || i < 0 {
+			return true
+		}
+		if i >= c.Size {
+			// doesn't overlap the file, so sends nothing
+			continue
+		}
+		if i == 0 {
+			return true
+		}
+		last := c.Size - 1
+		if end != "" {
+			j, err := strconv.ParseInt(end, 10, 64)
+			if err != nil || i > j {
+				return true
+			}
+			last = min(j, last)
+		}
+		sum += last - i + 1
+	}
+	// ranges adding up to more than the file are ignored, and the
+	// whole file is sent
+	return sum > c.Size
+}
+
+// ifRangeMatches reports whether the Range header applies, given the
+// If-Range header. If it doesn't, the whole file is sent.
+func ifRangeMatches(ifRange string, c Content) bool {
+	if ifRange == "" {
+		return true
+	}
+	if strings.HasPrefix(ifRange, `"`) {
+		return c.Sha256 != "" && ifRange == `"`+c.Sha256+`"`
+	}
+	t, err := http.ParseTime(ifRange)
+	if err != nil || c.ModTime.IsZero() {
+		return false
+	}
+	return c.ModTime.Truncate(time.Second).Equal(t)
+}
@@ -0,0 +1,100 @@
+package storage
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestServeContent(t *testing.T) {
+	ctx := context.Background()
+	l, err := NewLocal(t.TempDir())
+	assert.NoError(t, err)
+
+	content := "0123456789"
+	assert.NoError(t, l.Put(ctx, "drive/x.txt", strings.NewReader(content), int64(len(content))))
+	c := Content{
+		Key:         "drive/x.txt",
+		Size:        int64(len(content)),
+		ModTime:     time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC),
+		ContentType: "text/plain; charset=utf-8",
+		Sha256:      "abc123",
+		Name:        "résumé final.txt",
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	w := httptest.NewRecorder()
+	ServeContent(w, req, l, c)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, content, w.Body.String())
+	assert.Equal(t, `"abc123"`, w.Header().Get("ETag"))
+	assert.Equal(t, "text/plain; charset=utf-8", w.Header().Get("Content-Type"))
+	assert.Equal(t, "Tue, 02 Jan 2024 03:04:05 GMT", w.Header().Get("Last-Modified"))
+	assert.Equal(t, "attachment; filename*=utf-8''r%C3%A9sum%C3%A9%20final.txt", w.Header().Get("Content-Disposition"))
+
+	req = httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("If-None-Match", `"abc123"`)
+	w = httptest.NewRecorder()
+	ServeContent(w, req, l, c)
+	assert.Equal(t, http.StatusNotModified, w.Code)
+
+	req = httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("Range", "bytes=2-4")
+	req.Header.Set("If-Range", `"abc123"`)
+	w = httptest.NewRecorder()
+	ServeContent(w, req, l, c)
+	assert.Equal(t, http.StatusPartialContent, w.Code)
+	assert.Equal(t, "234", w.Body.String())
+
+	c.Inline = true
+	w = httptest.NewRecorder()
+	ServeContent(w, httptest.NewRequest(http.MethodGet, "/", nil), l, c)
+	assert.True(t, strings.HasPrefix(w.Header().Get("Content-Disposition"), "inline;"))
+}
+
+func TestIsDownloadStart(t *testing.T) {
+	c := Content{
+		Size:    10,
+		ModTime: time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC),
+		Sha256:  "abc123",
+	}
+	tests := []struct {
+		rng     string
+		ifRange string
+		want    bool
+	}{
+		{"", "", true},
+		{"bytes=0-", "", true},
+		{"bytes=0-0", "", true},
+		{"bytes=1-", "", false},
+		{"bytes=5-9", "", false},
+		// suffix ranges start at byte 0 once they cover the file
+		{"bytes=-3", "", false},
+		{"bytes=-10", "", true},
+		{"bytes=-100", "", true},
+		// multiple ranges
+		{"bytes=1-2,0-0", "", true},
+		{"bytes=1-2,4-5", "", false},
+		{"bytes=1-9,1-9", "", true},
+		{"bytes=1-2, -20", "", true},
+		// nothing in the file
+		{"bytes=10-", "", false},
+		// malformed ranges are ignored
+		{"bytes=x-", "", true},
+		{"bytes=5-2", "", true},
+		{"items=1-", "", true},
+		// a stale If-Range gets the whole file
+		{"bytes=1-", `"abc123"`, false},
+		{"bytes=1-", `"other"`, true},
+		{"bytes=1-", "Tue, 02 Jan 2024 03:04:05 GMT", false},
+		{"bytes=1-", "Wed, 03 Jan 2024 03:04:05 GMT", true},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, IsDownloadStart(tt.rng, tt.ifRange, c), "Range %q If-Range %q", tt.rng, tt.ifRange)
+	}
+}
@@ -0,0 +1,37 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"io"
+	"time"
+)
+
+var (
+	ErrNotFound   = errors.New("blob not found")
+	ErrInvalidKey = errors.New("invalid blob key")
+)
+
+// BlobInfo is the metadata a BlobStore keeps about a stored blob.
+type BlobInfo struct {
+	Key     string
+	Size    int64
+	ModTime time.Time
+}
+
+// BlobStore stores opaque blobs under slash-separated keys
+// such as "drive/<uuid>.pdf".
+type BlobStore interface {
+	// Put stores size bytes read from r under key, replacing
+	// any existing blob.
+	Put(ctx context.Context, key string, r io.Reader, size int64) error
+	// Get returns the full contents of the blob.
+	Get(ctx context.Context, key string) (io.ReadCloser, error)
+	// Open returns length bytes of the blob starting at offset.
+	// A negative length reads until the end of the blob.
+	Open(ctx context.Context, key string, offset, length int64) (io.ReadCloser, error)
+	Stat(ctx context.Context, key string) (*BlobInfo, error)
+	Delete(ctx context.Context, key string) error
+	// List returns every blob whose key starts with prefix.
+	List(ctx context.Context, prefix string) ([]BlobInfo, error)
+}
@@ -1,16 +1,27 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"net/http"
 	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
 
 	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
 	flags "github.com/jessevdk/go-flags"
+	"github.com/prometheus/client_golang/prometheus"
 
 	"github.com/btschwartz12/site/api"
 	"github.com/btschwartz12/site/base"
 	"github.com/btschwartz12/site/drive"
+	"github.com/btschwartz12/site/internal/health"
+	"github.com/btschwartz12/site/internal/ipdata"
+	"github.com/btschwartz12/site/internal/logging"
+	"github.com/btschwartz12/site/internal/metrics"
 	"github.com/btschwartz12/site/internal/proxy"
 	"github.com/btschwartz12/site/internal/repo"
 	"github.com/btschwartz12/site/pics"
@@ -24,13 +35,35 @@ type arguments struct {
 	Port        int  `short:"p" long:"port" description:"Port to listen on" default:"8080"`
 	DevLogging  bool `short:"d" long:"dev-logging" description:"Enable development logging"`
 	EnableProxy bool `long:"enable-proxy" description:"Enable proxying to other services"`
+	MetricsPort int  `long:"metrics-port" description:"Port to serve Prometheus metrics on, separate from the public one (0 disables them)" default:"9090"`
+
+	ShutdownTimeout time.Duration `long:"shutdown-timeout" description:"How long to wait for requests and background work to finish when shutting down" default:"30s"`
+
+	JanitorInterval time.Duration `long:"janitor-interval" description:"How often to clean up expired permalinks, the trash and orphaned blobs" default:"1h"`
+	JanitorDryRun   bool          `long:"janitor-dry-run" description:"Only report what the janitor would clean up"`
+
+	Migrate migrateCommand `command:"migrate" description:"Manage database schema migrations"`
+}
+
+type migrateCommand struct {
+	Status struct{}           `command:"status" description:"Show applied and pending migrations"`
+	Up     struct{}           `command:"up" description:"Apply all pending migrations"`
+	Down   migrateDownCommand `command:"down" description:"Roll back applied migrations"`
+}
+
+type migrateDownCommand struct {
+	Steps int `short:"n" long:"steps" description:"Number of migrations to roll back" default:"1"`
 }
 
 var opts arguments
 
+const fileReaperInterval = time.Minute
+
 func main() {
 	// parse cl args
-	_, err := flags.Parse(&opts)
+	parser := flags.NewParser(&opts, flags.Default)
+	parser.SubcommandsOptional = true
+	_, err := parser.Parse()
 	if err != nil {
 		panic(fmt.Errorf("failed to parse flags: %w", err))
 	}
@@ -50,14 +83,43 @@ func main() {
 	}
 	logger := l.Sugar()
 
+	// run migrate subcommand instead of the server
+	if parser.Active != nil && parser.Active.Name == "migrate" {
+		if err := runMigrate(logger, parser.Active.Active.Name); err != nil {
+			logger.Fatalw("migrate error", "error", err)
+		}
+		return
+	}
+
+	// only believe forwarded client ips from our own proxies
+	if err := ipdata.Init(); err != nil {
+		panic(fmt.Errorf("failed to load trusted proxies: %w", err))
+	}
+
 	// set up repo
 	rpo, err := repo.NewRepo(logger, "var")
 	if err != nil {
 		panic(fmt.Errorf("failed to create repo: %w", err))
 	}
 
+	// stop on SIGINT or SIGTERM
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	// delete drive files past their ttl
+	rpo.Go(func() { rpo.RunFileReaper(ctx, fileReaperInterval) })
+
+	// clean up expired permalinks, the trash and orphaned blobs
+	rpo.Go(func() { rpo.RunJanitor(ctx, opts.JanitorInterval, opts.JanitorDryRun) })
+
 	// set up apps
 	r := chi.NewRouter()
+	r.Use(
+		middleware.RequestID,
+		logging.Middleware(logger),
+		metrics.Middleware,
+		logging.Recoverer(logger),
+	)
 	apps := map[string]app{
 		"/":       &base.BaseServer{},
 		"/poke":   &poke.PokeServer{},
@@ -66,32 +128,155 @@ func main() {
 		"/api":    &api.ApiServer{},
 		"/drive":  &drive.DriveServer{},
 	}
+	checks := []health.Check{
+		{Name: "database", Run: rpo.Ping},
+		{Name: "var_dir", Run: rpo.CheckVarDir},
+		{Name: "blobs", Run: rpo.CheckBlobs},
+	}
 	for mp, a := range apps {
 		err = a.Init(mp, logger, rpo)
 		if err != nil {
 			panic(fmt.Errorf("failed to init app: %w", err))
 		}
 		r.Mount(a.GetMountPoint(), a.GetRouter())
+		if rc, ok := a.(readinessChecker); ok {
+			checks = append(checks, rc.ReadinessChecks()...)
+		}
+	}
+	for _, a := range apps {
+		if err := a.Start(ctx); err != nil {
+			panic(fmt.Errorf("failed to start app: %w", err))
+		}
 	}
 
 	// enable proxying
 	if opts.EnableProxy {
-		r.HandleFunc("/rust*", proxy.Proxy(os.Getenv("RUST_TARGET"), "/rust"))
-		r.HandleFunc("/c*", proxy.Proxy(os.Getenv("C_TARGET"), "/c"))
+		targets := map[string]string{
+			"/rust": os.Getenv("RUST_TARGET"),
+			"/c":    os.Getenv("C_TARGET"),
+		}
+		for prefix, target := range targets {
+			r.HandleFunc(prefix+"*", proxy.Proxy(target, prefix))
+			checks = append(checks, health.Check{
+				Name: "proxy" + strings.ReplaceAll(prefix, "/", "_"),
+				Run:  func(ctx context.Context) error { return proxy.Reachable(ctx, target) },
+			})
+		}
 	}
 
+	// health checks and build info
+	r.Get("/healthz", health.Live)
+	r.Get("/readyz", health.Ready(logger, checks))
+	r.Get("/version", health.Version)
+
 	// start server
-	errChan := make(chan error)
-	go func() {
-		logger.Infow("starting server", "port", opts.Port)
-		errChan <- http.ListenAndServe(fmt.Sprintf(":%d", opts.Port), r)
-	}()
-	err = <-errChan
-	logger.Fatalw("server error", "error", err)
+	servers := []*http.Server{{
+		Addr:    fmt.Sprintf(":%d", opts.Port),
+		Handler: r,
+	}}
+
+	// prometheus metrics get their own listener, so they aren't
+	// public along with the rest of the site
+	if opts.MetricsPort != 0 {
+		prometheus.MustRegister(rpo.UsageCollector())
+		mr := chi.NewRouter()
+		mr.Handle("/metrics", metrics.Handler())
+		servers = append(servers, &http.Server{
+			Addr:    fmt.Sprintf(":%d", opts.MetricsPort),
+			Handler: mr,
+		})
+	}
+
+	errChan := make(chan error, len(servers))
+	for _, srv := range servers {
+		go func() {
+			logger.Infow("starting server", "addr", srv.Addr)
+			errChan <- srv.ListenAndServe()
+		}()
+	}
+	exitCode := 0
+	select {
+	case err := <-errChan:
+		logger.Errorw("server error", "error", err)
+		exitCode = 1
+	case <-ctx.Done():
+		logger.Infow("shutting down")
+	}
+	// a second signal kills the process right away
+	stop()
+
+	// stop taking requests, then let the apps and the repo finish up
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), opts.ShutdownTimeout)
+	defer cancel()
+	for _, srv := range servers {
+		if err := srv.Shutdown(shutdownCtx); err != nil {
+			logger.Errorw("error shutting down server", "addr", srv.Addr, "error", err)
+		}
+	}
+	for mp, a := range apps {
+		if err := a.Shutdown(shutdownCtx); err != nil {
+			logger.Errorw("error shutting down app", "app", mp, "error", err)
+		}
+	}
+	if err := rpo.Close(shutdownCtx); err != nil {
+		logger.Errorw("error closing repo", "error", err)
+	}
+	logger.Infow("shut down")
+	logger.Sync()
+	os.Exit(exitCode)
+}
+
+func runMigrate(logger *zap.SugaredLogger, cmd string) error {
+	m, err := repo.NewMigrator(logger, "var")
+	if err != nil {
+		return fmt.Errorf("failed to create migrator: %w", err)
+	}
+	defer m.Close()
+
+	ctx := context.Background()
+	switch cmd {
+	case "status":
+		statuses, err := m.Status(ctx)
+		if err != nil {
+			return fmt.Errorf("failed to get migration status: %w", err)
+		}
+		for _, s := range statuses {
+			applied := "pending"
+			if s.Applied {
+				applied = s.AppliedAt.Format(time.RFC3339)
+			}
+			fmt.Printf("%04d_%s\t%s\n", s.Version, s.Name, applied)
+		}
+	case "up":
+		n, err := m.Up(ctx)
+		if err != nil {
+			return fmt.Errorf("failed to apply migrations: %w", err)
+		}
+		logger.Infow("applied migrations", "count", n)
+	case "down":
+		n, err := m.Down(ctx, opts.Migrate.Down.Steps)
+		if err != nil {
+			return fmt.Errorf("failed to roll back migrations: %w", err)
+		}
+		logger.Infow("rolled back migrations", "count", n)
+	}
+	return nil
 }
 
 type app interface {
 	Init(mountPoint string, logger *zap.SugaredLogger, repo *repo.Repo) error
+	// Start runs once every app is mounted. Background work it starts
+	// should stop when ctx is done.
+	Start(ctx context.Context) error
+	// Shutdown runs after the server has stopped taking requests, to
+	// finish up before the repo is closed.
+	Shutdown(ctx context.Context) error
 	GetRouter() chi.Router
 	GetMountPoint() string
 }
+
+// readinessChecker is implemented by apps that need more than the
+// repo to serve traffic, checked by /readyz.
+type readinessChecker interface {
+	ReadinessChecks() []health.Check
+}
@@ -0,0 +1,34 @@
+package pics
+
+import (
+	"fmt"
+
+	env "github.com/Netflix/go-env"
+)
+
+type config struct {
+	// VoteSecret signs voter cookies and hashes voter IPs. Without
+	// it a random secret is generated and kept in the var dir, so
+	// voters keep their cookies across restarts.
+	VoteSecret string `env:"VOTE_SECRET"`
+	// Tls is set when the site is served over HTTPS, even if TLS ends
+	// at a proxy in front of it, so the voter cookie is only sent
+	// over HTTPS.
+	Tls bool `env:"TLS,default=false"`
+	// VoteRate is how many votes per minute each client may cast,
+	// with bursts of up to VoteBurst.
+	VoteRate  float64 `env:"VOTE_RATE_PER_MINUTE,default=20"`
+	VoteBurst int     `env:"VOTE_BURST,default=10"`
+	// CommentRate is how many comments per minute each client may
+	// post, with bursts of up to CommentBurst.
+	CommentRate  float64 `env:"COMMENT_RATE_PER_MINUTE,default=2"`
+	CommentBurst int     `env:"COMMENT_BURST,default=5"`
+}
+
+func newConfig() (*config, error) {
+	conf := config{}
+	if _, err := env.UnmarshalFromEnviron(&conf); err != nil {
+		return nil, fmt.Errorf("failed to load config: %w", err)
+	}
+	return &conf, nil
+}
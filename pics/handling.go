@@ -1,21 +1,21 @@
 package pics
 
 import (
-	"context"
-	"database/sql"
 	"errors"
 	"fmt"
 	"html/template"
 	"math/rand"
 	"net/http"
-	"os"
-	"sort"
+	"net/url"
 	"strconv"
 	"strings"
 	"time"
 
+	"github.com/btschwartz12/site/internal/ipdata"
+	"github.com/btschwartz12/site/internal/logging"
 	"github.com/btschwartz12/site/internal/repo"
 	"github.com/btschwartz12/site/internal/slack"
+	"github.com/btschwartz12/site/internal/storage"
 	"github.com/btschwartz12/site/pics/assets"
 	"github.com/go-chi/chi/v5"
 )
@@ -25,59 +25,178 @@ var (
 		"formatRFC3339": func(t time.Time) string {
 			return t.Format(time.RFC3339)
 		},
+		"srcset": srcset,
 	}).ParseFS(
 		assets.Templates,
 		"templates/base.html.tmpl",
+		"templates/admin.html.tmpl",
+		"templates/picture.html.tmpl",
 	))
 )
 
 type templateData struct {
 	Pictures []repo.Picture
 	Order    string
+	Seed     int64
+	Page     int
+	PrevUrl  string
+	NextUrl  string
+	// Votes are the client's own votes by picture id.
+	Votes map[int64]int64
+	// Pending is set after an upload that is waiting for moderation.
+	Pending bool
+	// Album and Tag are what the gallery is narrowed to, if anything,
+	// and BaseUrl is where it lives.
+	Album   *repo.Album
+	Tag     string
+	BaseUrl string
+	Albums  []repo.Album
+}
+
+type pictureData struct {
+	Picture  *repo.Picture
+	Comments []repo.Comment
+	Albums   []repo.Album
+	Tags     []string
+	// Vote is the client's own vote on the picture.
+	Vote             int64
+	MaxAuthorLength  int
+	MaxCommentLength int
+}
+
+var allowedOrders = map[string]bool{
+	repo.OrderNewest:   true,
+	repo.OrderOldest:   true,
+	repo.OrderLikes:    true,
+	repo.OrderDislikes: true,
+	repo.OrderRandom:   true,
+	repo.OrderTopWeek:  true,
+}
+
+// galleryPosition is where a client is in the gallery: which
+// pictures it is looking at, in what order, and on which page.
+type galleryPosition struct {
+	order string
+	seed  int64
+	page  int
+	// album is an album slug, and with tag narrows the pictures
+	album string
+	tag   string
+}
+
+// readGalleryPosition reads the gallery position from a request,
+// falling back to the first page of the newest pictures.
+func readGalleryPosition(r *http.Request) (galleryPosition, error) {
+	g := galleryPosition{order: repo.OrderNewest}
+	if v := r.FormValue("order"); allowedOrders[v] {
+		g.order = v
+	}
+	if g.order == repo.OrderRandom {
+		var err error
+		g.seed, err = strconv.ParseInt(r.FormValue("seed"), 10, 64)
+		if err != nil {
+			// a new shuffle, which the page links keep so later
+			// pages continue it rather than repeat pictures
+			g.seed = rand.Int63n(1 << 31)
+		}
+	}
+	page, err := strconv.Atoi(r.FormValue("page"))
+	if err != nil || page < 1 {
+		page = 1
+	}
+	g.page = page
+
+	// albums are in the path of gallery pages, and in the form of
+	// the votes cast from them
+	if g.album = chi.URLParam(r, "slug"); g.album == "" {
+		g.album = r.FormValue("album")
+	}
+	if v := r.FormValue("tag"); v != "" {
+		if g.tag, err = repo.NormalizeTag(v); err != nil {
+			return g, err
+		}
+	}
+	return g, nil
+}
+
+func (g galleryPosition) baseUrl() string {
+	if g.album != "" {
+		return "/pics/album/" + url.PathEscape(g.album)
+	}
+	return "/pics"
+}
+
+// url links to a page of the gallery at this position.
+func (g galleryPosition) url(page int) string {
+	v := url.Values{}
+	v.Set("order", g.order)
+	if g.order == repo.OrderRandom {
+		v.Set("seed", strconv.FormatInt(g.seed, 10))
+	}
+	if g.tag != "" {
+		v.Set("tag", g.tag)
+	}
+	if page > 1 {
+		v.Set("page", strconv.Itoa(page))
+	}
+	return g.baseUrl() + "?" + v.Encode()
 }
 
 func (s *PicsServer) indexHandler(w http.ResponseWriter, r *http.Request) {
-	pictures, err := s.rpo.GetAllPictures(r.Context())
+	g, err := readGalleryPosition(r)
+	if err != nil {
+		http.Error(w, "Invalid Tag", http.StatusBadRequest)
+		return
+	}
+
+	opts := repo.GalleryOptions{
+		Order: g.order,
+		Seed:  g.seed,
+		Page:  g.page - 1,
+		Tag:   g.tag,
+	}
+	var album *repo.Album
+	if g.album != "" {
+		album, err = s.rpo.GetAlbum(r.Context(), g.album)
+		if err != nil {
+			if errors.Is(err, repo.ErrNotFound) {
+				http.Error(w, "Not Found", http.StatusNotFound)
+				return
+			}
+			logging.FromRequest(s.logger, r).Errorw("error getting album", "error", err)
+			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+			return
+		}
+		opts.AlbumID = album.ID
+	}
+
+	pictures, more, err := s.rpo.GetGalleryPictures(r.Context(), opts)
+	if err != nil {
+		logging.FromRequest(s.logger, r).Errorw("error getting pictures", "error", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+
+	albums, err := s.rpo.GetAllAlbums(r.Context())
 	if err != nil {
-		s.logger.Errorw("error getting pictures", "error", err)
+		logging.FromRequest(s.logger, r).Errorw("error getting albums", "error", err)
 		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
 		return
 	}
 
-	order := "dsc"
-	v := r.FormValue("order")
-	allowedOrders := map[string]bool{
-		"asc":      true,
-		"dsc":      true,
-		"random":   true,
-		"likes":    true,
-		"dislikes": true,
-	}
-	if allowedOrders[v] {
-		order = v
-	}
-
-	switch order {
-	case "dsc":
-		sort.Slice(pictures, func(i, j int) bool {
-			return pictures[i].Pit.After(pictures[j].Pit)
-		})
-	case "asc":
-		sort.Slice(pictures, func(i, j int) bool {
-			return pictures[i].Pit.Before(pictures[j].Pit)
-		})
-	case "likes":
-		sort.Slice(pictures, func(i, j int) bool {
-			return pictures[i].NumLikes > pictures[j].NumLikes
-		})
-	case "dislikes":
-		sort.Slice(pictures, func(i, j int) bool {
-			return pictures[i].NumDislikes > pictures[j].NumDislikes
-		})
-	case "random":
-		rand.Shuffle(len(pictures), func(i, j int) {
-			pictures[i], pictures[j] = pictures[j], pictures[i]
-		})
+	// pages hand out the voter cookie, since votes need one from
+	// before they were cast
+	voter, err := s.voter(w, r)
+	if err != nil {
+		logging.FromRequest(s.logger, r).Errorw("error identifying voter", "error", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+	votes, err := s.rpo.GetVoterVotes(r.Context(), voter)
+	if err != nil {
+		logging.FromRequest(s.logger, r).Errorw("error getting votes", "error", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
 	}
 
 	for i, p := range pictures {
@@ -86,55 +205,174 @@ func (s *PicsServer) indexHandler(w http.ResponseWriter, r *http.Request) {
 
 	templateData := templateData{
 		Pictures: pictures,
-		Order:    order,
+		Order:    g.order,
+		Seed:     g.seed,
+		Page:     g.page,
+		Votes:    votes,
+		Pending:  r.FormValue("pending") != "",
+		Album:    album,
+		Tag:      g.tag,
+		BaseUrl:  g.baseUrl(),
+		Albums:   albums,
+	}
+	if g.page > 1 {
+		templateData.PrevUrl = g.url(g.page - 1)
+	}
+	if more {
+		templateData.NextUrl = g.url(g.page + 1)
 	}
 
 	if err := tmpl.ExecuteTemplate(w, "base.html.tmpl", templateData); err != nil {
-		s.logger.Errorw("error executing template", "error", err)
+		logging.FromRequest(s.logger, r).Errorw("error executing template", "error", err)
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 	}
 }
 
 func (s *PicsServer) likeHandler(w http.ResponseWriter, r *http.Request) {
+	s.vote(w, r, 1)
+}
+
+func (s *PicsServer) dislikeHandler(w http.ResponseWriter, r *http.Request) {
+	s.vote(w, r, -1)
+}
+
+// vote records the client's like or dislike, or takes it back if it
+// already voted that way.
+func (s *PicsServer) vote(w http.ResponseWriter, r *http.Request, vote int64) {
 	id := chi.URLParam(r, "id")
-	order := r.FormValue("order")
-	if order == "" {
-		order = "dsc"
+
+	// a voter minted for this request would let every request
+	// without a cookie vote again, so only count votes from clients
+	// that already had one
+	voter := s.readVoter(r)
+	if voter.ID == "" {
+		if _, err := s.voter(w, r); err != nil {
+			logging.FromRequest(s.logger, r).Errorw("error identifying voter", "error", err)
+			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+			return
+		}
+		http.Error(w, "Cookies are required to vote", http.StatusForbidden)
+		return
+	}
+	if _, err := s.rpo.VotePicture(r.Context(), id, voter, vote); err != nil {
+		if errors.Is(err, repo.ErrNotFound) || errors.Is(err, repo.ErrInvalidID) {
+			http.Error(w, "Not Found", http.StatusNotFound)
+			return
+		}
+		logging.FromRequest(s.logger, r).Errorw("error voting on picture", "error", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
 	}
 
-	err := s.rpo.LikePicture(r.Context(), id)
+	// back to the page the picture was on
+	if r.FormValue("from") == "picture" {
+		http.Redirect(w, r, "/pics/"+id, http.StatusSeeOther)
+		return
+	}
+	g, err := readGalleryPosition(r)
 	if err != nil {
-		if errors.Is(err, sql.ErrNoRows) {
+		http.Redirect(w, r, "/pics#pic-"+id, http.StatusSeeOther)
+		return
+	}
+	http.Redirect(w, r, g.url(g.page)+"#pic-"+id, http.StatusSeeOther)
+}
+
+// pictureHandler shows a picture with its details and comments.
+func (s *PicsServer) pictureHandler(w http.ResponseWriter, r *http.Request) {
+	p, err := s.rpo.GetPictureByID(r.Context(), chi.URLParam(r, "id"))
+	if err != nil {
+		if errors.Is(err, repo.ErrNotFound) || errors.Is(err, repo.ErrInvalidID) {
 			http.Error(w, "Not Found", http.StatusNotFound)
 			return
 		}
-		s.logger.Errorw("error liking picture", "error", err)
+		logging.FromRequest(s.logger, r).Errorw("error getting picture", "error", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+	if p.Status != repo.PictureApproved {
+		http.Error(w, "Not Found", http.StatusNotFound)
+		return
+	}
+	p.Url = "/pics/static/pic/" + strconv.FormatInt(p.ID, 10) + p.Extension
+
+	comments, err := s.rpo.GetPictureComments(r.Context(), p.ID)
+	if err != nil {
+		logging.FromRequest(s.logger, r).Errorw("error getting comments", "error", err)
 		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
 		return
 	}
 
-	http.Redirect(w, r, fmt.Sprintf("/pics?order=%s", order), http.StatusSeeOther)
+	albums, err := s.rpo.GetPictureAlbums(r.Context(), p.ID)
+	if err != nil {
+		logging.FromRequest(s.logger, r).Errorw("error getting picture albums", "error", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+
+	tags, err := s.rpo.GetPictureTags(r.Context(), p.ID)
+	if err != nil {
+		logging.FromRequest(s.logger, r).Errorw("error getting picture tags", "error", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+
+	// pages hand out the voter cookie, since votes need one from
+	// before they were cast
+	voter, err := s.voter(w, r)
+	if err != nil {
+		logging.FromRequest(s.logger, r).Errorw("error identifying voter", "error", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+	votes, err := s.rpo.GetVoterVotes(r.Context(), voter)
+	if err != nil {
+		logging.FromRequest(s.logger, r).Errorw("error getting votes", "error", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+
+	data := pictureData{
+		Picture:          p,
+		Comments:         comments,
+		Albums:           albums,
+		Tags:             tags,
+		Vote:             votes[p.ID],
+		MaxAuthorLength:  repo.MaxCommentAuthorLength,
+		MaxCommentLength: repo.MaxCommentLength,
+	}
+	if err := tmpl.ExecuteTemplate(w, "picture.html.tmpl", data); err != nil {
+		logging.FromRequest(s.logger, r).Errorw("error executing template", "error", err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
 }
 
-func (s *PicsServer) dislikeHandler(w http.ResponseWriter, r *http.Request) {
+func (s *PicsServer) commentHandler(w http.ResponseWriter, r *http.Request) {
 	id := chi.URLParam(r, "id")
-	order := r.FormValue("order")
-	if order == "" {
-		order = "dsc"
-	}
 
-	err := s.rpo.DislikePicture(r.Context(), id)
+	voter, err := s.voter(w, r)
 	if err != nil {
-		if errors.Is(err, sql.ErrNoRows) {
+		logging.FromRequest(s.logger, r).Errorw("error identifying commenter", "error", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+	c, err := s.rpo.AddComment(r.Context(), id, voter, r.FormValue("author"), r.FormValue("body"))
+	if err != nil {
+		if errors.Is(err, repo.ErrNotFound) || errors.Is(err, repo.ErrInvalidID) {
 			http.Error(w, "Not Found", http.StatusNotFound)
 			return
 		}
-		s.logger.Errorw("error disliking picture", "error", err)
+		if errors.Is(err, repo.ErrInvalidInput) {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		logging.FromRequest(s.logger, r).Errorw("error adding comment", "error", err)
 		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
 		return
 	}
 
-	http.Redirect(w, r, fmt.Sprintf("/pics?order=%s", order), http.StatusSeeOther)
+	s.rpo.RecordVisitorAsync(r, "commented on picture", getCommentBlocks(c))
+
+	http.Redirect(w, r, fmt.Sprintf("/pics/%s#comment-%d", id, c.ID), http.StatusSeeOther)
 }
 
 func (s *PicsServer) servePictureHandler(w http.ResponseWriter, r *http.Request) {
@@ -142,26 +380,52 @@ func (s *PicsServer) servePictureHandler(w http.ResponseWriter, r *http.Request)
 
 	p, err := s.rpo.GetPicture(r.Context(), basename)
 	if err != nil {
-		if errors.Is(err, sql.ErrNoRows) {
+		if errors.Is(err, repo.ErrNotFound) {
 			http.Error(w, "Not Found", http.StatusNotFound)
 			return
 		}
-		if strings.Contains(err.Error(), "invalid extension") {
-			http.Error(w, "Invalid Extension", http.StatusBadRequest)
+		if errors.Is(err, repo.ErrInvalidID) {
+			http.Error(w, "Invalid Basename", http.StatusBadRequest)
 			return
 		}
-		s.logger.Errorw("error getting picture", "error", err)
+		logging.FromRequest(s.logger, r).Errorw("error getting picture", "error", err)
 		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
 		return
 	}
-
-	if _, err := os.Stat(p.Url); err != nil {
-		s.logger.Errorw("error getting picture", "error", err)
-		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+	if p.Status != repo.PictureApproved {
+		http.Error(w, "Not Found", http.StatusNotFound)
 		return
 	}
 
-	http.ServeFile(w, r, p.Url)
+	var width int64
+	if v := r.URL.Query().Get("w"); v != "" {
+		width, err = strconv.ParseInt(v, 10, 64)
+		if err != nil || width < 0 {
+			http.Error(w, "Invalid Width", http.StatusBadRequest)
+			return
+		}
+	}
+	webp := strings.Contains(r.Header.Get("Accept"), "image/webp")
+
+	w.Header().Add("Vary", "Accept")
+	storage.ServeContent(w, r, s.rpo.Blobs(), p.ContentFor(width, webp))
+}
+
+// srcset lists the widths a picture can be served at, for the
+// browser to pick from.
+func srcset(p repo.Picture) string {
+	var candidates []string
+	seen := map[int64]bool{}
+	for _, v := range p.Variants {
+		if !seen[v.Width] {
+			seen[v.Width] = true
+			candidates = append(candidates, fmt.Sprintf("%s?w=%d %dw", p.Url, v.Width, v.Width))
+		}
+	}
+	if p.Width > 0 && !seen[p.Width] {
+		candidates = append(candidates, fmt.Sprintf("%s %dw", p.Url, p.Width))
+	}
+	return strings.Join(candidates, ", ")
 }
 
 func (s *PicsServer) uploadHandler(w http.ResponseWriter, r *http.Request) {
@@ -184,27 +448,46 @@ func (s *PicsServer) uploadHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	_, err = s.rpo.InsertPicture(r.Context(), file, header, author, description)
+	p, err := s.rpo.InsertPicture(r.Context(), file, header, author, description, ipdata.GetIp(r).String())
 	if err != nil {
-		if strings.Contains(err.Error(), "invalid extension") {
+		if errors.Is(err, repo.ErrInvalidExtension) {
 			http.Error(w, "Invalid Extension", http.StatusBadRequest)
 			return
 		}
-		if strings.Contains(err.Error(), "file too large") {
+		if errors.Is(err, repo.ErrInvalidImage) {
+			http.Error(w, "Invalid Image", http.StatusBadRequest)
+			return
+		}
+		if errors.Is(err, repo.ErrTooLarge) {
 			http.Error(w, err.Error(), http.StatusBadRequest)
 			return
 		}
-		s.logger.Errorw("error inserting picture", "error", err)
+		if errors.Is(err, repo.ErrStorageFull) {
+			http.Error(w, err.Error(), http.StatusInsufficientStorage)
+			return
+		}
+		logging.FromRequest(s.logger, r).Errorw("error inserting picture", "error", err)
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
 
-	go s.rpo.RecordVisitor(context.Background(), r, "uploaded picture", getPictureBlocks(author, description))
+	s.rpo.RecordVisitorAsync(r, "uploaded picture", getPictureBlocks(author, description, p.Status))
 
+	if p.Status == repo.PicturePending {
+		http.Redirect(w, r, "/pics?pending=1", http.StatusSeeOther)
+		return
+	}
 	http.Redirect(w, r, "/pics", http.StatusSeeOther)
 }
 
-func getPictureBlocks(author, description string) []slack.Block {
+func (s *PicsServer) adminHandler(w http.ResponseWriter, r *http.Request) {
+	if err := tmpl.ExecuteTemplate(w, "admin.html.tmpl", nil); err != nil {
+		logging.FromRequest(s.logger, r).Errorw("error executing template", "error", err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+func getPictureBlocks(author, description, status string) []slack.Block {
 	blocks := []slack.Block{
 		{
 			Type: "context",
@@ -221,6 +504,33 @@ func getPictureBlocks(author, description string) []slack.Block {
 					Type: "mrkdwn",
 					Text: fmt.Sprintf("caption: %s", description),
 				},
+				{
+					Type: "mrkdwn",
+					Text: fmt.Sprintf("status: %s", status),
+				},
+			},
+		},
+	}
+	return blocks
+}
+
+func getCommentBlocks(c *repo.Comment) []slack.Block {
+	blocks := []slack.Block{
+		{
+			Type: "context",
+			Elements: []slack.Element{
+				{
+					Type: "mrkdwn",
+					Text: fmt.Sprintf("comment on pic %d!", c.PictureID),
+				},
+				{
+					Type: "mrkdwn",
+					Text: fmt.Sprintf("author: %s", c.Author),
+				},
+				{
+					Type: "mrkdwn",
+					Text: fmt.Sprintf("comment: %s", c.Body),
+				},
 			},
 		},
 	}
@@ -1,9 +1,14 @@
 package pics
 
 import (
+	"context"
+	"fmt"
+
 	"github.com/go-chi/chi/v5"
 	"go.uber.org/zap"
+	"golang.org/x/time/rate"
 
+	"github.com/btschwartz12/site/internal/ratelimit"
 	"github.com/btschwartz12/site/internal/repo"
 )
 
@@ -12,6 +17,8 @@ type PicsServer struct {
 	rpo        *repo.Repo
 	router     *chi.Mux
 	mountPoint string
+	voteSecret []byte
+	tls        bool
 }
 
 func (s *PicsServer) Init(mountPoint string, logger *zap.SugaredLogger, rpo *repo.Repo) error {
@@ -20,10 +27,40 @@ func (s *PicsServer) Init(mountPoint string, logger *zap.SugaredLogger, rpo *rep
 	s.mountPoint = mountPoint
 	s.router = chi.NewRouter()
 
+	config, err := newConfig()
+	if err != nil {
+		return fmt.Errorf("failed to create config: %w", err)
+	}
+	s.tls = config.Tls
+	s.voteSecret = []byte(config.VoteSecret)
+	if config.VoteSecret == "" {
+		s.voteSecret, err = rpo.Secret("vote")
+		if err != nil {
+			return fmt.Errorf("failed to load vote secret: %w", err)
+		}
+	}
+
+	voteLimiter := ratelimit.New(ratelimit.Options{
+		Rate:  rate.Limit(config.VoteRate / 60),
+		Burst: config.VoteBurst,
+	})
+
+	commentLimiter := ratelimit.New(ratelimit.Options{
+		Rate:  rate.Limit(config.CommentRate / 60),
+		Burst: config.CommentBurst,
+	})
+
 	s.router.HandleFunc("/", s.indexHandler)
+	s.router.Get("/admin", s.adminHandler)
+	s.router.Get("/album/{slug}", s.indexHandler)
+	s.router.Get("/{id}", s.pictureHandler)
 	s.router.Post("/upload", s.uploadHandler)
-	s.router.Post("/like/{id}", s.likeHandler)
-	s.router.Post("/dislike/{id}", s.dislikeHandler)
+	s.router.Group(func(r chi.Router) {
+		r.Use(voteLimiter.Middleware)
+		r.Post("/like/{id}", s.likeHandler)
+		r.Post("/dislike/{id}", s.dislikeHandler)
+	})
+	s.router.With(commentLimiter.Middleware).Post("/{id}/comment", s.commentHandler)
 	s.router.HandleFunc("/static/pic/{basename}", s.servePictureHandler)
 
 	return nil
@@ -36,3 +73,11 @@ func (s *PicsServer) GetRouter() chi.Router {
 func (s *PicsServer) GetMountPoint() string {
 	return s.mountPoint
 }
+
+func (s *PicsServer) Start(ctx context.Context) error {
+	return nil
+}
+
+func (s *PicsServer) Shutdown(ctx context.Context) error {
+	return nil
+}
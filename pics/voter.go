@@ -0,0 +1,64 @@
+package pics
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/btschwartz12/site/internal/ratelimit"
+	"github.com/btschwartz12/site/internal/repo"
+)
+
+const (
+	voterCookie       = "pics_voter"
+	voterCookieMaxAge = 365 * 24 * time.Hour
+)
+
+func (s *PicsServer) mac(purpose, value string) string {
+	mac := hmac.New(sha256.New, s.voteSecret)
+	mac.Write([]byte(purpose + ":" + value))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// readVoter identifies the client from its voter cookie, leaving the
+// id empty if it has no valid one.
+func (s *PicsServer) readVoter(r *http.Request) repo.Voter {
+	v := repo.Voter{IpHash: s.mac("ip", ratelimit.ClientKey(r))}
+	c, err := r.Cookie(voterCookie)
+	if err != nil {
+		return v
+	}
+	id, sig, ok := strings.Cut(c.Value, ".")
+	if ok && hmac.Equal([]byte(sig), []byte(s.mac("voter", id))) {
+		v.ID = id
+	}
+	return v
+}
+
+// voter identifies the client like readVoter, giving it a new voter
+// cookie if it has no valid one.
+func (s *PicsServer) voter(w http.ResponseWriter, r *http.Request) (repo.Voter, error) {
+	v := s.readVoter(r)
+	if v.ID != "" {
+		return v, nil
+	}
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return v, err
+	}
+	v.ID = base64.RawURLEncoding.EncodeToString(b)
+	http.SetCookie(w, &http.Cookie{
+		Name:     voterCookie,
+		Value:    v.ID + "." + s.mac("voter", v.ID),
+		Path:     s.mountPoint,
+		MaxAge:   int(voterCookieMaxAge.Seconds()),
+		HttpOnly: true,
+		Secure:   s.tls || r.TLS != nil,
+		SameSite: http.SameSiteLaxMode,
+	})
+	return v, nil
+}
@@ -1,11 +1,11 @@
 package poke
 
 import (
-	"context"
 	"fmt"
 	"html/template"
 	"net/http"
 
+	"github.com/btschwartz12/site/internal/logging"
 	"github.com/btschwartz12/site/internal/slack"
 	"github.com/btschwartz12/site/poke/assets"
 )
@@ -31,12 +31,12 @@ func (s *PokeServer) indexHandler(w http.ResponseWriter, r *http.Request) {
 		templateData.Encounter = s.getEncounter()
 
 		if templateData.Encounter.Shiny {
-			go s.rpo.RecordVisitor(context.Background(), r, "shiny encounter", s.getShinyEncounterBlocks(templateData.Encounter))
+			s.rpo.RecordVisitorAsync(r, "shiny encounter", s.getShinyEncounterBlocks(templateData.Encounter))
 		}
 	}
 
 	if err := tmpl.ExecuteTemplate(w, "base.html.tmpl", templateData); err != nil {
-		s.logger.Errorw("error executing template", "error", err)
+		logging.FromRequest(s.logger, r).Errorw("error executing template", "error", err)
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 	}
 }
@@ -1,6 +1,7 @@
 package poke
 
 import (
+	"context"
 	"net/http"
 
 	"github.com/go-chi/chi/v5"
@@ -36,3 +37,11 @@ func (s *PokeServer) GetRouter() chi.Router {
 func (s *PokeServer) GetMountPoint() string {
 	return s.mountPoint
 }
+
+func (s *PokeServer) Start(ctx context.Context) error {
+	return nil
+}
+
+func (s *PokeServer) Shutdown(ctx context.Context) error {
+	return nil
+}
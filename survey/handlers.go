@@ -5,6 +5,7 @@ import (
 	"net/http"
 	"sort"
 
+	"github.com/btschwartz12/site/internal/logging"
 	"github.com/btschwartz12/site/survey/assets"
 )
 
@@ -56,7 +57,7 @@ func (s *SurveyServer) indexHandler(w http.ResponseWriter, r *http.Request) {
 	}
 
 	if err := tmpl.ExecuteTemplate(w, "base.html.tmpl", templateData); err != nil {
-		s.logger.Errorw("error executing template", "error", err)
+		logging.FromRequest(s.logger, r).Errorw("error executing template", "error", err)
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 	}
 }
@@ -5,9 +5,12 @@ import (
 	"fmt"
 	"net/http"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/btschwartz12/site/internal/handling"
+	"github.com/btschwartz12/site/internal/health"
+	"github.com/btschwartz12/site/internal/ratelimit"
 	"github.com/btschwartz12/site/internal/repo"
 	"github.com/btschwartz12/site/survey/assets"
 	"github.com/go-chi/chi/v5"
@@ -19,6 +22,9 @@ import (
 const (
 	maxQueueSize    = 10000
 	rateLimitPerSec = 10
+	// closeFrameTimeout bounds how long shutdown waits to send each
+	// client a close frame.
+	closeFrameTimeout = time.Second
 )
 
 type stateUpdate struct {
@@ -36,7 +42,15 @@ type SurveyServer struct {
 	state      *survey
 	stateMutex sync.Mutex
 	// clients is a map of all connected clients
-	clients map[*websocket.Conn]bool
+	clients      map[*websocket.Conn]bool
+	clientsMutex sync.Mutex
+	// version counts state updates, so an older save never overwrites
+	// a newer one
+	version      uint64
+	savedVersion uint64
+	saveMutex    sync.Mutex
+	// broadcasting is set while handleWsMessages runs
+	broadcasting atomic.Bool
 	// surveyMessageQueue is a channel that holds incoming state updates
 	surveyMessageQueue chan stateUpdate
 	// numConnectionsMessageQueue is a channel that holds incoming connection count updates
@@ -68,7 +82,9 @@ func (s *SurveyServer) Init(mountPoint string, logger *zap.SugaredLogger, rpo *r
 		r.Use(httprate.Limit(
 			rateLimitPerSec,
 			1*time.Second,
-			httprate.WithKeyFuncs(httprate.KeyByIP),
+			httprate.WithKeyFuncs(func(r *http.Request) (string, error) {
+				return ratelimit.ClientKey(r), nil
+			}),
 		))
 		r.HandleFunc("/update", s.updateHandler)
 	})
@@ -82,12 +98,47 @@ func (s *SurveyServer) Init(mountPoint string, logger *zap.SugaredLogger, rpo *r
 		return fmt.Errorf("failed to restore state: %w", err)
 	}
 
-	// start the websocket message handler
-	go s.handleWsMessages()
+	return nil
+}
+
+// Start starts the websocket message handler, which runs until ctx is
+// done.
+func (s *SurveyServer) Start(ctx context.Context) error {
+	go s.handleWsMessages(ctx)
+	return nil
+}
+
+// Shutdown disconnects every websocket client with a close frame and
+// saves the current state of the survey.
+func (s *SurveyServer) Shutdown(ctx context.Context) error {
+	s.closeClients()
 
+	s.stateMutex.Lock()
+	data, err := s.state.marshal()
+	version := s.version
+	s.stateMutex.Unlock()
+	if err != nil {
+		return fmt.Errorf("failed to marshal state: %w", err)
+	}
+	if err := s.saveState(ctx, data, version); err != nil {
+		return fmt.Errorf("failed to save state: %w", err)
+	}
 	return nil
 }
 
+// ReadinessChecks reports the survey unready when updates can't be
+// broadcast to clients.
+func (s *SurveyServer) ReadinessChecks() []health.Check {
+	return []health.Check{
+		{Name: "survey_broadcaster", Run: func(ctx context.Context) error {
+			if !s.broadcasting.Load() {
+				return fmt.Errorf("survey broadcaster is not running")
+			}
+			return nil
+		}},
+	}
+}
+
 func (s *SurveyServer) restoreState() error {
 	existingState, err := s.rpo.GetSurveyState(context.Background())
 	if err != nil {
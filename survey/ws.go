@@ -9,6 +9,8 @@ import (
 	"net/http"
 	"time"
 
+	"github.com/btschwartz12/site/internal/logging"
+	"github.com/btschwartz12/site/internal/metrics"
 	"github.com/btschwartz12/site/internal/slack"
 	"github.com/gorilla/websocket"
 )
@@ -22,36 +24,67 @@ const (
 
 // handleWsMessages will run in its own goroutine, waiting for messages
 // to be added to the messageQueue, then broadcasting them to all
-// connected clients.
-func (s *SurveyServer) handleWsMessages() {
-	// handle survey updates
-	go func() {
-		for {
-			update := <-s.surveyMessageQueue
-			surveyUpdateMessage := getSurveyUpdateMessage(update)
-			for client := range s.clients {
-				if err := client.WriteMessage(websocket.BinaryMessage, surveyUpdateMessage); err != nil {
-					s.logger.Errorw("error broadcasting message", "error", err)
-					client.Close()
-					delete(s.clients, client)
-				}
-			}
+// connected clients until ctx is done.
+func (s *SurveyServer) handleWsMessages(ctx context.Context) {
+	s.broadcasting.Store(true)
+	defer s.broadcasting.Store(false)
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		// handle survey updates
+		case update := <-s.surveyMessageQueue:
+			s.observeQueues()
+			s.broadcast(getSurveyUpdateMessage(update))
+		// handle number of connections updates
+		case numConnections := <-s.numConnectionsMessageQueue:
+			s.observeQueues()
+			s.broadcast(getNumConnectionsMessage(numConnections))
 		}
-	}()
-	// handle number of connections updates
-	go func() {
-		for {
-			numConnections := <-s.numConnectionsMessageQueue
-			numConnectionsMessage := getNumConnectionsMessage(numConnections)
-			for client := range s.clients {
-				if err := client.WriteMessage(websocket.BinaryMessage, numConnectionsMessage); err != nil {
-					s.logger.Errorw("error broadcasting message", "error", err)
-					client.Close()
-					delete(s.clients, client)
-				}
-			}
+	}
+}
+
+// broadcast sends a message to every client, dropping the ones that
+// can't be written to.
+func (s *SurveyServer) broadcast(message []byte) {
+	s.clientsMutex.Lock()
+	defer s.clientsMutex.Unlock()
+	for client := range s.clients {
+		if err := client.WriteMessage(websocket.BinaryMessage, message); err != nil {
+			s.logger.Errorw("error broadcasting message", "error", err)
+			client.Close()
+			delete(s.clients, client)
+		}
+	}
+	s.observeClients()
+}
+
+// closeClients sends every client a close frame and disconnects it.
+func (s *SurveyServer) closeClients() {
+	s.clientsMutex.Lock()
+	defer s.clientsMutex.Unlock()
+	message := websocket.FormatCloseMessage(websocket.CloseGoingAway, "server shutting down")
+	deadline := time.Now().Add(closeFrameTimeout)
+	for client := range s.clients {
+		if err := client.WriteControl(websocket.CloseMessage, message, deadline); err != nil {
+			s.logger.Errorw("error sending close frame", "error", err)
 		}
-	}()
+		client.Close()
+		delete(s.clients, client)
+	}
+	s.observeClients()
+}
+
+// observeClients records the number of connected clients. It must be
+// called with clientsMutex held.
+func (s *SurveyServer) observeClients() {
+	metrics.SurveyClients.Set(float64(len(s.clients)))
+}
+
+// observeQueues records how many messages are waiting in each queue.
+func (s *SurveyServer) observeQueues() {
+	metrics.SurveyQueueDepth.WithLabelValues("survey").Set(float64(len(s.surveyMessageQueue)))
+	metrics.SurveyQueueDepth.WithLabelValues("num_connections").Set(float64(len(s.numConnectionsMessageQueue)))
 }
 
 // getSurveyUpdateMessage will return a message to send to clients
@@ -82,18 +115,22 @@ func (s *SurveyServer) wsHandler(w http.ResponseWriter, r *http.Request) {
 
 	conn, err := upgrader.Upgrade(w, r, nil)
 	if err != nil {
-		s.logger.Errorw("error upgrading connection", "error", err)
+		logging.FromRequest(s.logger, r).Errorw("error upgrading connection", "error", err)
 		http.Error(w, "Failed to initialize websocket connection", http.StatusInternalServerError)
 		return
 	}
 	defer conn.Close()
 
+	s.clientsMutex.Lock()
 	s.clients[conn] = true
+	numClients := uint32(len(s.clients))
+	s.observeClients()
+	s.clientsMutex.Unlock()
 
 	s.stateMutex.Lock()
 	data, err := s.state.marshal()
 	if err != nil {
-		s.logger.Errorw("error marshaling state", "error", err)
+		logging.FromRequest(s.logger, r).Errorw("error marshaling state", "error", err)
 		s.stateMutex.Unlock()
 		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
 		return
@@ -103,29 +140,32 @@ func (s *SurveyServer) wsHandler(w http.ResponseWriter, r *http.Request) {
 	// send survey update
 	surveyUpdateMessage := getSurveyUpdateMessage(stateUpdate{MarshaledSurvey: data})
 	if err := conn.WriteMessage(websocket.BinaryMessage, surveyUpdateMessage); err != nil {
-		s.logger.Errorw("error sending state to client", "error", err)
+		logging.FromRequest(s.logger, r).Errorw("error sending state to client", "error", err)
 		return
 	}
 
 	// send number of connections
-	numClients := uint32(len(s.clients))
 	numConnectionsMessage := getNumConnectionsMessage(numClients)
 	if err := conn.WriteMessage(websocket.BinaryMessage, numConnectionsMessage); err != nil {
-		s.logger.Errorw("error sending number of connections to client", "error", err)
+		logging.FromRequest(s.logger, r).Errorw("error sending number of connections to client", "error", err)
 		return
 	}
 
 	// broadcast number of connections
 	s.numConnectionsMessageQueue <- numClients
+	s.observeQueues()
 
-	go s.rpo.RecordVisitor(context.Background(), r, "survey websocket connection", []slack.Block{})
+	s.rpo.RecordVisitorAsync(r, "survey websocket connection", []slack.Block{})
 
 	// wait until connection is closed
 	for {
 		time.Sleep(1 * time.Second)
 		_, _, err := conn.NextReader()
 		if err != nil {
+			s.clientsMutex.Lock()
 			delete(s.clients, conn)
+			s.observeClients()
+			s.clientsMutex.Unlock()
 			break
 		}
 	}
@@ -150,25 +190,43 @@ func (s *SurveyServer) updateHandler(w http.ResponseWriter, r *http.Request) {
 	// update the state
 	err = s.updateState(data)
 	if err != nil {
-		s.logger.Errorw("error updating state", "error", err)
+		logging.FromRequest(s.logger, r).Errorw("error updating state", "error", err)
 		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
 		return
 	}
+	s.version++
+	version := s.version
 
 	// save current state to database (for persistence b/w restarts)
-	go func() {
-		if err := s.rpo.UpdateSurveyState(context.Background(), data); err != nil {
-			s.logger.Errorw("error updating survey state in repo", "error", err)
+	s.rpo.Go(func() {
+		if err := s.saveState(context.Background(), data, version); err != nil {
+			logging.FromRequest(s.logger, r).Errorw("error updating survey state in repo", "error", err)
 		}
-	}()
+	})
 
 	// broadcast the change
 	s.surveyMessageQueue <- stateUpdate{MarshaledSurvey: data}
+	s.observeQueues()
 
 	w.WriteHeader(http.StatusOK)
 	w.Write([]byte("Survey received successfully"))
 }
 
+// saveState persists a version of the state, unless a newer one has
+// already been saved.
+func (s *SurveyServer) saveState(ctx context.Context, data []byte, version uint64) error {
+	s.saveMutex.Lock()
+	defer s.saveMutex.Unlock()
+	if version <= s.savedVersion {
+		return nil
+	}
+	if err := s.rpo.UpdateSurveyState(ctx, data); err != nil {
+		return err
+	}
+	s.savedVersion = version
+	return nil
+}
+
 // updateState will update the server's state with the provided survey,
 // This will be called with a lock held on the stateMutex.
 func (s *SurveyServer) updateState(data []byte) error {